@@ -0,0 +1,43 @@
+package uniqpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Barrier is a point in the submission order of a pool (see UniqPool.Barrier).
+type Barrier struct {
+	done <-chan struct{}
+}
+
+// Wait waits until every task submitted to the pool before the barrier was created has executed.
+// Returns the error of ctx if it is done first.
+func (b Barrier) Wait(ctx context.Context) error {
+	return waitDone(ctx, b.done)
+}
+
+// Barrier returns a barrier whose Wait returns only after every task that was pending or executing when the barrier
+// was created has executed or was dropped. It is a lighter-weight ordering primitive than stopping the pool.
+// The tasks submitted after the barrier, including the duplicates coalesced with the earlier tasks, are not
+// waited for. The tasks of SubmitAt and SubmitAfterKeys count as submitted once they get into the inbound queue.
+func (p *UniqPool[T]) Barrier() Barrier {
+	done := make(chan struct{})
+	var once sync.Once
+	h := &heldTask[T]{ready: func() { once.Do(func() { close(done) }) }, remaining: 1}
+
+	for _, s := range p.stripes {
+		s.mutex.Lock()
+		for id := range s.keys {
+			s.hold(h, id)
+		}
+		for id := range s.running {
+			if _, ok := s.keys[id]; !ok {
+				s.hold(h, id)
+			}
+		}
+		s.mutex.Unlock()
+	}
+	p.resolve([]*heldTask[T]{h})
+
+	return Barrier{done: done}
+}
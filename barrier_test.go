@@ -0,0 +1,36 @@
+package uniqpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestBarrier checks that the barrier waits for the pending and executing tasks submitted before it.
+func TestBarrier(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock))
+	require.NoError(t, pool.Barrier().Wait(context.Background()))
+
+	gate := make(chan struct{})
+	pool.Submit("running", func() { <-gate })
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return pool.Stats().RunningWorkers-pool.Stats().IdleWorkers == 1 },
+		time.Second, time.Millisecond)
+	pool.Submit("pending", func() {})
+
+	b := pool.Barrier()
+	pool.Submit("later", func() { <-gate })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	require.ErrorIs(t, b.Wait(ctx), context.DeadlineExceeded)
+
+	close(gate)
+	clock.tickChan <- time.Now()
+	require.NoError(t, b.Wait(context.Background()))
+
+	pool.StopAndWait()
+}
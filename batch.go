@@ -0,0 +1,104 @@
+package uniqpool
+
+import "sync/atomic"
+
+// KeyedTask is a task passed to the batch handler (see WithBatchDispatch).
+type KeyedTask[T comparable] struct {
+	// Identifier of the task.
+	ID T
+	// Function of the task.
+	Fn func()
+}
+
+// dispatchBatch submits the plain tasks of the batch to the executor as a single job
+// and returns the rest of the tasks to be dispatched individually.
+func (p *UniqPool[T]) dispatchBatch(ready []task[T]) []task[T] {
+	var batch []task[T]
+	rest := ready[:0]
+	for _, t := range ready {
+		if t.plain() {
+			batch = append(batch, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+
+	if len(batch) > 0 {
+		p.jobsWaitGroup.Add(1)
+		p.executor.submit(batch[0].id, func() {
+			defer p.jobsWaitGroup.Done()
+			atomic.AddInt64(&p.executorBacklog, -int64(len(batch)))
+			p.executeBatch(batch)
+		})
+	}
+
+	return rest
+}
+
+// executeBatch passes the tasks to the batch handler and completes them.
+func (p *UniqPool[T]) executeBatch(batch []task[T]) {
+	if p.isAborted() {
+		for _, t := range batch {
+			p.discard(t)
+			p.finish(t.id)
+			p.resumeParked(t.id)
+		}
+		return
+	}
+
+	if p.quarantine != nil {
+		runnable := batch[:0]
+		for _, t := range batch {
+			if p.quarantine.contains(t.id) {
+				p.skip(t)
+				p.finish(t.id)
+				p.resumeParked(t.id)
+			} else {
+				runnable = append(runnable, t)
+			}
+		}
+		batch = runnable
+	}
+
+	atomic.AddUint64(&p.counters.executed, uint64(len(batch)))
+	now := p.clock.Now()
+	keyed := make([]KeyedTask[T], len(batch))
+	for i, t := range batch {
+		p.latency.record(now.Sub(t.submittedAt))
+		keyed[i] = KeyedTask[T]{ID: t.id, Fn: t.fn}
+	}
+
+	completed := false
+	defer func() {
+		var err error
+		if !completed {
+			err = ErrTaskPanicked
+		}
+		for _, t := range batch {
+			p.complete(t, nil, err)
+			p.finish(t.id)
+			p.resumeParked(t.id)
+		}
+	}()
+
+	p.batchHandler(keyed)
+	completed = true
+}
+
+// resumeParked dispatches the task parked while the batched task with the same identifier was executing
+// (see WithSerialKeys).
+func (p *UniqPool[T]) resumeParked(id T) {
+	if !p.serialKeys {
+		return
+	}
+
+	p.runningMutex.Lock()
+	next, ok := p.parkedTasks[id]
+	delete(p.parkedTasks, id)
+	delete(p.runningKeys, id)
+	p.runningMutex.Unlock()
+
+	if ok {
+		p.dispatch([]task[T]{next})
+	}
+}
@@ -0,0 +1,52 @@
+package uniqpool
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchDispatch checks that the tasks dispatched per tick are passed to a single call of the batch handler,
+// while the tasks with results are executed individually.
+func TestBatchDispatch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock),
+		WithBatchDispatch(func(batch []KeyedTask[string]) {
+			ids := make([]string, 0, len(batch))
+			for _, t := range batch {
+				ids = append(ids, t.ID)
+			}
+			sort.Strings(ids)
+
+			mu.Lock()
+			batches = append(batches, ids)
+			mu.Unlock()
+		}))
+
+	pool.Submit("task1", func() {})
+	pool.Submit("task2", func() {})
+	pool.Submit("task1", func() {})
+
+	resultChan := make(chan any, 1)
+	pool.SubmitWithResult("task3", func() (any, error) { return 3, nil }, func(value any, _ error) {
+		resultChan <- value
+	})
+
+	clock.tickChan <- time.Now()
+	require.Equal(t, 3, <-resultChan)
+
+	pool.Submit("task1", func() {})
+	pool.StopAndWait()
+
+	// the batches may be executed concurrently by different workers
+	require.ElementsMatch(t, [][]string{{"task1", "task2"}, {"task1"}}, batches)
+	require.Equal(t, uint64(4), pool.Stats().Dispatched)
+}
@@ -0,0 +1,125 @@
+package uniqpool
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// BloomStore is an approximate UniqStore based on a counting Bloom filter, for workloads with tens of millions
+// of identifiers where exact per-identifier entries are too expensive: it takes about 10 bytes per expected
+// identifier at a 1% false-positive rate regardless of the identifier size. A false positive makes a new
+// identifier look pending, so the task is coalesced with nothing and dropped (extra suppression). Identifiers
+// are never missed, as long as only the added ones are removed (see Remove).
+type BloomStore[T comparable] struct {
+	mutex    sync.Mutex
+	counters []uint8
+	// The number of the hash functions.
+	hashes int
+	hash   func(T) uint64
+	n      int
+}
+
+// NewBloomStore creates a BloomStore sized for the expected number of identifiers in the set at once with
+// the false-positive rate. Returns an error wrapping ErrInvalidParameters if expected is not positive or fpRate
+// is not within (0, 1).
+func NewBloomStore[T comparable](expected int, fpRate float64) (*BloomStore[T], error) {
+	if expected <= 0 {
+		return nil, fmt.Errorf("%w: expected number of identifiers must be positive, got %d",
+			ErrInvalidParameters, expected)
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("%w: false-positive rate must be within (0, 1), got %v", ErrInvalidParameters, fpRate)
+	}
+
+	// the optimal sizes of the filter: m = -n*ln(p)/ln(2)^2 counters and k = m/n*ln(2) hash functions
+	m := int(math.Ceil(-float64(expected) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(expected) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &BloomStore[T]{
+		counters: make([]uint8, m),
+		hashes:   k,
+		hash:     newDefaultHasher[T](),
+	}, nil
+}
+
+// Add implements UniqStore. Returns false if the identifier is in the set or is a false positive.
+func (s *BloomStore[T]) Add(id T) bool {
+	h := s.hash(id)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.containsLocked(h) {
+		return false
+	}
+	for i := 0; i < s.hashes; i++ {
+		c := &s.counters[s.index(h, i)]
+		// a saturated counter is never decremented, so it cannot drop to zero while identifiers use it
+		if *c < math.MaxUint8 {
+			*c++
+		}
+	}
+	s.n++
+
+	return true
+}
+
+// Remove implements UniqStore. The identifier must have been added by Add that returned true and not removed
+// since: the filter cannot tell it from a false positive, and removing a false positive decrements the counters
+// shared with the identifiers in the set, so they may be missed. The pool removes only the identifiers it has
+// added. An identifier that is neither in the set nor a false positive is ignored.
+func (s *BloomStore[T]) Remove(id T) {
+	h := s.hash(id)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.containsLocked(h) {
+		return
+	}
+	for i := 0; i < s.hashes; i++ {
+		if c := &s.counters[s.index(h, i)]; *c < math.MaxUint8 {
+			*c--
+		}
+	}
+	s.n--
+}
+
+// Contains implements UniqStore. May return true for an identifier that is not in the set.
+func (s *BloomStore[T]) Contains(id T) bool {
+	h := s.hash(id)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.containsLocked(h)
+}
+
+// Len implements UniqStore.
+func (s *BloomStore[T]) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.n
+}
+
+// containsLocked returns true if all counters of the hash are set. Must be called under mutex.
+func (s *BloomStore[T]) containsLocked(h uint64) bool {
+	for i := 0; i < s.hashes; i++ {
+		if s.counters[s.index(h, i)] == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// index returns the counter of the i-th hash function by double hashing.
+func (s *BloomStore[T]) index(h uint64, i int) int {
+	h1, h2 := h&math.MaxUint32, h>>32
+	return int((h1 + uint64(i)*h2) % uint64(len(s.counters)))
+}
@@ -0,0 +1,44 @@
+package uniqpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestBloomStore checks that added identifiers are never missed and the false-positive rate is close to the target.
+func TestBloomStore(t *testing.T) {
+	_, err := NewBloomStore[int](0, 0.01)
+	require.ErrorIs(t, err, ErrInvalidParameters)
+	_, err = NewBloomStore[int](10, 1)
+	require.ErrorIs(t, err, ErrInvalidParameters)
+
+	s, err := NewBloomStore[int](1000, 0.01)
+	require.NoError(t, err)
+
+	var added []int
+	for i := 0; i < 1000; i++ {
+		if s.Add(i) {
+			added = append(added, i)
+		}
+	}
+	require.Equal(t, len(added), s.Len())
+	for i := 0; i < 1000; i++ {
+		require.True(t, s.Contains(i))
+	}
+
+	falsePositives := 0
+	for i := 1000; i < 11000; i++ {
+		if s.Contains(i) {
+			falsePositives++
+		}
+	}
+	require.Less(t, falsePositives, 300)
+
+	// only the identifiers added by Add that returned true are removed
+	for _, i := range added {
+		s.Remove(i)
+	}
+	require.Zero(t, s.Len())
+	require.True(t, s.Add(1))
+}
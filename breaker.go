@@ -0,0 +1,156 @@
+package uniqpool
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerBuckets is the number of buckets the window of the circuit breaker is split into.
+const breakerBuckets = 10
+
+type breakerState int
+
+const (
+	// Tasks are dispatched as usual.
+	breakerClosed breakerState = iota
+	// Dispatching is paused.
+	breakerOpen
+	// A single probe task is dispatched to check whether the failures have stopped.
+	breakerHalfOpen
+)
+
+// circuitBreaker pauses the dispatching when the failure rate of the tasks over the window exceeds the threshold.
+type circuitBreaker struct {
+	mutex sync.Mutex
+
+	// The failure rate at which the breaker opens.
+	threshold float64
+	// The minimum number of completed tasks in the window to evaluate the failure rate.
+	minSamples int
+	// The window over which the failure rate is computed.
+	window time.Duration
+	// The time the breaker stays open before probing.
+	cooldown time.Duration
+
+	state breakerState
+	// The time when the breaker was opened.
+	openedAt time.Time
+	// A probe task is executing in the half-open state.
+	probing bool
+	// Counters of the completed tasks by time buckets.
+	buckets [breakerBuckets]breakerBucket
+}
+
+type breakerBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+func newCircuitBreaker(threshold float64, minSamples int, window, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:  threshold,
+		minSamples: minSamples,
+		window:     window,
+		cooldown:   cooldown,
+	}
+}
+
+// allow returns the number of tasks that can be dispatched now, up to limit. In the half-open state it allows
+// a single task, which becomes the probe only when it is handed over to a worker (see probe).
+func (b *circuitBreaker) allow(now time.Time, limit int) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return 0
+		}
+		b.state = breakerHalfOpen
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return 0
+		}
+		return 1
+	default:
+		return limit
+	}
+}
+
+// probe marks the start of the probe task. Returns false if the breaker is not half-open or a probe is executing.
+func (b *circuitBreaker) probe() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state != breakerHalfOpen || b.probing {
+		return false
+	}
+	b.probing = true
+
+	return true
+}
+
+// cancelProbe allows a new probe, as the probe task was dropped without being executed.
+func (b *circuitBreaker) cancelProbe() {
+	b.mutex.Lock()
+	b.probing = false
+	b.mutex.Unlock()
+}
+
+// record registers the completion of a task.
+func (b *circuitBreaker) record(now time.Time, failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.state = breakerOpen
+			b.openedAt = now
+			return
+		}
+		b.state = breakerClosed
+		b.buckets = [breakerBuckets]breakerBucket{}
+		return
+	case breakerOpen:
+		// the task was dispatched before the breaker opened
+		return
+	}
+
+	bucketSize := b.window / breakerBuckets
+	start := now.Truncate(bucketSize)
+	bucket := &b.buckets[(start.UnixNano()/int64(bucketSize))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	if failed {
+		bucket.failures++
+	} else {
+		bucket.successes++
+	}
+
+	var successes, failures int
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.window {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+
+	total := successes + failures
+	if total >= b.minSamples && float64(failures)/float64(total) >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+}
+
+// isOpen returns true if the dispatching is paused or limited to probing.
+func (b *circuitBreaker) isOpen() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state != breakerClosed
+}
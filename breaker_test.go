@@ -0,0 +1,82 @@
+package uniqpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCircuitBreaker checks the transitions of the circuit breaker.
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(0.5, 4, time.Minute, time.Second*10)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.Equal(t, 100, b.allow(now, 100))
+
+	// not enough samples yet
+	b.record(now, true)
+	b.record(now, true)
+	b.record(now, false)
+	require.False(t, b.isOpen())
+
+	b.record(now, true)
+	require.True(t, b.isOpen())
+	require.Zero(t, b.allow(now.Add(time.Second), 100))
+
+	// a single probe after the cooldown, it fails
+	now = now.Add(time.Second * 10)
+	require.Equal(t, 1, b.allow(now, 100))
+	require.True(t, b.probe())
+	require.Zero(t, b.allow(now, 100))
+	require.False(t, b.probe())
+	b.record(now, true)
+	require.True(t, b.isOpen())
+	require.Zero(t, b.allow(now.Add(time.Second), 100))
+
+	// the next probe succeeds and closes the breaker
+	now = now.Add(time.Second * 10)
+	require.Equal(t, 1, b.allow(now, 100))
+	// a dropped probe does not keep the breaker waiting for its result
+	require.True(t, b.probe())
+	b.cancelProbe()
+	require.Equal(t, 1, b.allow(now, 100))
+	require.True(t, b.probe())
+	b.record(now, false)
+	require.False(t, b.isOpen())
+	require.Equal(t, 100, b.allow(now, 100))
+
+	// the failures out of the window are forgotten
+	b.record(now, true)
+	b.record(now, true)
+	now = now.Add(time.Minute)
+	b.record(now, true)
+	b.record(now, false)
+	require.False(t, b.isOpen())
+}
+
+// TestCircuitBreakerCancelledProbe checks that a probe dropped before execution does not keep the breaker open.
+func TestCircuitBreakerCancelledProbe(t *testing.T) {
+	pool := MustNew(10, 1, 10, time.Millisecond,
+		WithCircuitBreaker[string](0.5, 1, time.Minute, time.Millisecond*20))
+
+	errFailed := errors.New("failed")
+	failed := make(chan struct{})
+	pool.SubmitWithResult("fail", func() (any, error) { return nil, errFailed }, func(any, error) { close(failed) })
+	<-failed
+	require.True(t, pool.Stats().CircuitOpen)
+
+	done := make(chan struct{})
+	pool.Submit("probe", func() {})
+	require.True(t, pool.CancelKey("probe"))
+	pool.Submit("good", func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the task after the cancelled probe is not executed")
+	}
+	pool.StopAndWait()
+	require.False(t, pool.Stats().CircuitOpen)
+}
@@ -0,0 +1,27 @@
+package uniqpool
+
+import "sync/atomic"
+
+// executorSaturated returns true if all the workers are busy and the queue of the worker pool is full.
+func (p *UniqPool[T]) executorSaturated() bool {
+	stats := p.executor.stats()
+	return stats.IdleWorkers == 0 && stats.RunningWorkers >= stats.MaxWorkers &&
+		stats.WaitingTasks >= uint64(stats.Capacity)
+}
+
+// runInline executes the accepted task in the goroutine of the submitter (see WithCallerRuns).
+func (p *UniqPool[T]) runInline(t task[T]) {
+	if p.park(t) {
+		// executed by a worker right after the running task with the same identifier
+		return
+	}
+
+	t.execID = newExecutionID()
+	p.release(&t)
+	atomic.AddUint64(&p.counters.dispatched, 1)
+	p.observe(EventDispatched, t.id, nil)
+
+	p.jobsWaitGroup.Add(1)
+	defer p.jobsWaitGroup.Done()
+	p.execute(t)
+}
@@ -0,0 +1,34 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCallerRuns checks that the submitter executes the task when the inbound queue and the worker pool are full.
+func TestCallerRuns(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(1, 1, 1, time.Hour, WithClock[string](clock), WithCallerRuns[string]())
+
+	gate := make(chan struct{})
+	var executed int32
+	pool.Submit("running", func() { <-gate; atomic.AddInt32(&executed, 1) })
+	clock.tickChan <- time.Now()
+	pool.Submit("waiting", func() { atomic.AddInt32(&executed, 1) })
+	clock.tickChan <- time.Now()
+	require.Eventually(t, pool.executorSaturated, time.Second, time.Millisecond)
+	pool.Submit("queued", func() { atomic.AddInt32(&executed, 1) })
+
+	var inline int32
+	pool.Submit("inline", func() { atomic.AddInt32(&inline, 1) })
+	require.Equal(t, int32(1), atomic.LoadInt32(&inline))
+	require.Zero(t, atomic.LoadInt32(&executed))
+	require.Equal(t, uint64(3), pool.Stats().Dispatched)
+
+	close(gate)
+	pool.StopAndWait()
+	require.Equal(t, int32(3), atomic.LoadInt32(&executed))
+}
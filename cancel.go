@@ -0,0 +1,103 @@
+package uniqpool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// CancelKey cancels the tasks with the identifier, e.g. when the entity is deleted mid-processing: the pending task
+// is dropped instead of being dispatched, and the context of the executing tasks added by SubmitContext,
+// SubmitWithResultContext or SubmitRetryable is cancelled. Tasks that do not receive a context run to completion.
+// The result callbacks of the dropped task receive ErrCancelled. Submitting the identifier again before the pending
+// task is dispatched revives it. Returns true if there was a pending or executing task.
+func (p *UniqPool[T]) CancelKey(id T) bool {
+	id = p.normalizeKey(id)
+	s := p.stripe(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, found := s.keys[id]
+	// with ReleaseOnCompletion the identifier of the executing task is kept in the dedup set
+	if found && !(p.keyRelease == ReleaseOnCompletion && s.running[id] > 0) {
+		if _, ok := s.cancelled[id]; !ok {
+			s.cancelled[id] = struct{}{}
+			atomic.AddInt32(&p.cancelledKeys, 1)
+		}
+	}
+
+	for _, cancel := range s.cancels[id] {
+		cancel()
+		found = true
+	}
+
+	return found
+}
+
+// uncancel clears the cancellation of the pending task. Must be called under the mutex of the stripe s
+// holding the identifier.
+func (p *UniqPool[T]) uncancel(s *dedupStripe[T], id T) {
+	if _, ok := s.cancelled[id]; ok {
+		delete(s.cancelled, id)
+		atomic.AddInt32(&p.cancelledKeys, -1)
+	}
+}
+
+// dropCancelled drops the tasks cancelled by CancelKey from the batch and returns the rest.
+func (p *UniqPool[T]) dropCancelled(batch []task[T]) []task[T] {
+	rest := batch[:0]
+	for _, t := range batch {
+		s := p.stripe(t.id)
+		s.mutex.Lock()
+		_, ok := s.cancelled[t.id]
+		s.mutex.Unlock()
+		if !ok {
+			rest = append(rest, t)
+			continue
+		}
+
+		p.unstore(t.id)
+		s.mutex.Lock()
+		if _, ok := s.cancelled[t.id]; !ok {
+			// submitted again while the store was called, the task is kept
+			s.mutex.Unlock()
+			if p.uniqStore != nil {
+				p.uniqStore.Add(t.id)
+			}
+			rest = append(rest, t)
+			continue
+		}
+		waiters, held := p.dropLocked(s, t)
+		s.mutex.Unlock()
+
+		p.notifyDropped(t, ErrCancelled, waiters, held)
+	}
+
+	return rest
+}
+
+// taskContext returns the context passed to the executing task, cancelled by StopNow and CancelKey,
+// and the function to call when the task is completed.
+func (p *UniqPool[T]) taskContext(t task[T]) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(withExecutionID(p.ctx, t.execID))
+
+	s := p.stripe(t.id)
+	s.mutex.Lock()
+	cancels := s.cancels[t.id]
+	if cancels == nil {
+		cancels = make(map[ExecutionID]context.CancelFunc)
+		s.cancels[t.id] = cancels
+	}
+	cancels[t.execID] = cancel
+	s.mutex.Unlock()
+
+	return ctx, func() {
+		s.mutex.Lock()
+		delete(cancels, t.execID)
+		if len(cancels) == 0 {
+			delete(s.cancels, t.id)
+		}
+		s.mutex.Unlock()
+
+		cancel()
+	}
+}
@@ -0,0 +1,71 @@
+package uniqpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCancelKey checks that CancelKey drops the pending task and cancels the context of the executing one.
+func TestCancelKey(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock))
+
+	var executed int32
+	results := make(chan error, 1)
+	pool.SubmitWithResult("pending", func() (any, error) {
+		atomic.AddInt32(&executed, 1)
+		return nil, nil
+	}, func(_ any, err error) { results <- err })
+	require.True(t, pool.CancelKey("pending"))
+
+	// resubmitting revives the cancelled task
+	pool.Submit("revived", func() { atomic.AddInt32(&executed, 10) })
+	require.True(t, pool.CancelKey("revived"))
+	pool.Submit("revived", func() {})
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	pool.SubmitContext("running", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+	})
+	require.False(t, pool.CancelKey("unknown"))
+
+	clock.tickChan <- time.Now()
+	require.ErrorIs(t, <-results, ErrCancelled)
+	<-started
+	require.True(t, pool.CancelKey("running"))
+	<-cancelled
+
+	pool.StopAndWait()
+	require.Equal(t, int32(10), atomic.LoadInt32(&executed))
+}
+
+// TestCancelKeyPendingWhileRunning checks that with ReleaseOnDispatch CancelKey drops the pending task
+// even if the task with the same identifier is executing.
+func TestCancelKeyPendingWhileRunning(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Submit("key", func() {
+		close(started)
+		<-release
+	})
+	clock.tickChan <- time.Now()
+	<-started
+
+	var executed int32
+	pool.Submit("key", func() { atomic.AddInt32(&executed, 1) })
+	require.True(t, pool.CancelKey("key"))
+
+	close(release)
+	pool.StopAndWait()
+	require.Zero(t, atomic.LoadInt32(&executed))
+}
@@ -0,0 +1,43 @@
+package uniqpool
+
+// Step is a task of a pipeline (see SubmitChain). Fn returns the follow-up steps.
+type Step[T comparable] struct {
+	// Identifier of the task.
+	ID T
+	// Function of the task.
+	Fn func() []Step[T]
+}
+
+// SubmitChain adds a task whose function returns the follow-up tasks. After the task completes, the follow-up
+// tasks are added to the pool like by Submit, so each stage of a pipeline is deduplicated. The follow-up tasks
+// are accepted even while the pool is stopping, so StopAndWait finishes the whole pipeline.
+// Will block if the inbound queue is full.
+func (p *UniqPool[T]) SubmitChain(id T, fn func() []Step[T]) {
+	mustSubmit(p.submit(task[T]{id: id, chainFn: fn}, true))
+}
+
+// runChain executes the chained task and adds its follow-up tasks to the pool.
+func (p *UniqPool[T]) runChain(t task[T]) {
+	var steps []Step[T]
+	defer func() {
+		if len(steps) == 0 {
+			// releases the hold taken by reserve even if the task panics
+			p.pushed()
+			return
+		}
+		// submitted by another goroutine, so the worker does not block on the full inbound queue
+		// while the dispatcher is blocked on the busy workers
+		go p.submitSteps(steps)
+	}()
+
+	steps = t.chainFn()
+}
+
+// submitSteps adds the follow-up tasks to the pool and releases the hold taken by reserve for the chained task.
+func (p *UniqPool[T]) submitSteps(steps []Step[T]) {
+	defer p.pushed()
+
+	for _, next := range steps {
+		p.submit(task[T]{id: next.ID, chainFn: next.Fn, followUp: true}, true)
+	}
+}
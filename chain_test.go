@@ -0,0 +1,81 @@
+package uniqpool
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubmitChain checks that the follow-up tasks are deduplicated and executed even if the pool is stopping.
+func TestSubmitChain(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		executed []string
+	)
+
+	pool := MustNew[string](2, 2, 10, time.Hour)
+
+	var stage func(id string, n int) func() []Step[string]
+	stage = func(id string, n int) func() []Step[string] {
+		return func() []Step[string] {
+			mu.Lock()
+			executed = append(executed, id)
+			mu.Unlock()
+
+			if n == 3 {
+				return nil
+			}
+
+			// both branches lead to the same next stage, so it is coalesced
+			next := fmt.Sprintf("stage%d", n+1)
+			return []Step[string]{{ID: next, Fn: stage(next, n+1)}, {ID: next, Fn: stage(next, n+1)}}
+		}
+	}
+
+	pool.SubmitChain("stage1", stage("stage1", 1))
+	pool.StopAndWait()
+
+	require.Equal(t, []string{"stage1", "stage2", "stage3"}, executed)
+	require.Equal(t, uint64(2), pool.Stats().Coalesced)
+	require.Panics(t, func() { pool.SubmitChain("stage1", stage("stage1", 1)) })
+}
+
+// TestSubmitChainFullQueue checks that the follow-up tasks do not deadlock the pool when the inbound queue
+// and the worker pool are full.
+func TestSubmitChainFullQueue(t *testing.T) {
+	pool := MustNew[string](1, 1, 1, time.Millisecond)
+
+	var (
+		mu       sync.Mutex
+		executed int
+	)
+	leaf := func() []Step[string] {
+		mu.Lock()
+		executed++
+		mu.Unlock()
+		return nil
+	}
+
+	pool.SubmitChain("root", func() []Step[string] {
+		steps := make([]Step[string], 5)
+		for i := range steps {
+			steps[i] = Step[string]{ID: fmt.Sprintf("leaf%d", i), Fn: leaf}
+		}
+		return steps
+	})
+
+	done := make(chan struct{})
+	go func() {
+		pool.StopAndWait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "the pool is deadlocked")
+	}
+	require.Equal(t, 5, executed)
+}
@@ -0,0 +1,26 @@
+package uniqpool
+
+// startCheckpoints starts delivering the periodic snapshots of the pending identifiers (see WithCheckpoint).
+// The goroutine exits when the pool is stopped.
+func (p *UniqPool[T]) startCheckpoints() {
+	if p.checkpoint == nil {
+		return
+	}
+
+	ticker := p.clock.NewTicker(p.checkpointEvery)
+	stopChan := p.stopChan
+	p.stopWaitGroup.Add(1)
+	go func() {
+		defer p.stopWaitGroup.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-ticker.C():
+				p.checkpoint(p.pendingKeys(maxInt))
+			}
+		}
+	}()
+}
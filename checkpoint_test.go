@@ -0,0 +1,31 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckpoint checks that the pending identifiers are delivered periodically and once more on stop.
+func TestCheckpoint(t *testing.T) {
+	checkpoints := make(chan []string, 100)
+	pool := MustNew(10, 2, 10, time.Hour,
+		WithCheckpoint[string](time.Millisecond, func(keys []string) { checkpoints <- keys }))
+
+	pool.Submit("task1", func() {})
+	pool.Submit("task2", func() {})
+	require.Eventually(t, func() bool {
+		keys := <-checkpoints
+		return len(keys) == 2
+	}, time.Second, time.Millisecond)
+
+	pool.StopAndWait()
+	close(checkpoints)
+
+	var last []string
+	for keys := range checkpoints {
+		last = keys
+	}
+	require.Empty(t, last)
+}
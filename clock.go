@@ -0,0 +1,54 @@
+package uniqpool
+
+import "time"
+
+// Clock provides the current time, tickers and timers to the pool, so tests can control time
+// instead of sleeping for the real intervals. See the fakeclock package.
+// On Go 1.25+ the default clock also works with fake time inside a testing/synctest bubble.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTicker returns a new ticker with the given period.
+	NewTicker(d time.Duration) Ticker
+	// AfterFunc calls f in its own goroutine after the duration, like time.AfterFunc.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker delivers ticks at intervals.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// Timer calls a function once after a duration (see Clock.AfterFunc).
+type Timer interface {
+	// Stop prevents the timer from firing. Returns false if the timer has already fired or been stopped.
+	Stop() bool
+	// Reset changes the timer to fire after the duration. Returns true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// realClock is the Clock based on the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
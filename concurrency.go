@@ -0,0 +1,98 @@
+package uniqpool
+
+import (
+	"sync"
+	"time"
+)
+
+// concurrencyLimiter limits the number of the tasks executing at once and tunes the limit by AIMD (additive increase,
+// multiplicative decrease): after each window of completed tasks the limit is halved if their average latency
+// exceeds the target or their error rate exceeds the maximum, otherwise it grows by one.
+type concurrencyLimiter struct {
+	mutex sync.Mutex
+	cond  *sync.Cond
+
+	// The bounds of the limit.
+	minLimit int
+	maxLimit int
+	// The average latency of the tasks above which the limit is decreased.
+	targetLatency time.Duration
+	// The fraction of the failed tasks above which the limit is decreased.
+	maxErrorRate float64
+
+	limit    int
+	inFlight int
+	// The tasks completed since the limit was last tuned.
+	completed int
+	failed    int
+	latency   time.Duration
+}
+
+func newConcurrencyLimiter(minLimit int, targetLatency time.Duration, maxErrorRate float64) *concurrencyLimiter {
+	l := &concurrencyLimiter{
+		minLimit:      minLimit,
+		targetLatency: targetLatency,
+		maxErrorRate:  maxErrorRate,
+	}
+	l.cond = sync.NewCond(&l.mutex)
+
+	return l
+}
+
+// setMax sets the upper bound of the limit and starts from it.
+func (l *concurrencyLimiter) setMax(maxLimit int) {
+	if l.minLimit > maxLimit {
+		l.minLimit = maxLimit
+	}
+	l.maxLimit = maxLimit
+	l.limit = maxLimit
+}
+
+// acquire waits until the number of the executing tasks is below the limit and takes a slot.
+func (l *concurrencyLimiter) acquire() {
+	l.mutex.Lock()
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+	l.mutex.Unlock()
+}
+
+// release frees the slot of the task completed after the latency and tunes the limit at the end of the window.
+func (l *concurrencyLimiter) release(latency time.Duration, failed bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inFlight--
+	l.completed++
+	l.latency += latency
+	if failed {
+		l.failed++
+	}
+
+	// the window is the current limit, so the limit is tuned about once per round of the executing tasks
+	if l.completed >= l.limit {
+		overloaded := l.latency/time.Duration(l.completed) > l.targetLatency ||
+			float64(l.failed)/float64(l.completed) > l.maxErrorRate
+		switch {
+		case overloaded && l.limit > l.minLimit:
+			l.limit /= 2
+			if l.limit < l.minLimit {
+				l.limit = l.minLimit
+			}
+		case !overloaded && l.limit < l.maxLimit:
+			l.limit++
+		}
+		l.completed, l.failed, l.latency = 0, 0, 0
+	}
+
+	l.cond.Broadcast()
+}
+
+// current returns the current limit.
+func (l *concurrencyLimiter) current() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.limit
+}
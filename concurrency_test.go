@@ -0,0 +1,40 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestAdaptiveConcurrency checks that the limit is halved on slow or failing rounds and grows on healthy ones.
+func TestAdaptiveConcurrency(t *testing.T) {
+	l := newConcurrencyLimiter(1, time.Millisecond*10, 0.5)
+	l.setMax(4)
+	round := func(latency time.Duration, failures int) {
+		n := l.current()
+		for i := 0; i < n; i++ {
+			l.acquire()
+		}
+		for i := 0; i < n; i++ {
+			l.release(latency, i < failures)
+		}
+	}
+
+	round(time.Millisecond*20, 0)
+	require.Equal(t, 2, l.current())
+	round(time.Millisecond, 0)
+	require.Equal(t, 3, l.current())
+	round(time.Millisecond, 2)
+	require.Equal(t, 1, l.current())
+	round(time.Millisecond*20, 0)
+	require.Equal(t, 1, l.current())
+
+	pool := MustNew[string](10, 4, 10, time.Millisecond, WithAdaptiveConcurrency[string](1, time.Second, 0.1))
+	require.Equal(t, 4, pool.Stats().ConcurrencyLimit)
+
+	done := make(chan struct{})
+	pool.Submit("task1", func() { close(done) })
+	<-done
+	pool.StopAndWait()
+}
@@ -0,0 +1,94 @@
+package uniqpool
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer implements trailing-edge debounce per task identifier on top of the pool: a task is submitted
+// to the pool only after no task with the same identifier has been added to the debouncer for the quiet period.
+// Each duplicate resets the timer and replaces the task function, so the latest one is executed.
+type Debouncer[T comparable] struct {
+	pool  *UniqPool[T]
+	quiet time.Duration
+
+	// Pending tasks by identifier.
+	pending map[T]*debouncedTask
+	mutex   sync.Mutex
+}
+
+type debouncedTask struct {
+	timer Timer
+	fn    func()
+}
+
+// NewDebouncer creates a new Debouncer submitting tasks to the pool after the quiet period.
+func NewDebouncer[T comparable](pool *UniqPool[T], quiet time.Duration) *Debouncer[T] {
+	return &Debouncer[T]{
+		pool:    pool,
+		quiet:   quiet,
+		pending: make(map[T]*debouncedTask),
+	}
+}
+
+// Submit adds a task to the debouncer. If a task with the same identifier is pending, its timer is reset
+// and its function is replaced by fn. The identifier is normalized by the normalizer of the pool, if any.
+func (d *Debouncer[T]) Submit(id T, fn func()) {
+	id = d.pool.normalizeKey(id)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if t, ok := d.pending[id]; ok && t.timer.Stop() {
+		t.fn = fn
+		t.timer.Reset(d.quiet)
+		return
+	}
+
+	// a fired timer that has not taken the task yet sees that it was replaced
+	t := &debouncedTask{fn: fn}
+	t.timer = d.pool.clock.AfterFunc(d.quiet, func() { d.fire(id, t) })
+	d.pending[id] = t
+}
+
+// Flush submits all pending tasks to the pool immediately.
+func (d *Debouncer[T]) Flush() {
+	d.mutex.Lock()
+	pending := d.pending
+	d.pending = make(map[T]*debouncedTask)
+	d.mutex.Unlock()
+
+	// a fired timer that has not taken its task yet sees that it was flushed
+	for id, t := range pending {
+		t.timer.Stop()
+		d.submit(id, t.fn)
+	}
+}
+
+// Pending returns the number of tasks waiting for the quiet period.
+func (d *Debouncer[T]) Pending() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return len(d.pending)
+}
+
+// fire submits the task to the pool after the quiet period.
+func (d *Debouncer[T]) fire(id T, t *debouncedTask) {
+	d.mutex.Lock()
+	if d.pending[id] != t {
+		// replaced by a newer task or flushed
+		d.mutex.Unlock()
+		return
+	}
+	delete(d.pending, id)
+	fn := t.fn
+	d.mutex.Unlock()
+
+	d.submit(id, fn)
+}
+
+// submit adds the task to the pool. The task is dropped if the pool is stopped.
+func (d *Debouncer[T]) submit(id T, fn func()) {
+	_, _ = d.pool.SubmitEx(id, fn)
+}
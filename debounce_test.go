@@ -0,0 +1,48 @@
+package uniqpool
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDebouncer checks that a task is executed once after the quiet period with the latest function.
+func TestDebouncer(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Millisecond)
+	d := NewDebouncer(pool, time.Millisecond*200)
+
+	var last int32
+	for i := 1; i <= 5; i++ {
+		i := i
+		d.Submit("task1", func() { atomic.StoreInt32(&last, int32(i)) })
+		time.Sleep(time.Millisecond * 10)
+	}
+	require.Equal(t, 1, d.Pending())
+	require.Zero(t, atomic.LoadInt32(&last))
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&last) == 5 }, time.Second, time.Millisecond)
+	require.Zero(t, d.Pending())
+	require.Equal(t, uint64(1), pool.Stats().Submitted)
+
+	d.Submit("task2", func() { atomic.StoreInt32(&last, 6) })
+	d.Flush()
+	pool.StopAndWait()
+	require.Equal(t, int32(6), atomic.LoadInt32(&last))
+}
+
+// TestDebouncerKeyNormalizer checks that the identifiers equal after the normalization share one timer.
+func TestDebouncerKeyNormalizer(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Millisecond, WithKeyNormalizer(strings.ToLower))
+	d := NewDebouncer(pool, time.Hour)
+
+	d.Submit("Task1", func() {})
+	d.Submit("TASK1", func() {})
+	require.Equal(t, 1, d.Pending())
+
+	d.Flush()
+	pool.StopAndWait()
+	require.Equal(t, uint64(1), pool.Stats().Submitted)
+}
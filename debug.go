@@ -0,0 +1,174 @@
+package uniqpool
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDebugPendingLimit is the default maximum number of pending keys reported by DebugHandler.
+const defaultDebugPendingLimit = 100
+
+// debugState is the state of the pool reported by DebugHandler.
+type debugState[T comparable] struct {
+	Config  debugConfig
+	Stats   Stats
+	Workers executorStats
+	// A sample of the identifiers of the pending tasks.
+	PendingKeys []T
+}
+
+// debugConfig is the configuration of the pool reported by DebugHandler.
+type debugConfig struct {
+	Name                 string `json:",omitempty"`
+	InboundQueueCapacity int
+	UnboundedQueue       bool
+	RingBuffer           bool
+	Interval             time.Duration
+	KeyRelease           ReleasePoint
+	SerialKeys           bool
+	KeySharding          bool
+	OrderedDispatch      bool
+	RejectionPolicy      bool
+	LoadShedding         bool
+	SuppressionWindow    time.Duration
+	ResultCache          bool
+	NamespaceQuotas      map[string]int
+	Lanes                map[string]time.Duration
+	NamespaceProfiles    map[string]NamespaceProfile
+	UniqStore            bool
+	DedupStripes         int
+	MaxDrainBatch        int
+	MaxQueueLatency      time.Duration
+	MemoryBudget         int64
+	Priorities           bool
+	CircuitBreaker       bool
+	AdaptiveConcurrency  bool
+	ResourceLimits       bool
+	Checkpoint           time.Duration
+	MaxPendingKeys       int
+	TopKeys              bool
+	SlowKeys             bool
+	SlowTaskThreshold    time.Duration
+	StaleThreshold       time.Duration
+	WorkerHardLimit      time.Duration
+	ReplaceHungWorkers   bool
+	Requeue              bool
+	Quarantine           bool
+	BatchDispatch        bool
+}
+
+// DebugHandler returns an HTTP handler reporting the configuration, the statistics, the worker pool
+// statistics and a sample of the pending task identifiers in JSON. The number of reported identifiers
+// is limited by the "limit" query parameter, 100 by default. Task identifiers must be encodable by encoding/json.
+func (p *UniqPool[T]) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultDebugPendingLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		data, err := json.Marshal(p.debugState(limit))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
+
+// MarshalJSON encodes the state of the pool reported by DebugHandler with up to 100 pending keys,
+// so it can be captured by incident tooling. Task identifiers must be encodable by encoding/json.
+func (p *UniqPool[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.debugState(defaultDebugPendingLimit))
+}
+
+// debugState returns the state of the pool with up to limit pending keys.
+func (p *UniqPool[T]) debugState(limit int) debugState[T] {
+	config := debugConfig{
+		Name:                 p.name,
+		InboundQueueCapacity: p.inboundQueue.cap(),
+		UnboundedQueue:       p.unbounded,
+		RingBuffer:           p.inboundQueue.ring != nil,
+		Interval:             p.interval,
+		KeyRelease:           p.keyRelease,
+		SerialKeys:           p.serialKeys,
+		KeySharding:          p.shardHash != nil,
+		OrderedDispatch:      p.orderedDispatch,
+		RejectionPolicy:      p.rejectionPolicy != nil,
+		LoadShedding:         p.shedding != nil,
+		SuppressionWindow:    p.suppressionWindow,
+		ResultCache:          p.resultCache != nil,
+		UniqStore:            p.uniqStore != nil,
+		DedupStripes:         len(p.stripes),
+		MaxDrainBatch:        int(atomic.LoadInt64(&p.maxBatch)),
+		MaxQueueLatency:      p.maxQueueLatency,
+		MemoryBudget:         p.memoryBudget,
+		Priorities:           p.priorities,
+		CircuitBreaker:       p.breaker != nil,
+		AdaptiveConcurrency:  p.limiter != nil,
+		ResourceLimits:       p.resources != nil,
+		Checkpoint:           p.checkpointEvery,
+		MaxPendingKeys:       p.maxPendingKeys,
+		TopKeys:              p.hotKeys != nil,
+		SlowKeys:             p.slowKeys != nil,
+		SlowTaskThreshold:    p.slowTaskThreshold,
+		StaleThreshold:       p.staleThreshold,
+		WorkerHardLimit:      p.workerHardLimit,
+		ReplaceHungWorkers:   p.replaceHungWorkers,
+		Requeue:              p.requeue != nil,
+		Quarantine:           p.quarantine != nil,
+		BatchDispatch:        p.batchHandler != nil,
+	}
+	if len(p.namespaceSlots) > 0 {
+		config.NamespaceQuotas = make(map[string]int, len(p.namespaceSlots))
+		for namespace, slots := range p.namespaceSlots {
+			config.NamespaceQuotas[namespace] = cap(slots)
+		}
+	}
+	if len(p.profiles) > 0 {
+		config.NamespaceProfiles = make(map[string]NamespaceProfile, len(p.profiles))
+		for namespace, profile := range p.profiles {
+			config.NamespaceProfiles[namespace] = profile
+		}
+	}
+	if len(p.laneSpecs) > 0 {
+		config.Lanes = make(map[string]time.Duration, len(p.laneSpecs))
+		for _, spec := range p.laneSpecs {
+			config.Lanes[spec.name] = spec.interval
+		}
+	}
+
+	return debugState[T]{
+		Config:      config,
+		Stats:       p.Stats(),
+		Workers:     p.executor.stats(),
+		PendingKeys: p.pendingKeys(limit),
+	}
+}
+
+// pendingKeys returns up to limit identifiers of the pending tasks in no particular order.
+func (p *UniqPool[T]) pendingKeys(limit int) []T {
+	keys := make([]T, 0)
+	for _, s := range p.stripes {
+		s.mutex.Lock()
+		for id := range s.keys {
+			if len(keys) == limit {
+				break
+			}
+			keys = append(keys, id)
+		}
+		s.mutex.Unlock()
+	}
+
+	return keys
+}
@@ -0,0 +1,67 @@
+package uniqpool
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDebugHandler checks the JSON reported by the debug handler.
+func TestDebugHandler(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Hour, WithSerialKeys[string]())
+	defer pool.StopAndWait()
+
+	pool.Submit("task1", func() {})
+	pool.Submit("task2", func() {})
+	pool.Submit("task3", func() {})
+
+	rec := httptest.NewRecorder()
+	pool.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pool?limit=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var state debugState[string]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	require.Equal(t, 10, state.Config.InboundQueueCapacity)
+	require.Equal(t, time.Hour, state.Config.Interval)
+	require.True(t, state.Config.SerialKeys)
+	require.Equal(t, 3, state.Stats.Pending)
+	require.Equal(t, 2, state.Workers.MaxWorkers)
+	require.Len(t, state.PendingKeys, 2)
+
+	rec = httptest.NewRecorder()
+	pool.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pool?limit=x", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestMarshalJSON checks the JSON dump of the pool.
+func TestMarshalJSON(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Hour, WithPriorities[int](0))
+	defer pool.StopAndWait()
+
+	pool.Submit(1, func() {})
+
+	data, err := json.Marshal(pool)
+	require.NoError(t, err)
+
+	var state debugState[int]
+	require.NoError(t, json.Unmarshal(data, &state))
+	require.True(t, state.Config.Priorities)
+	require.Equal(t, uint64(1), state.Stats.Submitted)
+	require.Equal(t, []int{1}, state.PendingKeys)
+}
+
+// TestString checks the human-readable summary of the pool.
+func TestString(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Hour, WithName[int]("orders"))
+	pool.Submit(1, func() {})
+	pool.Submit(1, func() {})
+	require.Equal(t, "uniqpool(name=orders pending=1 running=0 coalesced=1 stopped=false)", pool.String())
+
+	pool.StopAndWait()
+	require.Equal(t, "uniqpool(name=orders pending=0 running=0 coalesced=1 stopped=true)", pool.String())
+}
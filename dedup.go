@@ -0,0 +1,150 @@
+package uniqpool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// dedupStripe is a part of the dedup state. Task identifiers are distributed among the stripes by hash,
+// so submitters of different identifiers do not contend for one mutex.
+type dedupStripe[T comparable] struct {
+	mutex sync.Mutex
+	// Identifiers of the pending tasks with their metadata.
+	keys map[T]pendingKey
+	// Time of the last execution of the tasks. Used only with suppressionWindow.
+	executedAt map[T]time.Time
+	// Callbacks waiting for the results of the pending tasks.
+	resultWaiters map[T][]resultWaiter
+	// Priorities of the pending tasks. Used only if priorities are enabled.
+	priorities map[T]int
+	// The number of the dispatched tasks that are not completed yet.
+	running map[T]int
+	// Tasks held until the task with the identifier is completed (see SubmitAfterKeys).
+	dependents map[T][]*heldTask[T]
+	// Identifiers of the pending tasks cancelled by CancelKey.
+	cancelled map[T]struct{}
+	// Cancel functions of the contexts of the executing tasks by execution ID.
+	cancels map[T]map[ExecutionID]context.CancelFunc
+
+	// The maximum sizes of keys and executedAt since they were rebuilt.
+	keysPeak       int
+	executedAtPeak int
+}
+
+func newDedupStripes[T comparable](n, capacity int) []*dedupStripe[T] {
+	stripes := make([]*dedupStripe[T], n)
+	for i := range stripes {
+		stripes[i] = &dedupStripe[T]{
+			keys:          make(map[T]pendingKey, capacity/n),
+			executedAt:    make(map[T]time.Time),
+			resultWaiters: make(map[T][]resultWaiter),
+			priorities:    make(map[T]int),
+			running:       make(map[T]int),
+			dependents:    make(map[T][]*heldTask[T]),
+			cancelled:     make(map[T]struct{}),
+			cancels:       make(map[T]map[ExecutionID]context.CancelFunc),
+		}
+	}
+
+	return stripes
+}
+
+const (
+	// shrinkMinPeak is the minimum peak size of a map to be rebuilt. Smaller maps are not worth it.
+	shrinkMinPeak = 1024
+	// shrinkRatio is the ratio of the peak size to the current size of a map at which the map is rebuilt.
+	shrinkRatio = 4
+)
+
+// pendingKey is the metadata of a pending task (see Peek).
+type pendingKey struct {
+	submittedAt time.Time
+	// The number of the tasks accepted to the inbound queue up to and including the task.
+	seq uint64
+	// The number of the submissions coalesced with the task.
+	coalesced int
+	labels    map[string]string
+	// True if the task executes a plain function, so it can be replaced by Update.
+	plain bool
+	// The function replacing the function of the task when it is dispatched (see Update). Nil if it is not replaced.
+	fn func()
+}
+
+// insertKey adds the identifier of the pending task t accepted with the sequence number seq.
+func (s *dedupStripe[T]) insertKey(t *task[T], seq uint64) {
+	s.keys[t.id] = pendingKey{submittedAt: t.submittedAt, seq: seq, plain: t.plain()}
+	if len(s.keys) > s.keysPeak {
+		s.keysPeak = len(s.keys)
+	}
+}
+
+// setLabels sets the labels of the pending task.
+func (s *dedupStripe[T]) setLabels(id T, labels map[string]string) {
+	k := s.keys[id]
+	k.labels = labels
+	s.keys[id] = k
+}
+
+// setExecutedAt sets the time of the last execution of the task.
+func (s *dedupStripe[T]) setExecutedAt(id T, at time.Time) {
+	s.executedAt[id] = at
+	if len(s.executedAt) > s.executedAtPeak {
+		s.executedAtPeak = len(s.executedAt)
+	}
+}
+
+// shrink rebuilds the maps that have shrunk a lot since their peak, e.g. after a burst is drained.
+// Go maps never release the memory of their buckets, so a map that once held a million keys would keep
+// that footprint forever.
+func (s *dedupStripe[T]) shrink() {
+	s.keys = shrinkMap(s.keys, &s.keysPeak)
+	s.executedAt = shrinkMap(s.executedAt, &s.executedAtPeak)
+}
+
+// shrinkMap returns a rebuilt copy of the map if it has shrunk enough since the peak, otherwise the map itself.
+func shrinkMap[K comparable, V any](m map[K]V, peak *int) map[K]V {
+	if *peak < shrinkMinPeak || len(m) > *peak/shrinkRatio {
+		return m
+	}
+
+	rebuilt := make(map[K]V, len(m))
+	for k, v := range m {
+		rebuilt[k] = v
+	}
+	*peak = len(rebuilt)
+
+	return rebuilt
+}
+
+// shrinkDedup rebuilds the maps of the dedup state that have shrunk after a burst.
+func (p *UniqPool[T]) shrinkDedup() {
+	for _, s := range p.stripes {
+		s.mutex.Lock()
+		s.shrink()
+		s.mutex.Unlock()
+	}
+}
+
+// stripe returns the stripe of the dedup state that holds the identifier.
+func (p *UniqPool[T]) stripe(id T) *dedupStripe[T] {
+	if len(p.stripes) == 1 {
+		return p.stripes[0]
+	}
+
+	return p.stripes[p.stripeHash(id)%uint64(len(p.stripes))]
+}
+
+// lockStripes locks all stripes of the dedup state.
+func (p *UniqPool[T]) lockStripes() {
+	for _, s := range p.stripes {
+		s.mutex.Lock()
+	}
+}
+
+// unlockStripes unlocks all stripes of the dedup state.
+func (p *UniqPool[T]) unlockStripes() {
+	for _, s := range p.stripes {
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,90 @@
+package uniqpool
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDedupStripeShrink checks that the maps are rebuilt after a burst is drained.
+func TestDedupStripeShrink(t *testing.T) {
+	s := newDedupStripes[int](1, 0)[0]
+
+	for i := 0; i < shrinkMinPeak*2; i++ {
+		s.insertKey(&task[int]{id: i}, 0)
+	}
+	for i := 10; i < shrinkMinPeak*2; i++ {
+		delete(s.keys, i)
+	}
+	require.Equal(t, shrinkMinPeak*2, s.keysPeak)
+
+	s.shrink()
+	require.Equal(t, 10, s.keysPeak)
+	require.Len(t, s.keys, 10)
+	for i := 0; i < 10; i++ {
+		require.Contains(t, s.keys, i)
+	}
+
+	// small maps are not rebuilt
+	delete(s.keys, 0)
+	s.shrink()
+	require.Equal(t, 10, s.keysPeak)
+}
+
+// TestDefaultHasher checks that equal identifiers have equal hashes, so they always map to the same stripe.
+func TestDefaultHasher(t *testing.T) {
+	type key struct {
+		name *string
+		size float64
+		_    int
+	}
+
+	name := "a"
+	hash := newDefaultHasher[key]()
+	k := key{name: &name, size: 0}
+	before := hash(k)
+	// the pointee is not hashed
+	name = "b"
+	require.Equal(t, before, hash(k))
+	require.Equal(t, hash(key{name: &name, size: 0}), hash(key{name: &name, size: math.Copysign(0, -1)}))
+
+	arrayHash := newDefaultHasher[[2]float32]()
+	require.Equal(t, arrayHash([2]float32{1, 0}), arrayHash([2]float32{1, float32(math.Copysign(0, -1))}))
+	// a pointer key whose pointee changes while it is pending leaves the dedup set
+	type node struct{ name string }
+	n := &node{name: "a"}
+	pool := MustNew(10, 1, 10, time.Hour, WithDedupStripes[*node](8))
+	pool.Submit(n, func() {})
+	n.name = "b"
+	pool.StopAndWait()
+	require.Zero(t, pool.Stats().Pending)
+}
+
+// TestDispatchLocksTouchedStripes checks that dispatching a batch does not lock the stripes the batch does not touch.
+func TestDispatchLocksTouchedStripes(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[int](clock), WithDedupStripes[int](4))
+	defer pool.StopAndWait()
+
+	// the first tick sweeps the stripe 0, so another stripe is held
+	var other *dedupStripe[int]
+	for _, s := range pool.stripes[1:] {
+		if s != pool.stripe(1) {
+			other = s
+			break
+		}
+	}
+	other.mutex.Lock()
+	defer other.mutex.Unlock()
+
+	done := make(chan struct{})
+	pool.Submit(1, func() { close(done) })
+	clock.tickChan <- time.Now()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the task is not dispatched")
+	}
+}
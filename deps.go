@@ -0,0 +1,177 @@
+package uniqpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// heldTask is a task held until its dependencies are completed (see SubmitAfterKeys).
+type heldTask[T comparable] struct {
+	t task[T]
+	// Called instead of submitting the task when the dependencies are completed. Nil if the task is submitted.
+	ready func()
+	// The number of the dependencies that are not completed yet, plus one while they are being registered.
+	// Updated atomically.
+	remaining int32
+}
+
+// SubmitAfterKeys adds a task that is held until the tasks with the identifiers deps are completed, if they are
+// pending or executing, then submits it like Submit. Dependencies that are not pending or executing are ignored.
+// The held task is not deduplicated until it is submitted. StopAndWait waits for the held tasks.
+func (p *UniqPool[T]) SubmitAfterKeys(id T, deps []T, fn func()) {
+	h := &heldTask[T]{t: task[T]{id: id, fn: fn, followUp: true}, remaining: 1}
+
+	s := p.stripe(p.normalizeKey(id))
+	s.mutex.Lock()
+	if p.Stopped() {
+		s.mutex.Unlock()
+		panic("pool is stopped")
+	}
+	// the dispatcher does not stop until the held task is submitted
+	atomic.AddInt32(&p.pendingPushes, 1)
+	s.mutex.Unlock()
+
+	for _, dep := range deps {
+		p.holdUntil(h, dep)
+	}
+
+	p.resolve([]*heldTask[T]{h})
+}
+
+// WaitAll blocks until the tasks with the identifiers are completed, if they are pending or executing,
+// for fan-out/fan-in flows built on top of the pool. Identifiers that are not pending or executing are ignored.
+// Returns the error of ctx if it is done first.
+func (p *UniqPool[T]) WaitAll(ctx context.Context, ids ...T) error {
+	done := make(chan struct{})
+	h := &heldTask[T]{ready: func() { close(done) }, remaining: 1}
+	for _, id := range ids {
+		p.holdUntil(h, id)
+	}
+	p.resolve([]*heldTask[T]{h})
+
+	return waitDone(ctx, done)
+}
+
+// WaitAny blocks until any of the tasks with the identifiers is completed. Returns immediately if any of them
+// is not pending or executing, or if there are no identifiers. Returns the error of ctx if it is done first.
+func (p *UniqPool[T]) WaitAny(ctx context.Context, ids ...T) error {
+	done := make(chan struct{})
+	var once sync.Once
+	ready := func() { once.Do(func() { close(done) }) }
+
+	if len(ids) == 0 {
+		ready()
+	}
+	for _, id := range ids {
+		h := &heldTask[T]{ready: ready, remaining: 1}
+		p.holdUntil(h, id)
+		p.resolve([]*heldTask[T]{h})
+	}
+
+	return waitDone(ctx, done)
+}
+
+// waitDone waits until done is closed or ctx is done.
+func waitDone(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// holdUntil makes the held task wait for the completion of the task with the identifier dep,
+// if it is pending or executing.
+func (p *UniqPool[T]) holdUntil(h *heldTask[T], dep T) {
+	dep = p.normalizeKey(dep)
+	s := p.stripe(dep)
+	s.mutex.Lock()
+	if _, pending := s.keys[dep]; pending || s.running[dep] > 0 {
+		s.hold(h, dep)
+	}
+	s.mutex.Unlock()
+}
+
+// hold makes the held task wait for the completion of the task with the identifier dep. Must be called under mutex.
+func (s *dedupStripe[T]) hold(h *heldTask[T], dep T) {
+	atomic.AddInt32(&h.remaining, 1)
+	s.dependents[dep] = append(s.dependents[dep], h)
+}
+
+// takeDependents returns the tasks held by the identifier, if no task with it is executing.
+// Must be called under mutex.
+func (s *dedupStripe[T]) takeDependents(id T) []*heldTask[T] {
+	if s.running[id] > 0 {
+		return nil
+	}
+
+	held, ok := s.dependents[id]
+	if ok {
+		delete(s.dependents, id)
+	}
+
+	return held
+}
+
+// finish marks the dispatched task as completed and submits the tasks held by it.
+func (p *UniqPool[T]) finish(id T) {
+	s := p.stripe(id)
+	s.mutex.Lock()
+	if s.running[id]--; s.running[id] == 0 {
+		delete(s.running, id)
+	}
+	held := s.takeDependents(id)
+	s.mutex.Unlock()
+
+	p.resolveDetached(held)
+}
+
+// resolve marks a dependency of the held tasks as completed and submits the tasks whose dependencies are all completed.
+func (p *UniqPool[T]) resolve(held []*heldTask[T]) {
+	for _, t := range p.readyTasks(held) {
+		p.submitHeld(t)
+	}
+}
+
+// resolveDetached is resolve for the worker and dispatcher goroutines: the tasks are submitted by another
+// goroutine, so a worker does not block on the full inbound queue while the dispatcher is blocked on the busy
+// workers. The dispatcher does not stop until they are submitted.
+func (p *UniqPool[T]) resolveDetached(held []*heldTask[T]) {
+	tasks := p.readyTasks(held)
+	if len(tasks) == 0 {
+		return
+	}
+
+	go func() {
+		for _, t := range tasks {
+			p.submitHeld(t)
+		}
+	}()
+}
+
+// readyTasks marks a dependency of the held tasks as completed, calls ready of the ones whose dependencies
+// are all completed and returns the tasks to submit.
+func (p *UniqPool[T]) readyTasks(held []*heldTask[T]) []task[T] {
+	var tasks []task[T]
+	for _, h := range held {
+		if atomic.AddInt32(&h.remaining, -1) != 0 {
+			continue
+		}
+
+		if h.ready != nil {
+			h.ready()
+			continue
+		}
+		tasks = append(tasks, h.t)
+	}
+
+	return tasks
+}
+
+// submitHeld submits the held task and releases the hold taken for it by SubmitAfterKeys.
+func (p *UniqPool[T]) submitHeld(t task[T]) {
+	p.submit(t, true)
+	p.pushed()
+}
@@ -0,0 +1,134 @@
+package uniqpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubmitAfterKeys checks that a task is held until its pending and executing dependencies are completed.
+func TestSubmitAfterKeys(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		executed []string
+	)
+	record := func(id string) func() {
+		return func() {
+			mu.Lock()
+			executed = append(executed, id)
+			mu.Unlock()
+		}
+	}
+
+	pool := MustNew[string](10, 4, 10, time.Millisecond)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	pool.Submit("running", func() {
+		close(started)
+		<-unblock
+		record("running")()
+	})
+	<-started
+
+	pool.SubmitAfterKeys("after", []string{"running", "unknown"}, record("after"))
+	time.Sleep(time.Millisecond * 50)
+	mu.Lock()
+	require.Empty(t, executed)
+	mu.Unlock()
+
+	close(unblock)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(executed) == 2
+	}, time.Second, time.Millisecond)
+	require.Equal(t, []string{"running", "after"}, executed)
+
+	// the held task is submitted on stop after its pending dependency is executed
+	pool.Submit("pending", record("pending"))
+	pool.SubmitAfterKeys("last", []string{"pending"}, record("last"))
+	pool.StopAndWait()
+	require.Equal(t, []string{"running", "after", "pending", "last"}, executed)
+	require.Panics(t, func() { pool.SubmitAfterKeys("last", nil, func() {}) })
+}
+
+// TestWaitAllAny checks that WaitAll and WaitAny block until all or any of the tasks are completed.
+func TestWaitAllAny(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock))
+
+	unblock := make(chan struct{})
+	pool.Submit("fast", func() {})
+	pool.Submit("slow", func() { <-unblock })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	require.ErrorIs(t, pool.WaitAny(ctx, "fast", "slow"), context.DeadlineExceeded)
+	// not pending
+	require.NoError(t, pool.WaitAny(context.Background(), "fast", "unknown"))
+	require.NoError(t, pool.WaitAll(context.Background()))
+
+	clock.tickChan <- time.Now()
+	require.NoError(t, pool.WaitAny(context.Background(), "fast", "slow"))
+
+	allDone := make(chan error)
+	go func() { allDone <- pool.WaitAll(context.Background(), "fast", "slow", "unknown") }()
+	select {
+	case <-allDone:
+		require.Fail(t, "WaitAll returned before the slow task is completed")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(unblock)
+	require.NoError(t, <-allDone)
+	pool.StopAndWait()
+}
+
+// TestSubmitAfterKeysSaturated checks that the held task submitted when its dependency completes does not deadlock
+// the pool when the inbound queue and the worker pool are full.
+func TestSubmitAfterKeysSaturated(t *testing.T) {
+	pool := MustNew[string](1, 1, 1, time.Millisecond)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	pool.Submit("a", func() {
+		close(started)
+		<-unblock
+	})
+	<-started
+
+	var (
+		mu       sync.Mutex
+		executed []string
+	)
+	record := func(id string) func() {
+		return func() {
+			mu.Lock()
+			executed = append(executed, id)
+			mu.Unlock()
+		}
+	}
+	pool.SubmitAfterKeys("b", []string{"a"}, record("b"))
+	// fill the worker pool queue, the dispatcher and the inbound queue
+	for _, id := range []string{"c", "d", "e"} {
+		pool.Submit(id, record(id))
+		time.Sleep(time.Millisecond * 10)
+	}
+	close(unblock)
+
+	done := make(chan struct{})
+	go func() {
+		pool.StopAndWait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "the pool is deadlocked")
+	}
+	require.ElementsMatch(t, []string{"b", "c", "d", "e"}, executed)
+}
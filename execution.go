@@ -0,0 +1,40 @@
+package uniqpool
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+)
+
+// ExecutionID identifies a single execution of a task. All submissions coalesced into the execution
+// share its ID, so they can be correlated in logs. IDs are unique within the process.
+type ExecutionID uint64
+
+// lastExecutionID is the last generated execution ID. Updated atomically.
+var lastExecutionID uint64
+
+// newExecutionID generates a new execution ID.
+func newExecutionID() ExecutionID {
+	return ExecutionID(atomic.AddUint64(&lastExecutionID, 1))
+}
+
+// String returns the decimal representation of the ID.
+func (id ExecutionID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+type executionIDKey struct{}
+
+// withExecutionID returns the context carrying the execution ID.
+func withExecutionID(ctx context.Context, id ExecutionID) context.Context {
+	return context.WithValue(ctx, executionIDKey{}, id)
+}
+
+// ExecutionIDFromContext returns the execution ID from the context passed to the task.
+func ExecutionIDFromContext(ctx context.Context) (ExecutionID, bool) {
+	id, ok := ctx.Value(executionIDKey{}).(ExecutionID)
+	return id, ok
+}
+
+// resultWaiter receives the result of the execution of a task.
+type resultWaiter func(exec ExecutionID, value any, err error)
@@ -0,0 +1,170 @@
+package uniqpool
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alitto/pond"
+)
+
+// executor executes dispatched tasks.
+type executor[T comparable] interface {
+	// submit sends the task to execution. Blocks if the executor queue is full.
+	submit(id T, fn func())
+	// stopAndWait waits for all submitted tasks to be executed and stops the executor.
+	stopAndWait()
+	// stats returns the statistics of the executor.
+	stats() executorStats
+}
+
+// executorStats contains the statistics of the executor.
+type executorStats struct {
+	MaxWorkers      int
+	Capacity        int
+	RunningWorkers  int
+	IdleWorkers     int
+	WaitingTasks    uint64
+	SubmittedTasks  uint64
+	SuccessfulTasks uint64
+	FailedTasks     uint64
+}
+
+// pondExecutor executes tasks using the pond worker pool.
+type pondExecutor[T comparable] struct {
+	pool *pond.WorkerPool
+}
+
+// printPanic prints the value and the stack trace of the panic of a task, like pond does by default.
+// It is called in the goroutine of the panicked task.
+func printPanic(panicValue any) {
+	fmt.Printf("Worker exits from a panic: %v\nStack trace: %s\n", panicValue, string(debug.Stack()))
+}
+
+// newPondExecutor creates the executor. The panics of the tasks are passed to panicHandler or printed if it is nil.
+func newPondExecutor[T comparable](workersCount, capacity int, panicHandler func(any)) *pondExecutor[T] {
+	if panicHandler == nil {
+		panicHandler = printPanic
+	}
+
+	return &pondExecutor[T]{pool: pond.New(workersCount, capacity, pond.PanicHandler(panicHandler))}
+}
+
+func (e *pondExecutor[T]) submit(_ T, fn func()) {
+	e.pool.Submit(fn)
+}
+
+func (e *pondExecutor[T]) stopAndWait() {
+	e.pool.StopAndWait()
+}
+
+func (e *pondExecutor[T]) stats() executorStats {
+	return executorStats{
+		MaxWorkers:      e.pool.MaxWorkers(),
+		Capacity:        e.pool.MaxCapacity(),
+		RunningWorkers:  e.pool.RunningWorkers(),
+		IdleWorkers:     e.pool.IdleWorkers(),
+		WaitingTasks:    e.pool.WaitingTasks(),
+		SubmittedTasks:  e.pool.SubmittedTasks(),
+		SuccessfulTasks: e.pool.SuccessfulTasks(),
+		FailedTasks:     e.pool.FailedTasks(),
+	}
+}
+
+// shardedExecutor executes tasks on a fixed set of workers. Each task identifier is always executed
+// by the same worker, so tasks with the same identifier are executed sequentially in dispatch order.
+type shardedExecutor[T comparable] struct {
+	hash   func(T) uint64
+	shards []chan func()
+	wg     sync.WaitGroup
+	// Receives the values of the panics of the tasks.
+	panicHandler func(any)
+
+	// Statistics counters. Updated atomically.
+	submitted  uint64
+	successful uint64
+	failed     uint64
+	busy       int32
+}
+
+func newShardedExecutor[T comparable](workersCount, capacity int, hash func(T) uint64,
+	panicHandler func(any),
+) *shardedExecutor[T] {
+	shardCapacity := capacity / workersCount
+	if shardCapacity < 1 {
+		shardCapacity = 1
+	}
+	if panicHandler == nil {
+		panicHandler = printPanic
+	}
+
+	e := &shardedExecutor[T]{
+		hash:         hash,
+		shards:       make([]chan func(), workersCount),
+		panicHandler: panicHandler,
+	}
+
+	e.wg.Add(workersCount)
+	for i := range e.shards {
+		e.shards[i] = make(chan func(), shardCapacity)
+		go e.worker(e.shards[i])
+	}
+
+	return e
+}
+
+func (e *shardedExecutor[T]) submit(id T, fn func()) {
+	atomic.AddUint64(&e.submitted, 1)
+	e.shards[e.hash(id)%uint64(len(e.shards))] <- fn
+}
+
+func (e *shardedExecutor[T]) stopAndWait() {
+	for _, shard := range e.shards {
+		close(shard)
+	}
+	e.wg.Wait()
+}
+
+func (e *shardedExecutor[T]) stats() executorStats {
+	stats := executorStats{
+		MaxWorkers:      len(e.shards),
+		RunningWorkers:  len(e.shards),
+		SubmittedTasks:  atomic.LoadUint64(&e.submitted),
+		SuccessfulTasks: atomic.LoadUint64(&e.successful),
+		FailedTasks:     atomic.LoadUint64(&e.failed),
+	}
+	stats.IdleWorkers = stats.RunningWorkers - int(atomic.LoadInt32(&e.busy))
+
+	for _, shard := range e.shards {
+		stats.Capacity += cap(shard)
+		stats.WaitingTasks += uint64(len(shard))
+	}
+
+	return stats
+}
+
+// worker executes the tasks of one shard.
+func (e *shardedExecutor[T]) worker(tasks <-chan func()) {
+	defer e.wg.Done()
+
+	for fn := range tasks {
+		e.run(fn)
+	}
+}
+
+// run executes the task. A panic does not stop the worker, it is passed to the panic handler.
+func (e *shardedExecutor[T]) run(fn func()) {
+	atomic.AddInt32(&e.busy, 1)
+	defer atomic.AddInt32(&e.busy, -1)
+
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddUint64(&e.failed, 1)
+			e.panicHandler(r)
+		}
+	}()
+
+	fn()
+	atomic.AddUint64(&e.successful, 1)
+}
@@ -0,0 +1,162 @@
+// Package fakeclock implements uniqpool.Clock with manually controlled time for deterministic tests.
+package fakeclock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/n-r-w/uniqpool"
+)
+
+// Clock is a uniqpool.Clock whose time moves only by Advance.
+type Clock struct {
+	now     time.Time
+	tickers []*ticker
+	timers  []*timer
+	mutex   sync.Mutex
+}
+
+// New creates a new Clock with the given current time.
+func New(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+// NewTicker returns a new ticker with the given period.
+func (c *Clock) NewTicker(d time.Duration) uniqpool.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &ticker{
+		clock:  c,
+		period: d,
+		next:   c.now.Add(d),
+		c:      make(chan time.Time, 1),
+	}
+	c.tickers = append(c.tickers, t)
+
+	return t
+}
+
+// AfterFunc returns a timer that calls f in its own goroutine when the time is advanced by d.
+// A non-positive d calls f right away.
+func (c *Clock) AfterFunc(d time.Duration, f func()) uniqpool.Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &timer{clock: c, f: f}
+	c.schedule(t, d)
+
+	return t
+}
+
+// Advance moves the time forward and fires the tickers and the timers that are due. As with the real tickers,
+// ticks are dropped if the previous ones are not received yet.
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			timers = append(timers, t)
+			continue
+		}
+		go t.f()
+	}
+	for i := len(timers); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+	c.timers = timers
+}
+
+// schedule adds the timer to fire after d. A timer that is already due fires right away. Must be called under mutex.
+func (c *Clock) schedule(t *timer, d time.Duration) {
+	if d <= 0 {
+		go t.f()
+		return
+	}
+
+	t.at = c.now.Add(d)
+	c.timers = append(c.timers, t)
+}
+
+// unschedule removes the timer. Returns false if the timer is not active. Must be called under mutex.
+func (c *Clock) unschedule(t *timer) bool {
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+type ticker struct {
+	clock  *Clock
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *ticker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	for i, other := range t.clock.tickers {
+		if other == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+type timer struct {
+	clock *Clock
+	at    time.Time
+	f     func()
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	return t.clock.unschedule(t)
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	active := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+
+	return active
+}
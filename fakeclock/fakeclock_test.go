@@ -0,0 +1,59 @@
+package fakeclock
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n-r-w/uniqpool"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPoolWithFakeClock checks that the pool dispatches tasks only when the fake time is advanced.
+func TestPoolWithFakeClock(t *testing.T) {
+	clock := New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	pool := uniqpool.MustNew(10, 2, 10, time.Minute,
+		uniqpool.WithClock[string](clock),
+		uniqpool.WithSuppressionWindow[string](time.Hour))
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	pool.Submit("task1", fn)
+	clock.Advance(time.Second * 59)
+	require.Never(t, func() bool { return atomic.LoadInt32(&processed) > 0 }, time.Millisecond*50, time.Millisecond*10)
+
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 1 }, time.Second, time.Millisecond)
+
+	// suppressed until the fake time passes the window
+	pool.Submit("task1", fn)
+	require.Equal(t, uint64(1), pool.Stats().Suppressed)
+	clock.Advance(time.Hour)
+	pool.Submit("task1", fn)
+	require.Equal(t, uint64(1), pool.Stats().Suppressed)
+
+	pool.StopAndWait()
+	require.Equal(t, int32(2), processed)
+}
+
+// TestTimersWithFakeClock checks that the timers of the pool fire only when the fake time is advanced.
+func TestTimersWithFakeClock(t *testing.T) {
+	clock := New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	pool := uniqpool.MustNew(10, 2, 10, time.Millisecond, uniqpool.WithClock[string](clock))
+	debouncer := uniqpool.NewDebouncer(pool, time.Minute)
+
+	var processed int32
+	debouncer.Submit("task1", func() { atomic.AddInt32(&processed, 1) })
+	clock.Advance(time.Second * 30)
+	debouncer.Submit("task1", func() { atomic.AddInt32(&processed, 10) })
+	clock.Advance(time.Second * 59)
+	require.Equal(t, 1, debouncer.Pending())
+
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return pool.Stats().Pending == 1 }, time.Second, time.Millisecond)
+	require.Zero(t, debouncer.Pending())
+
+	pool.StopAndWait()
+	require.Equal(t, int32(10), processed)
+}
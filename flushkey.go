@@ -0,0 +1,200 @@
+package uniqpool
+
+// FlushKey dispatches the pending task with the identifier right away instead of waiting for the next tick,
+// e.g. when a user asks to refresh an entity whose task is already accumulated. The other pending tasks
+// keep waiting. Returns false if there is no pending task with the identifier.
+func (p *UniqPool[T]) FlushKey(id T) bool {
+	id = p.normalizeKey(id)
+	s := p.stripe(id)
+	s.mutex.Lock()
+	_, pending := s.keys[id]
+	// with ReleaseOnCompletion the identifier of the executing task is kept in the dedup set
+	pending = pending && !(p.keyRelease == ReleaseOnCompletion && s.running[id] > 0)
+	s.mutex.Unlock()
+
+	if !pending {
+		return false
+	}
+
+	p.flushKeysMutex.Lock()
+	p.flushKeyRequests = append(p.flushKeyRequests, id)
+	p.flushKeysMutex.Unlock()
+
+	select {
+	case p.flushKeyChan <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// flushKeys dispatches the pending tasks requested by FlushKey. The tasks that are still being pushed
+// into the inbound queue are dispatched on the next tick. Must be called only by the processTasks goroutine.
+func (p *UniqPool[T]) flushKeys() {
+	p.flushKeysMutex.Lock()
+	ids := p.flushKeyRequests
+	p.flushKeyRequests = nil
+	p.flushKeysMutex.Unlock()
+
+	p.holdQueued()
+
+	var batch []task[T]
+	for _, id := range ids {
+		if t, ok := p.removeHeld(id); ok {
+			batch = append(batch, t)
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	p.inboundQueue.release(len(batch))
+	p.dispatchTaken(batch)
+}
+
+// holdQueued moves the tasks from the inbound queue to the priority queue, or to the carried tasks if priorities
+// are not enabled. The slots are kept while the tasks are held, so their number is limited by the capacity.
+// Each task is moved once, so a flushed task is found in amortized constant time.
+// Must be called only by the processTasks goroutine.
+func (p *UniqPool[T]) holdQueued() {
+	for {
+		t, ok := p.inboundQueue.popHeld()
+		if !ok {
+			return
+		}
+
+		if p.priorityQueue != nil {
+			p.priorityQueue.push(t)
+		} else {
+			p.carried.push(t)
+		}
+	}
+}
+
+// removeHeld removes the held task with the identifier. Returns false if there is no such task.
+// Its slot is kept. Must be called only by the processTasks goroutine.
+func (p *UniqPool[T]) removeHeld(id T) (task[T], bool) {
+	if p.priorityQueue != nil {
+		return p.priorityQueue.remove(id)
+	}
+
+	return p.carried.remove(id)
+}
+
+// takeCarried appends up to limit carried tasks to batch in FIFO order and frees their slots.
+// Must be called only by the processTasks goroutine.
+func (p *UniqPool[T]) takeCarried(batch []task[T], limit int) []task[T] {
+	n := 0
+	for len(batch) < limit {
+		t, ok := p.carried.pop()
+		if !ok {
+			break
+		}
+		batch = append(batch, t)
+		n++
+	}
+	p.inboundQueue.release(n)
+
+	return batch
+}
+
+// carriedQueue is a FIFO queue of the carried tasks indexed by identifier, so a task flushed by FlushKey
+// is removed in constant time: it is marked as removed and skipped by pop.
+type carriedQueue[T comparable] struct {
+	entries []carriedEntry[T]
+	// The index of the first entry that is not popped.
+	head int
+	// The number of the entries popped and compacted away, so the positions in the index stay valid.
+	base int
+	// The position of the last pushed task by identifier, counted from the first entry ever pushed.
+	index map[T]int
+	// The number of the tasks that are not popped or removed.
+	n int
+}
+
+type carriedEntry[T comparable] struct {
+	t       task[T]
+	removed bool
+}
+
+// len returns the number of the carried tasks.
+func (q *carriedQueue[T]) len() int {
+	return q.n
+}
+
+// push adds the task to the end of the queue.
+func (q *carriedQueue[T]) push(t task[T]) {
+	if q.index == nil {
+		q.index = make(map[T]int)
+	}
+	q.index[t.id] = q.base + len(q.entries)
+	q.entries = append(q.entries, carriedEntry[T]{t: t})
+	q.n++
+}
+
+// pop removes the first task from the queue. Returns false if the queue is empty.
+func (q *carriedQueue[T]) pop() (task[T], bool) {
+	for q.head < len(q.entries) {
+		e := &q.entries[q.head]
+		pos := q.base + q.head
+		q.head++
+		if e.removed {
+			continue
+		}
+
+		t := e.t
+		// the entry is cleared to not retain the task closure
+		*e = carriedEntry[T]{}
+		if q.index[t.id] == pos {
+			delete(q.index, t.id)
+		}
+		q.n--
+		q.compact()
+		return t, true
+	}
+
+	q.compact()
+	return task[T]{}, false
+}
+
+// remove removes the task with the identifier. Returns false if there is no such task.
+func (q *carriedQueue[T]) remove(id T) (task[T], bool) {
+	pos, ok := q.index[id]
+	if !ok {
+		return task[T]{}, false
+	}
+	delete(q.index, id)
+
+	e := &q.entries[pos-q.base]
+	t := e.t
+	// the entry is cleared to not retain the task closure
+	*e = carriedEntry[T]{removed: true}
+	q.n--
+	q.compact()
+
+	return t, true
+}
+
+// compact drops the popped entries once they make up half of the queue, so the memory does not grow.
+func (q *carriedQueue[T]) compact() {
+	if q.n == 0 {
+		q.base += len(q.entries)
+		for i := range q.entries[q.head:] {
+			q.entries[q.head+i] = carriedEntry[T]{}
+		}
+		q.entries = q.entries[:0]
+		q.head = 0
+		return
+	}
+	if q.head < len(q.entries)/2 {
+		return
+	}
+
+	rest := copy(q.entries, q.entries[q.head:])
+	for i := rest; i < len(q.entries); i++ {
+		q.entries[i] = carriedEntry[T]{}
+	}
+	q.entries = q.entries[:rest]
+	q.base += q.head
+	q.head = 0
+}
@@ -0,0 +1,109 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFlushKey checks that FlushKey dispatches one pending task right away and keeps the order of the others.
+func TestFlushKey(t *testing.T) {
+	for name, opts := range map[string][]Option[string]{
+		"fifo":       nil,
+		"priorities": {WithPriorities[string](0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clock := manualClock{tickChan: make(chan time.Time)}
+			pool := MustNew(10, 1, 10, time.Hour,
+				append(opts, WithClock[string](clock), WithOrderedDispatch[string]())...)
+
+			order := make(chan string, 3)
+			for _, id := range []string{"a", "b", "c"} {
+				id := id
+				pool.Submit(id, func() { order <- id })
+			}
+
+			require.True(t, pool.FlushKey("b"))
+			require.Equal(t, "b", <-order)
+			require.False(t, pool.FlushKey("b"))
+			require.False(t, pool.FlushKey("unknown"))
+			require.Equal(t, 2, pool.Stats().Pending)
+
+			clock.tickChan <- time.Now()
+			require.Equal(t, "a", <-order)
+			require.Equal(t, "c", <-order)
+
+			pool.StopAndWait()
+		})
+	}
+}
+
+// TestCarriedQueue checks that the carried tasks are popped in FIFO order without the removed ones.
+func TestCarriedQueue(t *testing.T) {
+	var q carriedQueue[int]
+	for i := 0; i < 10; i++ {
+		q.push(task[int]{id: i})
+	}
+
+	removed, ok := q.remove(3)
+	require.True(t, ok)
+	require.Equal(t, 3, removed.id)
+	_, ok = q.remove(3)
+	require.False(t, ok)
+	require.Equal(t, 9, q.len())
+
+	var popped []int
+	for i := 0; i < 6; i++ {
+		next, ok := q.pop()
+		require.True(t, ok)
+		popped = append(popped, next.id)
+	}
+	require.Equal(t, []int{0, 1, 2, 4, 5, 6}, popped)
+
+	// the positions stay valid after the popped entries are compacted away
+	q.push(task[int]{id: 10})
+	removed, ok = q.remove(8)
+	require.True(t, ok)
+	require.Equal(t, 8, removed.id)
+	removed, ok = q.remove(10)
+	require.True(t, ok)
+	require.Equal(t, 10, removed.id)
+
+	popped = nil
+	for {
+		next, ok := q.pop()
+		if !ok {
+			break
+		}
+		popped = append(popped, next.id)
+	}
+	require.Equal(t, []int{7, 9}, popped)
+	require.Zero(t, q.len())
+	require.Empty(t, q.index)
+	require.Empty(t, q.entries)
+}
+
+// TestFlushKeyPendingWhileRunning checks that with ReleaseOnDispatch FlushKey dispatches the pending task
+// even if the task with the same identifier is executing.
+func TestFlushKeyPendingWhileRunning(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Submit("key", func() {
+		close(started)
+		<-release
+	})
+	clock.tickChan <- time.Now()
+	<-started
+
+	flushed := make(chan struct{})
+	pool.Submit("key", func() { close(flushed) })
+	require.True(t, pool.FlushKey("key"))
+	<-flushed
+
+	close(release)
+	pool.StopAndWait()
+}
@@ -0,0 +1,174 @@
+package uniqpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Gang is a set of task identifiers dispatched together, for jobs that only make sense as a complete group
+// (see NewGang).
+type Gang[T comparable] struct {
+	pool    *UniqPool[T]
+	timeout time.Duration
+	members map[T]struct{}
+
+	mutex sync.Mutex
+	// The tasks of the members submitted in the current round.
+	held map[T]task[T]
+	// The number of the current round, so the timer of a released round does not release the next one.
+	round uint64
+	timer Timer
+}
+
+// NewGang declares a gang of the task identifiers. The tasks of the members submitted by Gang.Submit are held
+// until all the members are submitted, or until timeout passes since the first of them, and then dispatched
+// together right away, bypassing the inbound queue and the interval. After that the gang accepts the next round.
+// A zero timeout waits for all the members. When the pool is stopped, the held tasks are dispatched right away.
+func (p *UniqPool[T]) NewGang(timeout time.Duration, ids ...T) *Gang[T] {
+	g := &Gang[T]{
+		pool:    p,
+		timeout: timeout,
+		members: make(map[T]struct{}, len(ids)),
+		held:    make(map[T]task[T], len(ids)),
+	}
+	for _, id := range ids {
+		g.members[p.normalizeKey(id)] = struct{}{}
+	}
+
+	return g
+}
+
+// Submit adds the task of a member of the gang. Duplicates of a held task are coalesced with it. A held task is
+// deduplicated against the pool only when the gang is dispatched, so a member that is already pending in the pool
+// is coalesced with it instead of being dispatched with the gang. Panics if id is not a member of the gang
+// or the pool is stopped.
+func (g *Gang[T]) Submit(id T, fn func()) {
+	p := g.pool
+	id = p.normalizeKey(id)
+	if _, ok := g.members[id]; !ok {
+		panic("not a member of the gang")
+	}
+
+	g.mutex.Lock()
+	if _, ok := g.held[id]; ok {
+		g.mutex.Unlock()
+		atomic.AddUint64(&p.counters.coalesced, 1)
+		p.observe(EventCoalesced, id, nil)
+		return
+	}
+
+	if len(g.held) == 0 {
+		if !p.holdGang(g) {
+			g.mutex.Unlock()
+			panic("pool is stopped")
+		}
+		if g.timeout > 0 {
+			round := g.round
+			g.timer = g.pool.clock.AfterFunc(g.timeout, func() { g.release(round) })
+		}
+	}
+	g.held[id] = task[T]{id: id, fn: fn, followUp: true}
+
+	if len(g.held) < len(g.members) {
+		g.mutex.Unlock()
+		return
+	}
+	held := g.take()
+	g.mutex.Unlock()
+
+	p.dispatchGang(held)
+}
+
+// release dispatches the tasks held in the round, if it is still the current one.
+func (g *Gang[T]) release(round uint64) {
+	g.mutex.Lock()
+	if g.round != round || len(g.held) == 0 {
+		g.mutex.Unlock()
+		return
+	}
+	held := g.take()
+	g.mutex.Unlock()
+
+	g.pool.dispatchGang(held)
+}
+
+// take returns the held tasks and starts the next round. Must be called under mutex.
+func (g *Gang[T]) take() []task[T] {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	g.round++
+
+	held := make([]task[T], 0, len(g.held))
+	for id, t := range g.held {
+		held = append(held, t)
+		delete(g.held, id)
+	}
+
+	p := g.pool
+	p.gangMutex.Lock()
+	delete(p.gangs, g)
+	p.gangMutex.Unlock()
+
+	return held
+}
+
+// holdGang registers the gang holding tasks, so it is released when the pool is stopped.
+// Returns false if the pool is stopped.
+func (p *UniqPool[T]) holdGang(g *Gang[T]) bool {
+	p.gangMutex.Lock()
+	defer p.gangMutex.Unlock()
+
+	// checked under the mutex, so a gang registered after flushGangs sees the stopped pool
+	if p.Stopped() {
+		return false
+	}
+	if p.gangs == nil {
+		p.gangs = make(map[*Gang[T]]struct{})
+	}
+	p.gangs[g] = struct{}{}
+	// the dispatcher does not stop until the gang is dispatched
+	atomic.AddInt32(&p.pendingPushes, 1)
+
+	return true
+}
+
+// dispatchGang sends the tasks of the gang to the worker pool together.
+func (p *UniqPool[T]) dispatchGang(held []task[T]) {
+	ready := make([]task[T], 0, len(held))
+	for i := range held {
+		if p.acceptNow(&held[i]) == Enqueued {
+			ready = append(ready, held[i])
+		}
+	}
+	if len(ready) > 0 {
+		p.dispatch(ready)
+	}
+
+	for range ready {
+		p.pushed()
+	}
+	// the push of the gang itself
+	p.pushed()
+}
+
+// flushGangs dispatches the tasks held by all the gangs without waiting for the other members.
+// Called when the pool is stopped.
+func (p *UniqPool[T]) flushGangs() {
+	p.gangMutex.Lock()
+	gangs := make([]*Gang[T], 0, len(p.gangs))
+	for g := range p.gangs {
+		gangs = append(gangs, g)
+	}
+	p.gangMutex.Unlock()
+
+	for _, g := range gangs {
+		g.mutex.Lock()
+		round := g.round
+		g.mutex.Unlock()
+		// dispatching may wait for the executor, so the dispatcher must not wait for it
+		go g.release(round)
+	}
+}
@@ -0,0 +1,56 @@
+package uniqpool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGang checks that the members of a gang are dispatched together once all of them are submitted,
+// the timeout passes or the pool is stopped.
+func TestGang(t *testing.T) {
+	pool := MustNew[string](10, 3, 10, time.Hour)
+
+	var (
+		mu       sync.Mutex
+		executed []string
+	)
+	fn := func(id string) func() {
+		return func() {
+			mu.Lock()
+			executed = append(executed, id)
+			mu.Unlock()
+		}
+	}
+	executedCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(executed)
+	}
+
+	gang := pool.NewGang(0, "a", "b", "c")
+	gang.Submit("a", fn("a"))
+	gang.Submit("b", fn("b"))
+	gang.Submit("b", fn("b"))
+	require.Panics(t, func() { gang.Submit("d", fn("d")) })
+	time.Sleep(time.Millisecond * 20)
+	require.Zero(t, executedCount())
+
+	gang.Submit("c", fn("c"))
+	require.Eventually(t, func() bool { return executedCount() == 3 }, time.Second, time.Millisecond)
+	require.ElementsMatch(t, []string{"a", "b", "c"}, executed)
+
+	// the timeout dispatches the incomplete gang
+	timed := pool.NewGang(time.Millisecond*10, "x", "y")
+	timed.Submit("x", fn("x"))
+	require.Eventually(t, func() bool { return executedCount() == 4 }, time.Second, time.Millisecond)
+
+	// the stop dispatches the held tasks
+	gang.Submit("a", fn("a2"))
+	pool.StopAndWait()
+	require.Equal(t, 5, executedCount())
+	require.Equal(t, uint64(1), pool.Stats().Coalesced)
+	require.Panics(t, func() { gang.Submit("b", fn("b")) })
+}
@@ -0,0 +1,42 @@
+package uniqpool
+
+import "sync"
+
+// Group is a facade over UniqPool similar to errgroup.Group: tasks return errors and Wait returns the first one.
+// Unlike errgroup.Group, tasks with the same identifier are coalesced by the pool, so all the coalesced
+// calls of Go receive the error of a single execution. Several groups can share one pool.
+type Group[T comparable] struct {
+	pool *UniqPool[T]
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup creates a new Group executing tasks in the pool.
+func NewGroup[T comparable](pool *UniqPool[T]) *Group[T] {
+	return &Group[T]{pool: pool}
+}
+
+// Go adds a task to the pool. Will block if the inbound queue is full.
+// A panic of the task is reported as ErrTaskPanicked.
+func (g *Group[T]) Go(id T, fn func() error) {
+	g.wg.Add(1)
+	g.pool.SubmitWithResult(id,
+		func() (any, error) {
+			return nil, fn()
+		},
+		func(_ any, err error) {
+			if err != nil {
+				g.errOnce.Do(func() { g.err = err })
+			}
+			g.wg.Done()
+		})
+}
+
+// Wait waits for all tasks added by Go to be executed and returns the first error, if any.
+func (g *Group[T]) Wait() error {
+	g.wg.Wait()
+
+	return g.err
+}
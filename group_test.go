@@ -0,0 +1,41 @@
+package uniqpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroup checks that Wait returns the first error of the coalesced tasks.
+func TestGroup(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Millisecond*10)
+	defer pool.StopAndWait()
+
+	var executed int32
+	errFailed := errors.New("failed")
+
+	g := NewGroup(pool)
+	g.Go("task1", func() error {
+		atomic.AddInt32(&executed, 1)
+		return nil
+	})
+	g.Go("task2", func() error {
+		atomic.AddInt32(&executed, 1)
+		return errFailed
+	})
+	// coalesced with task2 and receives its error
+	g.Go("task2", func() error {
+		atomic.AddInt32(&executed, 1)
+		return nil
+	})
+
+	require.ErrorIs(t, g.Wait(), errFailed)
+	require.Equal(t, int32(2), atomic.LoadInt32(&executed))
+
+	g = NewGroup(pool)
+	g.Go("task1", func() error { return nil })
+	require.NoError(t, g.Wait())
+}
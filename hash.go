@@ -0,0 +1,99 @@
+package uniqpool
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// newDefaultHasher returns a hash function for task identifiers, consistent with their equality: equal identifiers
+// always have equal hashes. Identifiers of the basic types are hashed directly, other types are hashed by reflection.
+// Pointers and channels are hashed by address, not by the value they point to, so a key whose pointee changes
+// keeps its hash. Floats are normalized, so 0.0 and -0.0 have equal hashes.
+func newDefaultHasher[T comparable]() func(T) uint64 {
+	seed := maphash.MakeSeed()
+
+	return func(id T) uint64 {
+		var h maphash.Hash
+		h.SetSeed(seed)
+
+		switch v := any(id).(type) {
+		case string:
+			_, _ = h.WriteString(v)
+		case int:
+			writeUint64(&h, uint64(v))
+		case int32:
+			writeUint64(&h, uint64(v))
+		case int64:
+			writeUint64(&h, uint64(v))
+		case uint:
+			writeUint64(&h, uint64(v))
+		case uint32:
+			writeUint64(&h, uint64(v))
+		case uint64:
+			writeUint64(&h, v)
+		default:
+			hashValue(&h, reflect.ValueOf(v))
+		}
+
+		return h.Sum64()
+	}
+}
+
+// hashValue writes the value of a comparable type to the hash, so that equal values produce equal hashes.
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		// nil interface
+		writeUint64(h, 0)
+	case reflect.String:
+		_, _ = h.WriteString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint64(h, 1)
+		} else {
+			writeUint64(h, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat64(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat64(h, real(c))
+		writeFloat64(h, imag(c))
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		writeUint64(h, uint64(v.Pointer()))
+	case reflect.Interface:
+		hashValue(h, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			// blank fields are ignored by the equality
+			if t.Field(i).Name != "_" {
+				hashValue(h, v.Field(i))
+			}
+		}
+	}
+}
+
+// writeFloat64 writes the float to the hash. Negative zero is written as zero, as they are equal.
+func writeFloat64(h *maphash.Hash, f float64) {
+	if f == 0 {
+		f = 0
+	}
+	writeUint64(h, math.Float64bits(f))
+}
+
+func writeUint64(h *maphash.Hash, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	_, _ = h.Write(b[:])
+}
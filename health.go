@@ -0,0 +1,82 @@
+package uniqpool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrDispatcherStalled is returned by Healthy if the dispatcher goroutine is not running or has not ticked for
+	// several intervals while there are pending tasks.
+	ErrDispatcherStalled = errors.New("dispatcher is stalled")
+	// ErrStaleBacklog is returned by Healthy if the oldest pending task is older than the stale threshold
+	// (see WithStaleBacklog).
+	ErrStaleBacklog = errors.New("backlog is stale")
+)
+
+// healthyMissedTicks is the number of intervals without a tick after which the dispatcher is considered stalled.
+const healthyMissedTicks = 3
+
+// Healthy returns nil if the pool is able to process tasks, so it can be used directly in HTTP readiness
+// and liveness probes. Returns ErrPoolStopped if the pool is stopped, an error wrapping ErrDispatcherStalled
+// if the dispatcher goroutine is not running or the ticker has not fired for several intervals while the pool
+// is awake, and ErrStaleBacklog if the backlog is stale (see WithStaleBacklog).
+func (p *UniqPool[T]) Healthy() error {
+	if p.Stopped() {
+		return ErrPoolStopped
+	}
+
+	if atomic.LoadInt32(&p.dispatching) == 0 {
+		return fmt.Errorf("%w: dispatcher goroutine is not running", ErrDispatcherStalled)
+	}
+
+	// an idle pool does not tick
+	if atomic.LoadInt32(&p.idle) == 0 {
+		sinceTick := p.clock.Now().Sub(time.Unix(0, atomic.LoadInt64(&p.tickedAt)))
+		if sinceTick > healthyMissedTicks*p.interval {
+			return fmt.Errorf("%w: no tick for %v with interval %v", ErrDispatcherStalled, sinceTick, p.interval)
+		}
+	}
+
+	if atomic.LoadInt32(&p.stale) == 1 {
+		return ErrStaleBacklog
+	}
+
+	return nil
+}
+
+// beat records that the dispatcher has ticked or has been woken up.
+func (p *UniqPool[T]) beat() {
+	atomic.StoreInt64(&p.tickedAt, p.clock.Now().UnixNano())
+}
+
+// Heartbeat contains the recent activity of the dispatcher goroutine (see UniqPool.Heartbeat).
+type Heartbeat struct {
+	// The time the dispatcher loop last started waiting for a tick or a signal. Zero if it has not run yet.
+	LastLoop time.Time
+	// The time of the last dispatch of tasks to the worker pool. Zero if no task has been dispatched.
+	LastDispatch time.Time
+	// True if the inbound queue was empty and the dispatcher stopped ticking, so LastLoop is expected to be old.
+	Idle bool
+}
+
+// Heartbeat returns the recent activity of the dispatcher, so external watchdogs can detect a wedged dispatcher
+// goroutine: an awake dispatcher starts a loop iteration at least once per interval.
+func (p *UniqPool[T]) Heartbeat() Heartbeat {
+	return Heartbeat{
+		LastLoop:     unixNanoTime(atomic.LoadInt64(&p.loopAt)),
+		LastDispatch: unixNanoTime(atomic.LoadInt64(&p.dispatchedAt)),
+		Idle:         atomic.LoadInt32(&p.idle) == 1,
+	}
+}
+
+// unixNanoTime converts Unix nanoseconds to the time. Zero is converted to the zero time.
+func unixNanoTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
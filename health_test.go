@@ -0,0 +1,54 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestHealthy checks that a stalled ticker, a stale backlog and a stopped pool are reported.
+func TestHealthy(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Millisecond*10, WithClock[string](clock))
+	require.NoError(t, pool.Healthy())
+
+	// the manual ticker never fires by itself
+	pool.Submit("task1", func() {})
+	require.NoError(t, pool.Healthy())
+	time.Sleep(time.Millisecond * 50)
+	require.ErrorIs(t, pool.Healthy(), ErrDispatcherStalled)
+
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return pool.Healthy() == nil }, time.Second, time.Millisecond)
+
+	pool.StopAndWait()
+	require.ErrorIs(t, pool.Healthy(), ErrPoolStopped)
+
+	pool = MustNew[string](10, 2, 10, time.Hour, WithStaleBacklog[string](time.Millisecond*20, nil))
+	pool.Submit("task1", func() {})
+	require.Eventually(t, func() bool { return pool.Healthy() != nil }, time.Second, time.Millisecond)
+	require.ErrorIs(t, pool.Healthy(), ErrStaleBacklog)
+	pool.StopAndWait()
+}
+
+// TestHeartbeat checks that the loop and the dispatch times are recorded.
+func TestHeartbeat(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock))
+	require.Eventually(t, func() bool { return !pool.Heartbeat().LastLoop.IsZero() }, time.Second, time.Millisecond)
+
+	hb := pool.Heartbeat()
+	require.True(t, hb.Idle)
+	require.True(t, hb.LastDispatch.IsZero())
+
+	started := time.Now()
+	pool.Submit("task1", func() {})
+	require.False(t, pool.Heartbeat().Idle)
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return !pool.Heartbeat().LastDispatch.Before(started) },
+		time.Second, time.Millisecond)
+	require.False(t, pool.Heartbeat().LastLoop.Before(hb.LastLoop))
+
+	pool.StopAndWait()
+}
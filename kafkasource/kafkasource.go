@@ -0,0 +1,42 @@
+// Package kafkasource adapts a Kafka consumer to uniqpool.Source.
+//
+// The package does not depend on a particular Kafka client. Reader is satisfied by *kafka.Reader
+// of github.com/segmentio/kafka-go as is:
+//
+//	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, GroupID: "group", Topic: "topic"})
+//	src := kafkasource.New[kafka.Message](reader)
+//	err := uniqpool.Consume(ctx, pool, src, func(m kafka.Message) string { return string(m.Key) }, handler, nil)
+//
+// Kafka commits offsets, not individual messages: committing a message also commits all previous messages
+// of its partition. Since coalesced tasks are executed out of order, use it with at-least-once handlers.
+package kafkasource
+
+import "context"
+
+// Reader is a Kafka consumer that fetches messages without committing them.
+type Reader[M any] interface {
+	// FetchMessage returns the next message without committing it.
+	FetchMessage(ctx context.Context) (M, error)
+	// CommitMessages commits the messages.
+	CommitMessages(ctx context.Context, msgs ...M) error
+}
+
+// Source is a uniqpool.Source reading messages from Kafka.
+type Source[M any] struct {
+	reader Reader[M]
+}
+
+// New creates a new Source.
+func New[M any](reader Reader[M]) *Source[M] {
+	return &Source[M]{reader: reader}
+}
+
+// Receive blocks until the next message is available.
+func (s *Source[M]) Receive(ctx context.Context) (M, error) {
+	return s.reader.FetchMessage(ctx)
+}
+
+// Ack commits the message.
+func (s *Source[M]) Ack(ctx context.Context, msg M) error {
+	return s.reader.CommitMessages(ctx, msg)
+}
@@ -0,0 +1,117 @@
+package kafkasource
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n-r-w/uniqpool"
+	"github.com/stretchr/testify/require"
+)
+
+type message struct {
+	key    string
+	offset int
+}
+
+// fakeReader is a Reader returning the messages one by one and blocking when they are exhausted.
+type fakeReader struct {
+	mu        sync.Mutex
+	msgs      []message
+	fetchErr  error
+	commitErr error
+	committed []int
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (message, error) {
+	r.mu.Lock()
+	if len(r.msgs) > 0 {
+		msg := r.msgs[0]
+		r.msgs = r.msgs[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	err := r.fetchErr
+	r.mu.Unlock()
+
+	if err != nil {
+		return message{}, err
+	}
+	<-ctx.Done()
+	return message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.commitErr != nil {
+		return r.commitErr
+	}
+	for _, msg := range msgs {
+		r.committed = append(r.committed, msg.offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) committedOffsets() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]int(nil), r.committed...)
+}
+
+// TestSource checks that the messages are committed only after their task is executed without an error.
+func TestSource(t *testing.T) {
+	reader := &fakeReader{msgs: []message{{"a", 1}, {"a", 2}, {"b", 3}, {"fail", 4}}}
+	pool := uniqpool.MustNew[string](10, 2, 10, time.Millisecond*10)
+
+	gate := make(chan struct{})
+	executed := make(chan string, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- uniqpool.Consume(ctx, pool, uniqpool.Source[message](New[message](reader)),
+			func(msg message) string { return msg.key },
+			func(_ context.Context, id string, _ message) error {
+				executed <- id
+				<-gate
+				if id == "fail" {
+					return errors.New("handler failed")
+				}
+				return nil
+			}, nil)
+	}()
+
+	<-executed
+	<-executed
+	require.Empty(t, reader.committedOffsets())
+
+	close(gate)
+	<-executed
+	pool.StopAndWait()
+	cancel()
+	require.NoError(t, <-done)
+	require.ElementsMatch(t, []int{1, 2, 3}, reader.committedOffsets())
+}
+
+// TestSourceErrors checks that the fetch error stops the consumption and the commit errors are reported.
+func TestSourceErrors(t *testing.T) {
+	fetchErr := errors.New("broker unavailable")
+	commitErr := errors.New("rebalance in progress")
+	reader := &fakeReader{msgs: []message{{"a", 1}}, fetchErr: fetchErr, commitErr: commitErr}
+	pool := uniqpool.MustNew[string](10, 2, 10, time.Millisecond*10)
+
+	ackErrors := make(chan error, 1)
+	err := uniqpool.Consume(context.Background(), pool, uniqpool.Source[message](New[message](reader)),
+		func(msg message) string { return msg.key },
+		func(context.Context, string, message) error { return nil },
+		func(err error) { ackErrors <- err })
+	require.ErrorIs(t, err, fetchErr)
+
+	pool.StopAndWait()
+	require.ErrorIs(t, <-ackErrors, commitErr)
+	require.Empty(t, reader.committedOffsets())
+}
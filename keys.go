@@ -0,0 +1,35 @@
+package uniqpool
+
+// Key2 is a composite task identifier of two parts, e.g. (namespace, id).
+type Key2[A, B comparable] struct {
+	A A
+	B B
+}
+
+// Key3 is a composite task identifier of three parts.
+type Key3[A, B, C comparable] struct {
+	A A
+	B B
+	C C
+}
+
+// Submit2 adds a task with the composite identifier (a, b) to the pool. Will block if the inbound queue is full.
+func Submit2[A, B comparable](p *UniqPool[Key2[A, B]], a A, b B, fn func()) {
+	p.Submit(Key2[A, B]{A: a, B: b}, fn)
+}
+
+// TrySubmit2 adds a task with the composite identifier (a, b) to the pool. Returns false if the inbound queue is full.
+func TrySubmit2[A, B comparable](p *UniqPool[Key2[A, B]], a A, b B, fn func()) bool {
+	return p.TrySubmit(Key2[A, B]{A: a, B: b}, fn)
+}
+
+// Submit3 adds a task with the composite identifier (a, b, c) to the pool. Will block if the inbound queue is full.
+func Submit3[A, B, C comparable](p *UniqPool[Key3[A, B, C]], a A, b B, c C, fn func()) {
+	p.Submit(Key3[A, B, C]{A: a, B: b, C: c}, fn)
+}
+
+// TrySubmit3 adds a task with the composite identifier (a, b, c) to the pool.
+// Returns false if the inbound queue is full.
+func TrySubmit3[A, B, C comparable](p *UniqPool[Key3[A, B, C]], a A, b B, c C, fn func()) bool {
+	return p.TrySubmit(Key3[A, B, C]{A: a, B: b, C: c}, fn)
+}
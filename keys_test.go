@@ -0,0 +1,31 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompositeKeys checks that tasks are coalesced by all parts of the composite identifier.
+func TestCompositeKeys(t *testing.T) {
+	pool := MustNew[Key2[string, int]](10, 2, 10, time.Hour)
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	Submit2(pool, "users", 1, fn)
+	Submit2(pool, "users", 1, fn)
+	Submit2(pool, "orders", 1, fn)
+	require.True(t, TrySubmit2(pool, "users", 2, fn))
+
+	pool.StopAndWait()
+	require.Equal(t, int32(3), processed)
+
+	pool3 := MustNew[Key3[string, string, int]](10, 2, 10, time.Hour)
+	Submit3(pool3, "tenant", "users", 1, fn)
+	require.True(t, TrySubmit3(pool3, "tenant", "users", 1, fn))
+	pool3.StopAndWait()
+	require.Equal(t, int32(4), processed)
+}
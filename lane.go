@@ -0,0 +1,59 @@
+package uniqpool
+
+import (
+	"fmt"
+	"time"
+)
+
+// laneSpec is the configuration of a lane (see WithLane).
+type laneSpec struct {
+	name          string
+	interval      time.Duration
+	queueCapacity int
+}
+
+// SubmitLane adds a task to the lane configured by WithLane. Will block if the inbound queue of the lane is full.
+// Identifiers are deduplicated within the lane, so the same identifier submitted to different lanes is
+// executed twice. Panics if the lane is not configured.
+func (p *UniqPool[T]) SubmitLane(lane string, id T, fn func()) {
+	p.lane(lane).Submit(id, fn)
+}
+
+// TrySubmitLane adds a task to the lane like SubmitLane. Returns false if the inbound queue of the lane is full.
+func (p *UniqPool[T]) TrySubmitLane(lane string, id T, fn func()) bool {
+	return p.lane(lane).TrySubmit(id, fn)
+}
+
+// lane returns the pool of the lane. Panics if the lane is not configured.
+func (p *UniqPool[T]) lane(name string) *UniqPool[T] {
+	l, ok := p.lanes[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown lane %q", name))
+	}
+
+	return l
+}
+
+// newLanes creates the pools of the configured lanes. They execute tasks on the executor of the pool
+// and are configured with its options.
+func (p *UniqPool[T]) newLanes(poolWorkersCount, poolCapacity int, opts []Option[T]) {
+	if len(p.laneSpecs) == 0 {
+		return
+	}
+
+	laneOpts := append(append([]Option[T]{}, opts...), withoutLanes[T](), withSharedExecutor[T](p.executor))
+	p.lanes = make(map[string]*UniqPool[T], len(p.laneSpecs))
+	for _, spec := range p.laneSpecs {
+		l := MustNew(spec.queueCapacity, poolWorkersCount, poolCapacity, spec.interval, laneOpts...)
+		// the weights of the tasks of all the lanes are limited by the same workers
+		l.weights = p.weights
+		p.lanes[spec.name] = l
+	}
+}
+
+// withoutLanes removes the lanes configured by the previous options, so a lane does not create its own lanes.
+func withoutLanes[T comparable]() Option[T] {
+	return func(p *UniqPool[T]) {
+		p.laneSpecs = nil
+	}
+}
@@ -0,0 +1,37 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLanes checks that the lanes accumulate tasks with their own intervals and share the workers of the pool.
+func TestLanes(t *testing.T) {
+	pool := MustNew(10, 1, 10, time.Hour, WithLane[string]("interactive", time.Millisecond, 10))
+
+	done := make(chan struct{})
+	pool.Submit("bulk", func() {})
+	pool.SubmitLane("interactive", "task1", func() { close(done) })
+	pool.SubmitLane("interactive", "task1", func() {})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the interactive task is not executed")
+	}
+
+	stats := pool.Stats()
+	require.Equal(t, uint64(2), stats.Submitted)
+	require.Equal(t, uint64(1), stats.Coalesced)
+	require.Equal(t, 1, stats.Pending)
+	require.Panics(t, func() { pool.SubmitLane("unknown", "task2", func() {}) })
+
+	pool.StopAndWait()
+	require.Equal(t, uint64(2), pool.Stats().Dispatched)
+
+	require.NoError(t, pool.Start())
+	require.True(t, pool.TrySubmitLane("interactive", "task3", func() {}))
+	require.Equal(t, []string{"task3"}, pool.StopNow())
+}
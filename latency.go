@@ -0,0 +1,95 @@
+package uniqpool
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets is the number of buckets of latencyHistogram. The last bucket starts at 2^38 µs, about 3 days.
+const latencyBuckets = 40
+
+// latencyHistogram is a lock-free histogram of durations with exponential buckets.
+// Bucket 0 counts durations below 1 µs, bucket i counts durations in [2^(i-1), 2^i) µs.
+type latencyHistogram struct {
+	// Updated atomically.
+	buckets [latencyBuckets]uint64
+}
+
+// record adds the duration to the histogram.
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	i := bits.Len64(uint64(d / time.Microsecond))
+	if i >= latencyBuckets {
+		i = latencyBuckets - 1
+	}
+
+	atomic.AddUint64(&h.buckets[i], 1)
+}
+
+// quantile returns the estimated duration below which the q fraction of the recorded durations falls.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	return h.snapshot().quantile(q)
+}
+
+// latencyCounts is a snapshot of the buckets of latencyHistogram.
+type latencyCounts [latencyBuckets]uint64
+
+// snapshot returns the current counts of the buckets.
+func (h *latencyHistogram) snapshot() latencyCounts {
+	var counts latencyCounts
+	for i := range counts {
+		counts[i] = atomic.LoadUint64(&h.buckets[i])
+	}
+
+	return counts
+}
+
+// sub returns the counts recorded since the base snapshot.
+func (c latencyCounts) sub(base latencyCounts) latencyCounts {
+	for i := range c {
+		c[i] -= base[i]
+	}
+
+	return c
+}
+
+// quantile returns the estimated duration below which the q fraction of the counted durations falls.
+// The duration is interpolated linearly inside the bucket. Returns 0 if nothing is counted.
+func (c latencyCounts) quantile(q float64) time.Duration {
+	var total uint64
+	for _, count := range c {
+		total += count
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var seen uint64
+	for i, count := range c {
+		if count == 0 || float64(seen+count) < rank {
+			seen += count
+			continue
+		}
+
+		lower, upper := latencyBucketBounds(i)
+		return lower + time.Duration(float64(upper-lower)*(rank-float64(seen))/float64(count))
+	}
+
+	_, upper := latencyBucketBounds(latencyBuckets - 1)
+	return upper
+}
+
+// latencyBucketBounds returns the range of the durations counted by the bucket.
+func latencyBucketBounds(i int) (lower, upper time.Duration) {
+	if i == 0 {
+		return 0, time.Microsecond
+	}
+
+	return time.Duration(1<<(i-1)) * time.Microsecond, time.Duration(1<<i) * time.Microsecond
+}
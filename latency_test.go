@@ -0,0 +1,41 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLatencyHistogram checks the estimation of the percentiles.
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	require.Zero(t, h.quantile(0.5))
+
+	for i := 0; i < 90; i++ {
+		h.record(time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.record(time.Second)
+	}
+
+	// the estimation is within the bucket of the recorded value, which is at most 2x wide
+	require.InDelta(t, float64(time.Millisecond), float64(h.quantile(0.5)), float64(time.Millisecond))
+	require.InDelta(t, float64(time.Second), float64(h.quantile(0.99)), float64(time.Second))
+}
+
+// TestLatencyStats checks that the latency includes the accumulation interval.
+func TestLatencyStats(t *testing.T) {
+	pool := MustNew[int](10, 2, 10, time.Millisecond*50)
+
+	for i := 0; i < 5; i++ {
+		pool.Submit(i, func() {})
+	}
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 5 }, time.Second, time.Millisecond)
+	pool.StopAndWait()
+
+	stats := pool.Stats()
+	// the tasks wait for the first tick, the estimation is within the 2x wide bucket
+	require.GreaterOrEqual(t, stats.LatencyP50, time.Millisecond*25)
+	require.GreaterOrEqual(t, stats.LatencyP99, stats.LatencyP50)
+}
@@ -0,0 +1,42 @@
+package uniqpool
+
+import "time"
+
+// SubmitWithMaxWait adds a task to the pool like Submit, guaranteeing that it is dispatched within maxWait
+// even if it is shorter than the interval: the inbound queue is flushed early when needed. The guarantee also
+// applies to the pending task the new one is coalesced with. The early flush dispatches all the pending tasks
+// regardless of WithMaxDrainBatch.
+func (p *UniqPool[T]) SubmitWithMaxWait(id T, maxWait time.Duration, fn func()) {
+	switch mustSubmit(p.submit(task[T]{id: id, fn: fn}, true)) {
+	case Enqueued, Coalesced:
+		p.flushBy(p.clock.Now().Add(maxWait))
+	}
+}
+
+// flushBy makes the dispatcher flush the inbound queue no later than the deadline.
+func (p *UniqPool[T]) flushBy(deadline time.Time) {
+	p.flushMutex.Lock()
+	defer p.flushMutex.Unlock()
+
+	if p.flushTimer != nil {
+		if !deadline.Before(p.flushAt) || !p.flushTimer.Stop() {
+			// the flush is already requested earlier or is being requested right now
+			return
+		}
+	}
+
+	p.flushAt = deadline
+	p.flushTimer = p.clock.AfterFunc(deadline.Sub(p.clock.Now()), p.requestFlush)
+}
+
+// requestFlush wakes the dispatcher up to flush the inbound queue before the next tick.
+func (p *UniqPool[T]) requestFlush() {
+	p.flushMutex.Lock()
+	p.flushTimer = nil
+	p.flushMutex.Unlock()
+
+	select {
+	case p.flushChan <- struct{}{}:
+	default:
+	}
+}
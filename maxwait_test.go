@@ -0,0 +1,42 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubmitWithMaxWait checks that the task is dispatched within the max wait shorter than the interval.
+func TestSubmitWithMaxWait(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Hour)
+
+	done := make(chan string, 2)
+	pool.Submit("task1", func() { done <- "task1" })
+	start := time.Now()
+	pool.SubmitWithMaxWait("task2", time.Millisecond*50, func() { done <- "task2" })
+
+	// the early flush dispatches the other pending tasks too
+	require.ElementsMatch(t, []string{"task1", "task2"}, []string{<-done, <-done})
+	require.GreaterOrEqual(t, time.Since(start), time.Millisecond*50)
+
+	pool.StopAndWait()
+}
+
+// TestMaxQueueLatency checks that the accepted tasks are dispatched within the max latency regardless of the interval
+// and the drain batch limit.
+func TestMaxQueueLatency(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Hour, WithMaxQueueLatency[string](time.Millisecond*50),
+		WithMaxDrainBatch[string](1))
+
+	done := make(chan string, 3)
+	for _, id := range []string{"task1", "task2", "task3"} {
+		id := id
+		pool.Submit(id, func() { done <- id })
+	}
+
+	require.ElementsMatch(t, []string{"task1", "task2", "task3"}, []string{<-done, <-done, <-done})
+	require.Zero(t, pool.Stats().Pending)
+
+	pool.StopAndWait()
+}
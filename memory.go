@@ -0,0 +1,74 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// reserveMemory adds the estimated size of the task to the pending memory. Returns false if the memory budget
+// would be exceeded. A task is always accepted if nothing else is pending, so a task larger than the budget
+// does not block the pool forever.
+func (p *UniqPool[T]) reserveMemory(t *task[T]) bool {
+	if p.memoryBudget <= 0 {
+		return true
+	}
+
+	size := p.taskSize(t)
+	pending := atomic.AddInt64(&p.pendingBytes, size)
+	if pending > p.memoryBudget && pending != size {
+		atomic.AddInt64(&p.pendingBytes, -size)
+		return false
+	}
+	t.size = size
+
+	return true
+}
+
+// waitMemory waits until the memory budget can be reserved for the task, like a submitter waits for the space
+// in the full inbound queue.
+func (p *UniqPool[T]) waitMemory(t *task[T]) {
+	// counted before the check, so the memory released after the check wakes the submitter up
+	atomic.AddInt32(&p.memoryWaiters, 1)
+	defer atomic.AddInt32(&p.memoryWaiters, -1)
+
+	p.memoryCond.L.Lock()
+	defer p.memoryCond.L.Unlock()
+
+	for !p.reserveMemory(t) {
+		p.memoryCond.Wait()
+	}
+}
+
+// releaseMemory removes the size of the task that left the inbound queue from the pending memory.
+func (p *UniqPool[T]) releaseMemory(t task[T]) {
+	if t.size == 0 {
+		return
+	}
+
+	atomic.AddInt64(&p.pendingBytes, -t.size)
+	if atomic.LoadInt32(&p.memoryWaiters) > 0 {
+		p.memoryCond.L.Lock()
+		p.memoryCond.Broadcast()
+		p.memoryCond.L.Unlock()
+	}
+}
+
+// taskSize returns the estimated memory used by the pending task.
+func (p *UniqPool[T]) taskSize(t *task[T]) int64 {
+	if p.sizeFn != nil {
+		return p.sizeFn(t.id, t.payload)
+	}
+
+	return keySize(t.id)
+}
+
+// keySize returns the estimated memory used by the task identifier: the size of the value
+// plus the bytes of the string, if the identifier is a string.
+func keySize[T comparable](id T) int64 {
+	size := int64(unsafe.Sizeof(id))
+	if s, ok := any(id).(string); ok {
+		size += int64(len(s))
+	}
+
+	return size
+}
@@ -0,0 +1,60 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryBudget checks that the tasks exceeding the memory budget are rejected.
+func TestMemoryBudget(t *testing.T) {
+	size := func(id string, _ any) int64 { return int64(len(id)) * 10 }
+	pool := MustNew(10, 2, 10, time.Hour, WithMemoryBudget(100, size))
+
+	fn := func() {}
+
+	require.NoError(t, pool.TrySubmitErr("aaaa", fn))
+	require.NoError(t, pool.TrySubmitErr("bbbbb", fn))
+	require.ErrorIs(t, pool.TrySubmitErr("cc", fn), ErrQueueFull)
+	require.Equal(t, int64(90), pool.Stats().PendingBytes)
+
+	pool.StopAndWait()
+	require.Zero(t, pool.Stats().PendingBytes)
+
+	// a single task larger than the budget is accepted if nothing else is pending
+	pool = MustNew(10, 2, 10, time.Hour, WithMemoryBudget(10, size))
+	require.NoError(t, pool.TrySubmitErr("aaaa", fn))
+	pool.StopAndWait()
+}
+
+// TestMemoryBudgetBlocks checks that Submit waits for the memory budget and the size function receives the payload.
+func TestMemoryBudgetBlocks(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock),
+		WithMemoryBudget(100, func(_ string, payload any) int64 { return int64(payload.(int)) }))
+
+	payloads, err := NewPayloadPool(pool, func(string, int) {})
+	require.NoError(t, err)
+	require.NoError(t, payloads.Submit("task1", 60))
+
+	submitted := make(chan error)
+	go func() { submitted <- payloads.Submit("task2", 50) }()
+	select {
+	case <-submitted:
+		require.FailNow(t, "the task exceeding the memory budget is accepted")
+	case <-time.After(time.Millisecond * 50):
+	}
+	require.Equal(t, int64(60), pool.Stats().PendingBytes)
+
+	clock.tickChan <- time.Now()
+	require.NoError(t, <-submitted)
+	require.NoError(t, payloads.StopAndWait())
+	require.Zero(t, pool.Stats().PendingBytes)
+}
+
+// TestKeySize checks the default estimation of the identifier size.
+func TestKeySize(t *testing.T) {
+	require.Equal(t, int64(8), keySize(int64(1)))
+	require.Equal(t, int64(16+5), keySize("hello"))
+}
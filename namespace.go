@@ -0,0 +1,16 @@
+package uniqpool
+
+import "strings"
+
+// PrefixClassifier returns a namespace classifier for string identifiers. The namespace of an identifier
+// is the first of the prefixes it starts with, or an empty string if none matches.
+func PrefixClassifier(prefixes ...string) func(id string) string {
+	return func(id string) string {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(id, prefix) {
+				return prefix
+			}
+		}
+		return ""
+	}
+}
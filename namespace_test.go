@@ -0,0 +1,42 @@
+package uniqpool
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNamespaceQuotas checks that a namespace cannot exceed its quota of pending tasks.
+func TestNamespaceQuotas(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Millisecond*100,
+		WithNamespaceQuotas(PrefixClassifier("flood:", "other:"), map[string]int{"flood:": 2}))
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	require.True(t, pool.TrySubmit("flood:1", fn))
+	require.True(t, pool.TrySubmit("flood:2", fn))
+	// quota is exhausted
+	require.False(t, pool.TrySubmit("flood:3", fn))
+	// duplicates are still coalesced
+	require.True(t, pool.TrySubmit("flood:1", fn))
+
+	// other namespaces are not affected
+	for i := 0; i < 5; i++ {
+		require.True(t, pool.TrySubmit("other:"+strconv.Itoa(i), fn))
+	}
+	require.True(t, pool.TrySubmit("unclassified", fn))
+
+	// waits for the quota to be freed
+	now := time.Now()
+	pool.Submit("flood:3", fn)
+	require.Greater(t, time.Since(now).Milliseconds(), int64(50))
+
+	pool.StopAndWait()
+
+	require.Equal(t, int32(9), processed)
+	require.Zero(t, pool.pending())
+}
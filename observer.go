@@ -0,0 +1,153 @@
+package uniqpool
+
+import "sync"
+
+// defaultEventsBuffer is the capacity of the channel returned by Events.
+const defaultEventsBuffer = 1024
+
+// EventType is the type of a lifecycle event of the pool.
+type EventType int
+
+const (
+	// EventEnqueued means that the task is added to the inbound queue.
+	EventEnqueued EventType = iota
+	// EventCoalesced means that the task is coalesced with the pending task with the same identifier.
+	EventCoalesced
+	// EventDispatched means that the task is dispatched to the worker pool.
+	EventDispatched
+	// EventCompleted means that the task is executed successfully.
+	EventCompleted
+	// EventFailed means that the task panicked or returned an error.
+	EventFailed
+	// EventDropped means that the task is suppressed, rejected, discarded by StopNow or skipped by the quarantine.
+	EventDropped
+	// EventStopped means that the pool is stopped and all its tasks are completed.
+	EventStopped
+	// EventDispatcherRestarted means that the dispatcher goroutine panicked and is restarted.
+	// Err wraps ErrDispatcherPanicked and the panic value.
+	EventDispatcherRestarted
+	// EventWorkerHung means that the task has been executing longer than the hard limit (see WithWorkerSupervisor).
+	EventWorkerHung
+)
+
+// String returns the name of the event type.
+func (e EventType) String() string {
+	switch e {
+	case EventEnqueued:
+		return "enqueued"
+	case EventCoalesced:
+		return "coalesced"
+	case EventDispatched:
+		return "dispatched"
+	case EventCompleted:
+		return "completed"
+	case EventFailed:
+		return "failed"
+	case EventDropped:
+		return "dropped"
+	case EventStopped:
+		return "stopped"
+	case EventDispatcherRestarted:
+		return "dispatcher restarted"
+	case EventWorkerHung:
+		return "worker hung"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a lifecycle event of the pool.
+type Event[T comparable] struct {
+	Type EventType
+	// Identifier of the task. Zero for EventStopped and EventDispatcherRestarted.
+	ID T
+	// The error of the failed task, or the reason of the dropped task: ErrQueueFull, ErrPoolStopped or ErrQuarantined.
+	// Nil for the tasks dropped because of the suppression window.
+	Err error
+}
+
+// Observer receives the lifecycle events of the pool (see WithObserver), a single integration point
+// for metrics, logging and auditing backends.
+type Observer[T comparable] interface {
+	// Observe is called synchronously by the submitters and the workers, so it must be fast and must not block.
+	Observe(e Event[T])
+}
+
+// ObserverFunc is an adapter to use an ordinary function as an Observer.
+type ObserverFunc[T comparable] func(e Event[T])
+
+// Observe calls f(e).
+func (f ObserverFunc[T]) Observe(e Event[T]) {
+	f(e)
+}
+
+// eventStream delivers the lifecycle events to the channel returned by Events.
+type eventStream[T comparable] struct {
+	events chan Event[T]
+	// Serializes the senders, so the oldest event is dropped when the channel is full.
+	mutex sync.Mutex
+}
+
+// send adds the event to the channel, dropping the oldest one if it is full.
+func (s *eventStream[T]) send(e Event[T]) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for {
+		select {
+		case s.events <- e:
+			return
+		default:
+		}
+
+		select {
+		case <-s.events:
+		default:
+		}
+	}
+}
+
+// Events returns a channel emitting the same lifecycle events as the observer (see WithObserver), for consumers
+// preferring channel-based fan-out. The channel is bounded: if the consumer falls behind, the oldest events
+// are dropped. The events are emitted since the first call. The channel is never closed, EventStopped marks
+// the stop of the pool.
+func (p *UniqPool[T]) Events() <-chan Event[T] {
+	p.eventsOnce.Do(func() {
+		p.events.Store(&eventStream[T]{events: make(chan Event[T], defaultEventsBuffer)})
+	})
+
+	return p.events.Load().events
+}
+
+// observed returns true if the lifecycle events are observed.
+func (p *UniqPool[T]) observed() bool {
+	return p.observer != nil || p.events.Load() != nil
+}
+
+// observe passes the event to the observer and the events channel, if any.
+func (p *UniqPool[T]) observe(typ EventType, id T, err error) {
+	if p.observer != nil {
+		p.observer.Observe(Event[T]{Type: typ, ID: id, Err: err})
+	}
+	if s := p.events.Load(); s != nil {
+		s.send(Event[T]{Type: typ, ID: id, Err: err})
+	}
+}
+
+// observeOutcome passes the event corresponding to the outcome of the submission to the observer, if any.
+func (p *UniqPool[T]) observeOutcome(id T, outcome Outcome) {
+	if !p.observed() {
+		return
+	}
+
+	switch outcome {
+	case Enqueued:
+		p.observe(EventEnqueued, id, nil)
+	case Coalesced:
+		p.observe(EventCoalesced, id, nil)
+	case Suppressed:
+		p.observe(EventDropped, id, nil)
+	case Rejected:
+		p.observe(EventDropped, id, ErrQueueFull)
+	}
+}
@@ -0,0 +1,59 @@
+package uniqpool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestObserver checks that the observer receives the lifecycle events of the tasks and the pool.
+func TestObserver(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events = make(map[string][]EventType)
+	)
+
+	errFailed := errors.New("failed")
+	pool := MustNew(1, 1, 10, time.Hour, WithObserver[string](ObserverFunc[string](func(e Event[string]) {
+		if e.Type == EventFailed {
+			require.Equal(t, errFailed, e.Err)
+		}
+
+		mu.Lock()
+		events[e.ID] = append(events[e.ID], e.Type)
+		mu.Unlock()
+	})))
+
+	pool.Submit("ok", func() {})
+	pool.Submit("ok", func() {})
+	require.False(t, pool.TrySubmit("full", func() {}))
+	pool.StopAndWait()
+
+	require.NoError(t, pool.Start())
+	pool.SubmitWithResult("failed", func() (any, error) { return nil, errFailed }, func(any, error) {})
+	pool.StopAndWait()
+
+	require.Equal(t, map[string][]EventType{
+		"ok":     {EventEnqueued, EventCoalesced, EventDispatched, EventCompleted},
+		"full":   {EventDropped},
+		"failed": {EventEnqueued, EventDispatched, EventFailed},
+		"":       {EventStopped, EventStopped},
+	}, events)
+}
+
+// TestEvents checks that the events channel drops the oldest events when it is full.
+func TestEvents(t *testing.T) {
+	pool := MustNew[int](defaultEventsBuffer*2, 2, 10, time.Hour)
+	events := pool.Events()
+
+	for i := 0; i < defaultEventsBuffer+1; i++ {
+		pool.Submit(i, func() {})
+	}
+	require.Len(t, events, defaultEventsBuffer)
+	require.Equal(t, Event[int]{Type: EventEnqueued, ID: 1}, <-events)
+
+	pool.StopAndWait()
+}
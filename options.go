@@ -0,0 +1,616 @@
+package uniqpool
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Option configures a UniqPool. New returns an error wrapping ErrInvalidParameters if an option has an invalid value.
+type Option[T comparable] func(*UniqPool[T])
+
+// invalidOption records the error of an option with an invalid value, which is returned by New.
+// Only the first error is kept.
+func (p *UniqPool[T]) invalidOption(format string, args ...any) {
+	if p.optionErr == nil {
+		p.optionErr = fmt.Errorf("%w: %s", ErrInvalidParameters, fmt.Sprintf(format, args...))
+	}
+}
+
+// ReleasePoint defines the moment when the task identifier is removed from the dedup set,
+// so a new task with the same identifier is no longer coalesced with it.
+type ReleasePoint int
+
+const (
+	// ReleaseOnDispatch releases the identifier when the task is sent to the worker pool (default).
+	// A task submitted while the previous one with the same identifier is waiting in the worker pool queue
+	// or running is executed once more.
+	ReleaseOnDispatch ReleasePoint = iota
+	// ReleaseOnCompletion releases the identifier after the task is completed. Tasks submitted while the previous
+	// one with the same identifier is waiting in the worker pool queue or running are coalesced with it.
+	ReleaseOnCompletion
+)
+
+// WithKeyRelease sets the moment when the task identifier is removed from the dedup set.
+func WithKeyRelease[T comparable](point ReleasePoint) Option[T] {
+	return func(p *UniqPool[T]) {
+		if point != ReleaseOnDispatch && point != ReleaseOnCompletion {
+			p.invalidOption("unknown key release point %d", point)
+			return
+		}
+		p.keyRelease = point
+	}
+}
+
+// WithSerialKeys guarantees that tasks with the same identifier are never executed concurrently.
+// If a task is dispatched while a task with the same identifier is still running, it waits for the running
+// one to finish and is executed right after it. While waiting, the task stays in the inbound dedup set,
+// so further duplicates are coalesced with it.
+func WithSerialKeys[T comparable]() Option[T] {
+	return func(p *UniqPool[T]) {
+		p.serialKeys = true
+	}
+}
+
+// WithKeySharding executes tasks on a fixed set of workers instead of the elastic worker pool.
+// Each task identifier is bound to one worker by its hash, so all tasks with the same identifier are executed
+// in submission order on the same goroutine. The worker pool capacity is split evenly between the workers.
+// If hash is nil, the default hash function is used.
+func WithKeySharding[T comparable](hash func(T) uint64) Option[T] {
+	return func(p *UniqPool[T]) {
+		if hash == nil {
+			hash = newDefaultHasher[T]()
+		}
+		p.shardHash = hash
+	}
+}
+
+// WithOrderedDispatch preserves the global submission order end-to-end: tasks are executed one by one
+// in the order they were submitted, the next task starts only after the previous one is completed.
+// The order of coalesced tasks is defined by the first submission. The workers count is ignored in this mode.
+func WithOrderedDispatch[T comparable]() Option[T] {
+	return func(p *UniqPool[T]) {
+		p.orderedDispatch = true
+	}
+}
+
+// WithSuppressionWindow drops new tasks whose identifier was executed less than window ago.
+// It provides "at most once per window per identifier" semantics in addition to the coalescing of pending tasks.
+// TrySubmit returns true for the dropped tasks, the same as for the coalesced ones. A zero window disables it.
+func WithSuppressionWindow[T comparable](window time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if window < 0 {
+			p.invalidOption("suppression window must not be negative, got %v", window)
+			return
+		}
+		p.suppressionWindow = window
+	}
+}
+
+// WithResultCache enables the cache of the results of the tasks added by SubmitWithResult.
+// Up to size successful results are kept for ttl, the least recently used results are evicted first.
+// A task resubmitted while its result is cached is not executed, the cached result is returned instead.
+func WithResultCache[T comparable](size int, ttl time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if size <= 0 || ttl <= 0 {
+			p.invalidOption("result cache size and ttl must be positive, got %d and %v", size, ttl)
+			return
+		}
+		p.resultCache = newResultCache[T](size, ttl)
+	}
+}
+
+// WithNamespaceQuotas limits the number of pending tasks per namespace, so that one source of tasks
+// cannot consume the entire inbound queue capacity shared by others. classify returns the namespace
+// of the task identifier (see PrefixClassifier), quotas contains the maximum number of pending tasks
+// for the namespaces. Namespaces without a quota are limited only by the inbound queue capacity.
+// When the quota is exhausted, TrySubmit returns false and Submit blocks. classify may be nil to use
+// the classifier of WithNamespaceProfiles. The quotas must be positive.
+func WithNamespaceQuotas[T comparable](classify func(id T) string, quotas map[string]int) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.namespaceClassifier = classify
+		p.namespaceSlots = make(map[string]chan struct{}, len(quotas))
+		for namespace, quota := range quotas {
+			if quota <= 0 {
+				p.invalidOption("quota of namespace %q must be positive, got %d", namespace, quota)
+				return
+			}
+			p.namespaceSlots[namespace] = make(chan struct{}, quota)
+		}
+	}
+}
+
+// withSharedExecutor makes the pool execute tasks on the executor shared with other pools.
+// The pool does not stop the shared executor.
+func withSharedExecutor[T comparable](e executor[T]) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.executor = e
+		p.sharedExecutor = true
+	}
+}
+
+// WithUniqStore makes the pool check the uniqueness of task identifiers also against the external store,
+// shared with other pools. An identifier is added to the store when the task is queued and removed
+// when it is dispatched. See the redisstore package for a store shared between service replicas.
+func WithUniqStore[T comparable](store UniqStore[T]) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.uniqStore = store
+	}
+}
+
+// WithClock sets the source of the current time and tickers used by the pool. By default the real time is used.
+// Tests can use a fake clock (see the fakeclock package) to advance time deterministically.
+func WithClock[T comparable](clock Clock) Option[T] {
+	return func(p *UniqPool[T]) {
+		if clock == nil {
+			p.invalidOption("clock must not be nil")
+			return
+		}
+		p.clock = clock
+	}
+}
+
+// WithDedupStripes splits the dedup state into n stripes by the hash of the task identifier, so concurrent
+// submitters of different identifiers do not contend for one mutex. If n is zero, the number of stripes is set
+// to GOMAXPROCS, which is also the default. n must not be negative. One stripe avoids hashing the identifiers,
+// which suits pools with few submitters.
+func WithDedupStripes[T comparable](n int) Option[T] {
+	return func(p *UniqPool[T]) {
+		switch {
+		case n < 0:
+			p.invalidOption("dedup stripes count must not be negative, got %d", n)
+			return
+		case n == 0:
+			n = runtime.GOMAXPROCS(0)
+		}
+		p.dedupStripes = n
+	}
+}
+
+// WithMaxDrainBatch limits the number of tasks dispatched from the inbound queue per interval, so the dispatcher
+// does not spin when producers keep the queue full. The rest of the tasks are dispatched on the next intervals.
+// By default the limit is the inbound queue capacity. On stop all tasks are dispatched regardless of the limit.
+// n must be positive.
+func WithMaxDrainBatch[T comparable](n int) Option[T] {
+	return func(p *UniqPool[T]) {
+		if n <= 0 {
+			p.invalidOption("drain batch limit must be positive, got %d", n)
+			return
+		}
+		p.maxBatch = int64(n)
+		p.maxBatchFixed = true
+	}
+}
+
+// WithStatsWindow sets the sliding window of the rolling statistics: the throughput and the dedup ratio.
+// The counters are sampled on each interval and on each Stats call, so the effective window is not shorter
+// than the interval. By default the window is 10 seconds. window must be positive.
+func WithStatsWindow[T comparable](window time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if window <= 0 {
+			p.invalidOption("stats window must be positive, got %v", window)
+			return
+		}
+		p.rates.window = window
+	}
+}
+
+// withParentContext binds the pool to the context, see NewWithContext.
+func withParentContext[T comparable](ctx context.Context) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.parentCtx = ctx
+	}
+}
+
+// WithShutdownGrace limits the time for finishing the backlog when the context of the pool created by
+// NewWithContext is cancelled. The tasks that are not started within the grace period are discarded
+// and the context of the running tasks added by SubmitContext is cancelled. By default, or if grace is zero,
+// the backlog is finished. grace must not be negative.
+func WithShutdownGrace[T comparable](grace time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if grace < 0 {
+			p.invalidOption("shutdown grace must not be negative, got %v", grace)
+			return
+		}
+		p.shutdownGrace = grace
+	}
+}
+
+// WithKeyNormalizer converts task identifiers to the canonical form before the dedup, so semantically equal
+// identifiers collide: e.g. lowercase strings, URLs without query parameters or rounded timestamps.
+// The tasks are executed and reported with the normalized identifiers.
+func WithKeyNormalizer[T comparable](normalize func(id T) T) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.keyNormalizer = normalize
+	}
+}
+
+// WithMemoryBudget limits the estimated memory of the tasks in the inbound queue to budget bytes, in addition
+// to the number of tasks. size returns the estimated memory used by the task with the identifier and the payload.
+// The payload is the one submitted to a PayloadPool, nil for the other tasks. If size is nil, only the identifier
+// is counted: its size plus the bytes of a string. A task exceeding the budget is handled like a task that does not
+// fit into the full inbound queue: TrySubmit returns false and Submit blocks until the memory is released.
+// budget must be positive.
+func WithMemoryBudget[T comparable](budget int64, size func(id T, payload any) int64) Option[T] {
+	return func(p *UniqPool[T]) {
+		if budget <= 0 {
+			p.invalidOption("memory budget must be positive, got %d", budget)
+			return
+		}
+		p.memoryBudget = budget
+		p.sizeFn = size
+	}
+}
+
+// WithPriorities dispatches the pending tasks in priority order (see SubmitWithPriority) instead of FIFO order.
+// Tasks with equal priority are dispatched in FIFO order. With a positive aging, a pending task gains one priority
+// level per aging period, so low-priority tasks eventually outrank a constant stream of high-priority ones.
+// Priorities matter when more tasks are pending than dispatched per interval (see WithMaxDrainBatch).
+// A duplicate with a higher priority than the pending task raises its priority, so the urgency is not lost.
+// A zero aging disables the aging, a negative one is invalid.
+func WithPriorities[T comparable](aging time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if aging < 0 {
+			p.invalidOption("priority aging must not be negative, got %v", aging)
+			return
+		}
+		p.priorities = true
+		p.priorityAging = aging
+	}
+}
+
+// WithCircuitBreaker pauses the dispatching when the tasks fail too often, so a failing downstream is not hammered
+// by the whole backlog. A task fails if it panics or, for the tasks added by SubmitWithResult, returns an error.
+// The breaker opens when at least minSamples tasks completed within the window and the fraction of failed ones
+// reaches threshold. After cooldown a single probe task is dispatched: if it succeeds, the dispatching resumes,
+// otherwise the breaker stays open for another cooldown. While the breaker is open the tasks stay in the inbound
+// queue, so Submit blocks and TrySubmit fails when it is full. On stop the backlog is dispatched regardless.
+// threshold must be within (0, 1], minSamples, window and cooldown must be positive.
+func WithCircuitBreaker[T comparable](threshold float64, minSamples int, window, cooldown time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if threshold <= 0 || threshold > 1 || minSamples <= 0 || window <= 0 || cooldown <= 0 {
+			p.invalidOption("circuit breaker threshold %v, min samples %d, window %v or cooldown %v is out of range",
+				threshold, minSamples, window, cooldown)
+			return
+		}
+		p.breaker = newCircuitBreaker(threshold, minSamples, window, cooldown)
+	}
+}
+
+// WithBatchDispatch passes all the tasks dispatched at once, i.e. per interval, to a single call of handler on one
+// worker instead of executing them one by one, e.g. to issue one bulk database write per interval. handler decides
+// whether to call the task functions. Only the tasks added by Submit and TrySubmit are batched: the tasks with
+// results or contexts are executed individually as usual.
+func WithBatchDispatch[T comparable](handler func(batch []KeyedTask[T])) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.batchHandler = handler
+	}
+}
+
+// WithRequeue resubmits the failed tasks added by SubmitRetryable after a backoff, which starts with baseDelay
+// and doubles with each consecutive failure of the identifier up to maxDelay. The requeued task is coalesced
+// with a newer submission of the identifier, if any. stop is called with the number of the previous requeues
+// of the identifier: if it returns true, the task is not requeued and the backoff is reset. If stop is nil,
+// the tasks are requeued until they succeed. The tasks requeued after the pool is stopped are dropped.
+// baseDelay must be positive and maxDelay must not be less than baseDelay.
+func WithRequeue[T comparable](baseDelay, maxDelay time.Duration, stop func(id T, err error, requeues int) bool,
+) Option[T] {
+	return func(p *UniqPool[T]) {
+		if baseDelay <= 0 || maxDelay < baseDelay {
+			p.invalidOption("requeue delays must be positive and ordered, got %v and %v", baseDelay, maxDelay)
+			return
+		}
+
+		p.requeue = &requeuer[T]{
+			baseDelay: baseDelay,
+			maxDelay:  maxDelay,
+			stop:      stop,
+			requeues:  make(map[T]int),
+		}
+	}
+}
+
+// WithQuarantine quarantines the identifiers of the tasks that fail failures times within the window, so one
+// malformed entity does not consume the workers and the retries forever. A task fails if it panics or returns
+// an error (see SubmitWithResult and SubmitRetryable). The tasks of a quarantined identifier are not executed:
+// new submissions are suppressed and the pending tasks are skipped, their result callbacks receive ErrQuarantined.
+// onQuarantine, if not nil, is called with the last error when an identifier is quarantined.
+// See Quarantined and Unquarantine. failures and window must be positive.
+func WithQuarantine[T comparable](failures int, window time.Duration, onQuarantine func(id T, err error)) Option[T] {
+	return func(p *UniqPool[T]) {
+		if failures <= 0 || window <= 0 {
+			p.invalidOption("quarantine failures and window must be positive, got %d and %v", failures, window)
+			return
+		}
+		p.quarantine = newQuarantine(failures, window, onQuarantine)
+	}
+}
+
+// WithObserver passes the lifecycle events of the tasks and the pool to the observer.
+func WithObserver[T comparable](observer Observer[T]) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.observer = observer
+	}
+}
+
+// WithName sets the name of the pool reported by String and DebugHandler, so pools can be told apart in logs.
+func WithName[T comparable](name string) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.name = name
+	}
+}
+
+// WithUnboundedQueue removes the capacity limit of the inbound queue for pipelines where blocking
+// the producers is unacceptable and memory is plentiful: Submit never blocks and TrySubmit is never rejected
+// because the queue is full. The inboundQueueCapacity parameter of New still sets the default number of tasks
+// dispatched per interval (see WithMaxDrainBatch). If softCap is positive, onSoftCap is called with the number
+// of pending tasks when the queue grows above softCap, and again only after it is drained below softCap.
+// A zero softCap disables the callback, otherwise softCap must be positive and onSoftCap must not be nil.
+func WithUnboundedQueue[T comparable](softCap int, onSoftCap func(pending int)) Option[T] {
+	return func(p *UniqPool[T]) {
+		if softCap < 0 || (softCap > 0 && onSoftCap == nil) {
+			p.invalidOption("soft cap must not be negative and needs a callback, got %d", softCap)
+			return
+		}
+		p.unbounded = true
+		if softCap > 0 {
+			p.softCap = softCap
+			p.onSoftCap = onSoftCap
+		}
+	}
+}
+
+// WithRingBuffer stores the tasks of the inbound queue in a preallocated ring buffer instead of a linked list,
+// which improves the cache behavior at very high submit rates at the cost of the memory for the whole capacity.
+// Ignored with WithUnboundedQueue.
+func WithRingBuffer[T comparable]() Option[T] {
+	return func(p *UniqPool[T]) {
+		p.ringBuffer = true
+	}
+}
+
+// WithMaxQueueLatency guarantees that no accepted task waits in the inbound queue longer than d before it is
+// dispatched, regardless of the interval and WithMaxDrainBatch, for freshness SLOs the interval alone cannot
+// express: the inbound queue is flushed early when its oldest task is about to become overdue. d must be positive.
+func WithMaxQueueLatency[T comparable](d time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if d <= 0 {
+			p.invalidOption("max queue latency must be positive, got %v", d)
+			return
+		}
+		p.maxQueueLatency = d
+	}
+}
+
+// WithCallerRuns makes Submit execute the task in the goroutine of the caller instead of blocking when both
+// the inbound queue and the worker pool are saturated, like CallerRunsPolicy of Java executors. The task is
+// deduplicated first, so its duplicates are coalesced while it is executing. Slowing down the producers this way
+// provides natural backpressure. TrySubmit still rejects the task. A panic of the task is propagated to the caller.
+// It is a shortcut for WithRejectionPolicy(CallerRunsPolicy[T]()).
+func WithCallerRuns[T comparable]() Option[T] {
+	return WithRejectionPolicy(CallerRunsPolicy[T]())
+}
+
+// WithRejectionPolicy sets what happens to the tasks submitted by blocking calls when the inbound queue is full:
+// wait for the space (default), reject the task, drop the oldest pending task, execute the task in the goroutine
+// of the caller or a custom decision (see RejectionPolicy).
+func WithRejectionPolicy[T comparable](policy RejectionPolicy[T]) Option[T] {
+	return func(p *UniqPool[T]) {
+		p.rejectionPolicy = policy
+	}
+}
+
+// WithLoadShedding drops a fraction of the new tasks before the inbound queue is full (random early detection),
+// so under sustained overload the pool sheds a controlled share of new identifiers instead of collapsing
+// into full-queue rejection storms. Below minFill of the capacity no task is dropped, between minFill and maxFill
+// the drop probability grows linearly up to maxProb, above maxFill every new task is dropped. Duplicates of
+// the pending tasks are still coalesced. The dropped tasks are rejected like when the queue is full.
+// The fills must be within 0 <= minFill < maxFill <= 1 and maxProb within (0, 1]. Ignored with WithUnboundedQueue.
+func WithLoadShedding[T comparable](minFill, maxFill, maxProb float64) Option[T] {
+	return func(p *UniqPool[T]) {
+		if minFill < 0 || minFill >= maxFill || maxFill > 1 || maxProb <= 0 || maxProb > 1 {
+			p.invalidOption("load shedding fills %v, %v or probability %v is out of range", minFill, maxFill, maxProb)
+			return
+		}
+		p.shedding = &loadShedding{minFill: minFill, maxFill: maxFill, maxProb: maxProb}
+	}
+}
+
+// WithAdaptiveConcurrency tunes the number of the tasks executing at once between minWorkers and the number
+// of workers of the pool, so the pool self-tunes as the downstream capacity changes. The limit is tuned by AIMD
+// after each round of completed tasks: it is halved if their average execution time exceeds targetLatency or
+// the fraction of the failed ones exceeds maxErrorRate, otherwise it grows by one. A task fails if it panics or
+// returns an error. The tasks above the limit wait for a free slot in the worker pool, and the tasks dispatched
+// by WithBatchDispatch are not limited. minWorkers and targetLatency must be positive, maxErrorRate must be
+// within [0, 1].
+func WithAdaptiveConcurrency[T comparable](minWorkers int, targetLatency time.Duration, maxErrorRate float64) Option[T] {
+	return func(p *UniqPool[T]) {
+		if minWorkers <= 0 || targetLatency <= 0 || maxErrorRate < 0 || maxErrorRate > 1 {
+			p.invalidOption("adaptive concurrency min workers %d, target latency %v or max error rate %v "+
+				"is out of range", minWorkers, targetLatency, maxErrorRate)
+			return
+		}
+		p.limiter = newConcurrencyLimiter(minWorkers, targetLatency, maxErrorRate)
+	}
+}
+
+// WithResourceLimits protects the host when the tasks are memory-heavy: the dispatching slows down as the heap
+// of the process approaches maxHeapBytes or the number of its goroutines approaches maxGoroutines, and pauses once
+// a limit is reached. The usage is read from runtime/metrics on every tick. While the dispatching is paused
+// the tasks stay in the inbound queue, so Submit blocks and TrySubmit fails when it is full. On stop the backlog
+// is dispatched regardless. A zero limit is not checked, but at least one limit must be set, and maxGoroutines
+// must not be negative.
+func WithResourceLimits[T comparable](maxHeapBytes uint64, maxGoroutines int) Option[T] {
+	return func(p *UniqPool[T]) {
+		if maxGoroutines < 0 || (maxHeapBytes == 0 && maxGoroutines == 0) {
+			p.invalidOption("resource limits must be set and not negative, got %d bytes and %d goroutines",
+				maxHeapBytes, maxGoroutines)
+			return
+		}
+		p.resources = newResourceLimits(maxHeapBytes, maxGoroutines)
+	}
+}
+
+// WithCheckpoint calls fn every period with the identifiers of the pending tasks in no particular order,
+// so applications can persist them externally and resubmit them after a crash. With ReleaseOnCompletion
+// the executing tasks are included. fn is called once more when the pool is stopped, with the tasks left
+// pending, so the persisted set does not keep the executed ones. The periodic calls are made in a separate
+// goroutine and must not block for long. every must be positive and fn must not be nil.
+func WithCheckpoint[T comparable](every time.Duration, fn func(keys []T)) Option[T] {
+	return func(p *UniqPool[T]) {
+		if every <= 0 || fn == nil {
+			p.invalidOption("checkpoint period must be positive and the function must not be nil, got %v", every)
+			return
+		}
+		p.checkpoint = fn
+		p.checkpointEvery = every
+	}
+}
+
+// WithMaxPendingKeys puts a hard cap on the number of the pending tasks: when a new task exceeds it, the oldest
+// pending task is dropped, so the memory of the pool is strictly bounded even under pathological identifier
+// cardinality, e.g. with WithUnboundedQueue. The dropped tasks are reported to the observer as EventDropped
+// with ErrQueueFull (see WithObserver), and their result callbacks receive ErrQueueFull. n must be positive.
+func WithMaxPendingKeys[T comparable](n int) Option[T] {
+	return func(p *UniqPool[T]) {
+		if n <= 0 {
+			p.invalidOption("max pending keys must be positive, got %d", n)
+			return
+		}
+		p.maxPendingKeys = n
+	}
+}
+
+// WithTopKeys tracks the identifiers with the most submissions coalesced with their pending tasks, so TopKeys can
+// report which keys generate the most duplicate traffic. The counts are estimated by the space-saving algorithm
+// in a memory of capacity entries: the identifiers hotter than 1/capacity of the duplicates are always reported.
+// capacity must be positive.
+func WithTopKeys[T comparable](capacity int) Option[T] {
+	return func(p *UniqPool[T]) {
+		if capacity <= 0 {
+			p.invalidOption("top keys capacity must be positive, got %d", capacity)
+			return
+		}
+		p.hotKeys = newHeavyHitters[T](capacity)
+	}
+}
+
+// WithSlowKeys tracks the execution times of the tasks per identifier, so SlowestKeys reports up to n identifiers
+// whose tasks took the longest to execute over the last window (up to two windows), e.g. to find which entities
+// make the pool fall behind. n and window must be positive.
+func WithSlowKeys[T comparable](n int, window time.Duration) Option[T] {
+	return func(p *UniqPool[T]) {
+		if n <= 0 || window <= 0 {
+			p.invalidOption("slow keys count and window must be positive, got %d and %v", n, window)
+			return
+		}
+		p.slowKeys = newSlowKeys[T](n, window)
+	}
+}
+
+// WithSlowTaskThreshold calls fn while a task is still running past the threshold, not just after it completes,
+// so stuck handlers are surfaced immediately. fn receives the identifier of the task and the time it has been
+// running, and is called once per execution in a separate goroutine. d must be positive and fn must not be nil.
+func WithSlowTaskThreshold[T comparable](d time.Duration, fn func(id T, elapsed time.Duration)) Option[T] {
+	return func(p *UniqPool[T]) {
+		if d <= 0 || fn == nil {
+			p.invalidOption("slow task threshold must be positive and the function must not be nil, got %v", d)
+			return
+		}
+		p.slowTaskThreshold = d
+		p.onSlowTask = fn
+	}
+}
+
+// WithStaleBacklog checks the age of the oldest pending task several times per threshold and calls fn with it
+// when it exceeds the threshold, indicating that the pool cannot keep up or the dispatcher has stalled.
+// fn is called again only after the backlog has recovered. While the backlog is stale, Stats reports
+// StaleBacklog. fn is called in a separate goroutine and may be nil if only the flag is needed.
+// Each check scans the pending tasks. threshold must be positive.
+func WithStaleBacklog[T comparable](threshold time.Duration, fn func(age time.Duration)) Option[T] {
+	return func(p *UniqPool[T]) {
+		if threshold <= 0 {
+			p.invalidOption("stale backlog threshold must be positive, got %v", threshold)
+			return
+		}
+		p.staleThreshold = threshold
+		p.onStale = fn
+	}
+}
+
+// WithWorkerSupervisor reports the tasks executing longer than hardLimit with EventWorkerHung and counts them in
+// Stats.HungTasks. If replace is true, the hung task is left running in its own goroutine and its worker is
+// released to execute the next tasks, so one hung task does not permanently reduce the effective concurrency.
+// The identifier of the detached task stays executing until it returns, and StopAndWait waits for it.
+// The panic of the detached task is passed to the panic handler (see WithPanicHandler).
+// hardLimit must be positive.
+func WithWorkerSupervisor[T comparable](hardLimit time.Duration, replace bool) Option[T] {
+	return func(p *UniqPool[T]) {
+		if hardLimit <= 0 {
+			p.invalidOption("worker hard limit must be positive, got %v", hardLimit)
+			return
+		}
+		p.workerHardLimit = hardLimit
+		p.replaceHungWorkers = replace
+	}
+}
+
+// WithPanicHandler sets the function receiving the values of the panics of the tasks instead of printing them
+// with the stack trace, e.g. to log them with debug.Stack, since the handler is called in the goroutine of the
+// panicked task. The panics are recovered, so they do not stop the workers. The panics are also reported with
+// EventFailed if the events are observed. handler must not be nil.
+func WithPanicHandler[T comparable](handler func(panicValue any)) Option[T] {
+	return func(p *UniqPool[T]) {
+		if handler == nil {
+			p.invalidOption("panic handler must not be nil")
+			return
+		}
+		p.panicHandler = handler
+	}
+}
+
+// WithLane adds a lane: a separate inbound queue with its own capacity and accumulation interval, e.g. an
+// "interactive" lane with a 10ms interval next to a "bulk" lane with a 5s one. Tasks are routed to the lane by
+// SubmitLane, and the tasks of all the lanes are executed by the workers of the pool. Stats of the pool include
+// the lanes. The lane is configured with the other options of the pool. name must not be empty, interval and
+// queueCapacity must be positive.
+func WithLane[T comparable](name string, interval time.Duration, queueCapacity int) Option[T] {
+	return func(p *UniqPool[T]) {
+		if name == "" || interval <= 0 || queueCapacity <= 0 {
+			p.invalidOption("lane %q must have a name, a positive interval and capacity, got %v and %d",
+				name, interval, queueCapacity)
+			return
+		}
+		p.laneSpecs = append(p.laneSpecs, laneSpec{name: name, interval: interval, queueCapacity: queueCapacity})
+	}
+}
+
+// WithNamespaceProfiles overrides the interval, the flush threshold, the suppression window and the default
+// priority for the namespaces of the identifiers (see NamespaceProfile), so one pool can serve heterogeneous
+// key families with different freshness needs. The namespace of an identifier is returned by classify, e.g.
+// PrefixClassifier. The classifier is shared with WithNamespaceQuotas: classify may be nil to use the one
+// of WithNamespaceQuotas, and New returns an error wrapping ErrInvalidParameters if both or neither options
+// set one, if profiles is empty or if a profile sets an interval longer than the interval of the pool.
+func WithNamespaceProfiles[T comparable](classify func(id T) string, profiles map[string]NamespaceProfile) Option[T] {
+	return func(p *UniqPool[T]) {
+		if len(profiles) == 0 {
+			p.invalidOption("namespace profiles must not be empty")
+			return
+		}
+
+		p.profileClassifier = classify
+		p.profiles = make(map[string]NamespaceProfile, len(profiles))
+		p.profilePending = make(map[string]*int64)
+		p.profileWindows = false
+		for namespace, profile := range profiles {
+			p.profiles[namespace] = profile
+			if profile.FlushThreshold > 0 {
+				p.profilePending[namespace] = new(int64)
+			}
+			if profile.SuppressionWindow > 0 {
+				p.profileWindows = true
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package uniqpool
+
+import "errors"
+
+var (
+	// ErrPoolStopped is returned when a task is submitted to the stopped pool.
+	ErrPoolStopped = errors.New("pool is stopped")
+	// ErrQueueFull is returned when a task is rejected because the inbound queue or the namespace quota is full.
+	ErrQueueFull = errors.New("queue is full")
+	// ErrPoolRunning is returned by Start if the pool is not stopped.
+	ErrPoolRunning = errors.New("pool is running")
+	// ErrQuarantined is passed to the result callbacks of a task skipped because its identifier is quarantined.
+	ErrQuarantined = errors.New("task identifier is quarantined")
+	// ErrCancelled is passed to the result callbacks of a pending task dropped by CancelKey.
+	ErrCancelled = errors.New("task is cancelled")
+)
+
+// Outcome is the result of adding a task to the pool.
+type Outcome int
+
+const (
+	// Enqueued means that the task is added to the inbound queue and will be executed.
+	Enqueued Outcome = iota
+	// Coalesced means that the task is coalesced with the pending task with the same identifier
+	// and will not be executed itself.
+	Coalesced
+	// Suppressed means that the task is dropped because of the suppression window (see WithSuppressionWindow)
+	// or because its identifier is quarantined (see WithQuarantine).
+	Suppressed
+	// Rejected means that the task is rejected because the inbound queue or the namespace quota is full.
+	Rejected
+	// Stopped means that the task is rejected because the pool is stopped.
+	Stopped
+)
+
+// String returns the name of the outcome.
+func (o Outcome) String() string {
+	switch o {
+	case Enqueued:
+		return "enqueued"
+	case Coalesced:
+		return "coalesced"
+	case Suppressed:
+		return "suppressed"
+	case Rejected:
+		return "rejected"
+	case Stopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// outcomeErr returns the outcome with ErrPoolStopped if the pool is stopped.
+func outcomeErr(o Outcome) (Outcome, error) {
+	if o == Stopped {
+		return o, ErrPoolStopped
+	}
+
+	return o, nil
+}
+
+// mustSubmit panics if the pool is stopped.
+func mustSubmit(o Outcome) Outcome {
+	if o == Stopped {
+		panic("pool is stopped")
+	}
+
+	return o
+}
@@ -0,0 +1,408 @@
+package uniqpool
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sort"
+	"sync"
+)
+
+// walCompactThreshold is the number of WAL records after which the WAL is compacted.
+const walCompactThreshold = 1024
+
+// PayloadPool is a pool of tasks with payloads, executed by a single handler. It is built on top of UniqPool,
+// so tasks with the same identifier are coalesced and the handler receives the payload of the first one.
+// Unlike closures, payloads can be persisted, so pending tasks survive restarts (see WithWAL).
+type PayloadPool[K comparable, V any] struct {
+	pool    *UniqPool[K]
+	handler func(id K, payload V)
+	// Receives all the payloads of the coalesced tasks. Replaces handler if set.
+	batchHandler func(id K, payloads []V)
+
+	// Pending tasks by identifier.
+	pending map[K]*pendingPayload[V]
+	// Sequence number of the last pending task.
+	seq uint64
+	// Mutex for working with pending tasks and the WAL.
+	mutex sync.Mutex
+
+	// Write-ahead log of the pending tasks. Nil if persistence is disabled.
+	wal WAL
+	// The number of WAL records since the last compaction.
+	walRecords int
+}
+
+// pendingPayload contains the payloads of a pending task.
+type pendingPayload[V any] struct {
+	// Sequence number of the task, distinguishes subsequent tasks with the same identifier in the WAL.
+	seq      uint64
+	payloads []V
+	// Closed when the outcome of the submission of the task is known. Nil after that.
+	submitting chan struct{}
+}
+
+// walRecord is a record of the PayloadPool WAL.
+type walRecord[K comparable, V any] struct {
+	Seq     uint64
+	ID      K
+	Payload V
+	// True if the task is executed.
+	Done bool
+}
+
+// PayloadOption configures a PayloadPool.
+type PayloadOption[K comparable, V any] func(*PayloadPool[K, V])
+
+// WithWAL enables persistence of the pending tasks. Each submission is appended to the WAL before it is
+// accepted and removed from it after it is executed. On creation, the pending tasks from the WAL are
+// resubmitted to the pool, so a crash does not drop queued work. Tasks are executed at least once:
+// a task that was executing during the crash is executed again. Identifiers and payloads must be
+// encodable with encoding/gob.
+func WithWAL[K comparable, V any](wal WAL) PayloadOption[K, V] {
+	return func(p *PayloadPool[K, V]) {
+		p.wal = wal
+	}
+}
+
+// NewPayloadPool creates a PayloadPool on top of the pool. handler is called for each executed task.
+// If the WAL is enabled, the pending tasks from the WAL are resubmitted to the pool.
+func NewPayloadPool[K comparable, V any](pool *UniqPool[K], handler func(id K, payload V),
+	opts ...PayloadOption[K, V],
+) (*PayloadPool[K, V], error) {
+	return newPayloadPool(pool, handler, nil, opts...)
+}
+
+// NewBatchPayloadPool creates a PayloadPool on top of the pool. Unlike NewPayloadPool, handler receives
+// the payloads of all the tasks coalesced into an execution in submission order, e.g. to aggregate events.
+func NewBatchPayloadPool[K comparable, V any](pool *UniqPool[K], handler func(id K, payloads []V),
+	opts ...PayloadOption[K, V],
+) (*PayloadPool[K, V], error) {
+	return newPayloadPool(pool, nil, handler, opts...)
+}
+
+func newPayloadPool[K comparable, V any](pool *UniqPool[K], handler func(id K, payload V),
+	batchHandler func(id K, payloads []V), opts ...PayloadOption[K, V],
+) (*PayloadPool[K, V], error) {
+	p := &PayloadPool[K, V]{
+		pool:         pool,
+		handler:      handler,
+		batchHandler: batchHandler,
+		pending:      make(map[K]*pendingPayload[V]),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.wal != nil {
+		if err := p.replay(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Submit adds a task with the payload to the pool. Will block if the inbound queue is full.
+// Returns an error if the task cannot be written to the WAL.
+func (p *PayloadPool[K, V]) Submit(id K, payload V) error {
+	_, err := p.submit(id, payload, true)
+	return err
+}
+
+// TrySubmit adds a task with the payload to the pool. Returns false if the inbound queue is full.
+// Returns an error if the task cannot be written to the WAL.
+func (p *PayloadPool[K, V]) TrySubmit(id K, payload V) (bool, error) {
+	return p.submit(id, payload, false)
+}
+
+// StopAndWait stops the pool and waits for all tasks to be executed.
+// Returns an error if the WAL cannot be cleared.
+func (p *PayloadPool[K, V]) StopAndWait() error {
+	p.pool.StopAndWait()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.wal != nil && len(p.pending) == 0 {
+		return p.wal.Rewrite(nil)
+	}
+
+	return nil
+}
+
+func (p *PayloadPool[K, V]) submit(id K, payload V, wait bool) (bool, error) {
+	id = p.pool.normalizeKey(id)
+
+	p.mutex.Lock()
+	pending, ok := p.pending[id]
+	for ok && pending.submitting != nil {
+		// the task may still be rejected, so the payload cannot be attached to it yet
+		if !wait {
+			p.mutex.Unlock()
+			return false, nil
+		}
+		submitting := pending.submitting
+		p.mutex.Unlock()
+		<-submitting
+		p.mutex.Lock()
+		pending, ok = p.pending[id]
+	}
+
+	if ok {
+		defer p.mutex.Unlock()
+		// the payload is kept, so it is not lost if the WAL is replayed
+		if err := p.appendWAL(walRecord[K, V]{Seq: pending.seq, ID: id, Payload: payload}); err != nil {
+			return false, err
+		}
+		pending.payloads = append(pending.payloads, payload)
+		return true, nil
+	}
+
+	p.seq++
+	pending = &pendingPayload[V]{seq: p.seq, payloads: []V{payload}, submitting: make(chan struct{})}
+	if err := p.appendWAL(walRecord[K, V]{Seq: pending.seq, ID: id, Payload: payload}); err != nil {
+		p.mutex.Unlock()
+		return false, err
+	}
+	p.pending[id] = pending
+	p.mutex.Unlock()
+
+	// submitted without the mutex, so a blocked submission does not block the executions and other submissions
+	outcome := p.pool.submitWaiter(p.newTask(id, payload), wait, p.waiter(id, pending), nil)
+
+	p.mutex.Lock()
+	close(pending.submitting)
+	pending.submitting = nil
+	if outcome == Stopped {
+		// the WAL record is rolled back, as the payload is not accepted
+		p.dropPending(id, pending)
+	}
+	p.mutex.Unlock()
+
+	switch outcome {
+	case Rejected:
+		return false, nil
+	case Stopped:
+		panic("pool is stopped")
+	default:
+		return true, nil
+	}
+}
+
+// newTask returns the pool task that executes the pending payloads of the identifier. The payload is the first
+// of them, passed to the size function of the memory budget.
+func (p *PayloadPool[K, V]) newTask(id K, payload V) task[K] {
+	return task[K]{id: id, fn: func() { p.execute(id) }, payload: payload}
+}
+
+// waiter returns the result waiter of the task submitted for the pending payloads. If the task is coalesced with
+// an execution that does not take the payloads, e.g. the running one with ReleaseOnCompletion, the task is
+// submitted again after the execution, so the payloads are executed by a follow-up run.
+func (p *PayloadPool[K, V]) waiter(id K, pending *pendingPayload[V]) resultWaiter {
+	return func(execID ExecutionID, _ any, _ error) {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		if p.pending[id] != pending {
+			// taken by an execution
+			return
+		}
+
+		if execID == 0 {
+			// the task is rejected, suppressed, dropped or pending in another pool sharing the store
+			p.dropPending(id, pending)
+			return
+		}
+
+		// called by the completing worker, so the submission that may block is made in another goroutine
+		go p.resubmit(id, pending, pending.payloads[0])
+	}
+}
+
+// resubmit submits the task again for the pending payloads left by the execution it was coalesced with.
+// If the pool is stopped, the payloads are kept in the WAL to be executed after the replay.
+func (p *PayloadPool[K, V]) resubmit(id K, pending *pendingPayload[V], payload V) {
+	p.pool.submitWaiter(p.newTask(id, payload), true, p.waiter(id, pending), nil)
+}
+
+// dropPending removes the pending payloads that will not be executed, unless they are already taken
+// by an execution. Must be called under mutex.
+func (p *PayloadPool[K, V]) dropPending(id K, pending *pendingPayload[V]) {
+	if p.pending[id] != pending {
+		return
+	}
+
+	delete(p.pending, id)
+	// the error is ignored: at worst the task will be executed after the replay
+	_ = p.appendWAL(walRecord[K, V]{Seq: pending.seq, ID: id, Done: true})
+}
+
+// execute takes the pending payloads of the task and calls the handler.
+func (p *PayloadPool[K, V]) execute(id K) {
+	p.mutex.Lock()
+	pending, ok := p.pending[id]
+	delete(p.pending, id)
+	p.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	defer p.complete(id, pending.seq)
+
+	if p.batchHandler != nil {
+		p.batchHandler(id, pending.payloads)
+		return
+	}
+
+	p.handler(id, pending.payloads[0])
+}
+
+// complete marks the task as executed in the WAL.
+func (p *PayloadPool[K, V]) complete(id K, seq uint64) {
+	if p.wal == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// the error is ignored: at worst the task will be executed again after the replay
+	_ = p.appendWAL(walRecord[K, V]{Seq: seq, ID: id, Done: true})
+
+	if p.walRecords >= walCompactThreshold && p.walRecords > 2*len(p.pending) {
+		_ = p.compactWAL()
+	}
+}
+
+// appendWAL appends the record to the WAL. Must be called under mutex.
+func (p *PayloadPool[K, V]) appendWAL(record walRecord[K, V]) error {
+	if p.wal == nil {
+		return nil
+	}
+
+	data, err := encodeWALPayload(record)
+	if err != nil {
+		return err
+	}
+
+	if err := p.wal.Append(data); err != nil {
+		return err
+	}
+
+	p.walRecords++
+	return nil
+}
+
+// compactWAL replaces the WAL content with the records of the pending tasks. Must be called under mutex.
+func (p *PayloadPool[K, V]) compactWAL() error {
+	var records [][]byte
+	for _, id := range p.pendingIDs() {
+		pending := p.pending[id]
+		for _, payload := range pending.payloads {
+			data, err := encodeWALPayload(walRecord[K, V]{Seq: pending.seq, ID: id, Payload: payload})
+			if err != nil {
+				return err
+			}
+			records = append(records, data)
+		}
+	}
+
+	if err := p.wal.Rewrite(records); err != nil {
+		return err
+	}
+
+	p.walRecords = len(records)
+	return nil
+}
+
+// replay restores the pending tasks from the WAL and submits them to the pool.
+func (p *PayloadPool[K, V]) replay() error {
+	type replayed struct {
+		id       K
+		payloads []V
+	}
+
+	bySeq := make(map[uint64]*replayed)
+	err := p.wal.Replay(func(data []byte) error {
+		var record walRecord[K, V]
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+			return err
+		}
+
+		if record.Done {
+			delete(bySeq, record.Seq)
+			return nil
+		}
+
+		if r, ok := bySeq[record.Seq]; ok {
+			r.payloads = append(r.payloads, record.Payload)
+		} else {
+			bySeq[record.Seq] = &replayed{id: record.ID, payloads: []V{record.Payload}}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	seqs := make([]uint64, 0, len(bySeq))
+	for seq := range bySeq {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	p.mutex.Lock()
+	for _, seq := range seqs {
+		r := bySeq[seq]
+		if pending, ok := p.pending[r.id]; ok {
+			pending.payloads = append(pending.payloads, r.payloads...)
+			continue
+		}
+
+		p.seq++
+		p.pending[r.id] = &pendingPayload[V]{seq: p.seq, payloads: r.payloads}
+	}
+	err = p.compactWAL()
+	ids := p.pendingIDs()
+	pendings := make([]*pendingPayload[V], len(ids))
+	payloads := make([]V, len(ids))
+	for i, id := range ids {
+		pendings[i] = p.pending[id]
+		payloads[i] = pendings[i].payloads[0]
+	}
+	p.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		// the tasks that are not enqueued are handled by the waiter like the submitted ones, so the payloads
+		// of a suppressed task are dropped from the WAL instead of being replayed again
+		mustSubmit(p.pool.submitWaiter(p.newTask(id, payloads[i]), true, p.waiter(id, pendings[i]), nil))
+	}
+
+	return nil
+}
+
+// pendingIDs returns the identifiers of the pending tasks in submission order. Must be called under mutex.
+func (p *PayloadPool[K, V]) pendingIDs() []K {
+	ids := make([]K, 0, len(p.pending))
+	for id := range p.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return p.pending[ids[i]].seq < p.pending[ids[j]].seq })
+
+	return ids
+}
+
+func encodeWALPayload[K comparable, V any](record walRecord[K, V]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,220 @@
+package uniqpool
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPayloadPool checks that coalesced tasks are executed with the payload of the first submission.
+func TestPayloadPool(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		executed = make(map[string]int)
+	)
+
+	pool, err := NewPayloadPool(MustNew[string](10, 2, 10, time.Millisecond*10), func(id string, payload int) {
+		mu.Lock()
+		executed[id] = payload
+		mu.Unlock()
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, pool.Submit("task1", 1))
+	require.NoError(t, pool.Submit("task1", 2))
+	ok, err := pool.TrySubmit("task2", 3)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, pool.StopAndWait())
+	require.Equal(t, map[string]int{"task1": 1, "task2": 3}, executed)
+	require.Empty(t, pool.pending)
+}
+
+// TestBatchPayloadPool checks that the batch handler receives the payloads of all coalesced tasks.
+func TestBatchPayloadPool(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		executed = make(map[string][]int)
+	)
+
+	pool, err := NewBatchPayloadPool(MustNew[string](10, 2, 10, time.Hour), func(id string, payloads []int) {
+		mu.Lock()
+		executed[id] = payloads
+		mu.Unlock()
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, pool.Submit("task1", 1))
+	require.NoError(t, pool.Submit("task2", 2))
+	require.NoError(t, pool.Submit("task1", 3))
+
+	require.NoError(t, pool.StopAndWait())
+	require.Equal(t, map[string][]int{"task1": {1, 3}, "task2": {2}}, executed)
+}
+
+// TestPayloadPoolWAL checks that pending tasks are replayed from the WAL after a crash.
+func TestPayloadPoolWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.wal")
+
+	wal, err := OpenFileWAL(path, true)
+	require.NoError(t, err)
+
+	block := make(chan struct{})
+	crashed := MustNew[string](10, 2, 10, time.Hour)
+	pool, err := NewPayloadPool(crashed, func(string, string) { <-block }, WithWAL[string, string](wal))
+	require.NoError(t, err)
+
+	require.NoError(t, pool.Submit("task1", "payload1"))
+	require.NoError(t, pool.Submit("task2", "payload2"))
+	require.NoError(t, pool.Submit("task1", "payload3"))
+
+	// simulate the crash: the tasks are never executed
+	require.NoError(t, wal.Close())
+
+	wal, err = OpenFileWAL(path, true)
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		executed = make(map[string]string)
+	)
+	restored, err := NewPayloadPool(MustNew[string](10, 2, 10, time.Millisecond*10), func(id, payload string) {
+		mu.Lock()
+		executed[id] = payload
+		mu.Unlock()
+	}, WithWAL[string, string](wal))
+	require.NoError(t, err)
+
+	require.NoError(t, restored.StopAndWait())
+	require.Equal(t, map[string]string{"task1": "payload1", "task2": "payload2"}, executed)
+
+	// the WAL is empty after all tasks are executed
+	count := 0
+	require.NoError(t, wal.Replay(func([]byte) error {
+		count++
+		return nil
+	}))
+	require.Zero(t, count)
+	require.NoError(t, wal.Close())
+
+	close(block)
+	crashed.StopAndWait()
+}
+
+// TestPayloadPoolWALReplaySuppressed checks that a replayed task suppressed by the pool is dropped from the WAL
+// instead of being replayed forever.
+func TestPayloadPoolWALReplaySuppressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.wal")
+
+	wal, err := OpenFileWAL(path, true)
+	require.NoError(t, err)
+	crashed := MustNew[string](10, 2, 10, time.Hour)
+	pool, err := NewPayloadPool(crashed, func(string, string) {}, WithWAL[string, string](wal))
+	require.NoError(t, err)
+	require.NoError(t, pool.Submit("task1", "payload1"))
+	require.NoError(t, wal.Close())
+
+	// the task is pending in another pool sharing the store, so the replayed one is suppressed
+	store := NewMemoryStore[string]()
+	other := MustNew(10, 2, 10, time.Hour, WithUniqStore[string](store))
+	other.Submit("task1", func() {})
+
+	wal, err = OpenFileWAL(path, true)
+	require.NoError(t, err)
+	var executed int
+	restored, err := NewPayloadPool(MustNew(10, 2, 10, time.Hour, WithUniqStore[string](store)),
+		func(string, string) { executed++ }, WithWAL[string, string](wal))
+	require.NoError(t, err)
+
+	require.NoError(t, restored.StopAndWait())
+	require.Zero(t, executed)
+	require.Empty(t, restored.pending)
+	count := 0
+	require.NoError(t, wal.Replay(func([]byte) error {
+		count++
+		return nil
+	}))
+	require.Zero(t, count)
+	require.NoError(t, wal.Close())
+
+	other.StopAndWait()
+	crashed.StopAndWait()
+}
+
+// TestPayloadPoolBlockedSubmit checks that a submission blocked on the full queue does not block the others.
+func TestPayloadPoolBlockedSubmit(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		executed = make(map[string][]int)
+	)
+
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool, err := NewBatchPayloadPool(MustNew(1, 2, 10, time.Hour, WithClock[string](clock)),
+		func(id string, payloads []int) {
+			mu.Lock()
+			executed[id] = payloads
+			mu.Unlock()
+		})
+	require.NoError(t, err)
+
+	require.NoError(t, pool.Submit("task1", 1))
+	blocked := make(chan error)
+	go func() { blocked <- pool.Submit("task2", 2) }()
+	require.Eventually(t, func() bool {
+		pool.mutex.Lock()
+		defer pool.mutex.Unlock()
+		return pool.pending["task2"] != nil
+	}, time.Second, time.Millisecond)
+
+	// the payload is attached to the accepted task, the task being submitted cannot take payloads yet
+	require.NoError(t, pool.Submit("task1", 3))
+	ok, err := pool.TrySubmit("task2", 4)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = pool.TrySubmit("task3", 5)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	clock.tickChan <- time.Now()
+	require.NoError(t, <-blocked)
+	require.NoError(t, pool.StopAndWait())
+	require.Equal(t, map[string][]int{"task1": {1, 3}, "task2": {2}}, executed)
+	require.Empty(t, pool.pending)
+}
+
+// TestPayloadPoolFollowUp checks that the payload submitted while the task is executing with ReleaseOnCompletion
+// is executed by a follow-up run.
+func TestPayloadPoolFollowUp(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		executed [][]int
+	)
+
+	started := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	pool, err := NewBatchPayloadPool(
+		MustNew(10, 2, 10, time.Millisecond, WithKeyRelease[string](ReleaseOnCompletion)),
+		func(id string, payloads []int) {
+			started <- struct{}{}
+			<-unblock
+			mu.Lock()
+			executed = append(executed, payloads)
+			mu.Unlock()
+		})
+	require.NoError(t, err)
+
+	require.NoError(t, pool.Submit("task1", 1))
+	<-started
+	require.NoError(t, pool.Submit("task1", 2))
+	require.NoError(t, pool.Submit("task1", 3))
+	close(unblock)
+	<-started
+
+	require.NoError(t, pool.StopAndWait())
+	require.Equal(t, [][]int{{1}, {2, 3}}, executed)
+	require.Empty(t, pool.pending)
+}
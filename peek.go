@@ -0,0 +1,87 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TaskInfo describes a pending task (see Peek).
+type TaskInfo struct {
+	// The time the task was accepted into the pool.
+	SubmittedAt time.Time
+	// The current priority of the task. Zero unless priorities are enabled (see WithPriorities).
+	Priority int
+	// The number of the submissions coalesced with the task.
+	Coalesced int
+	// The namespace of the task. Empty unless a namespace classifier is set (see WithNamespaceQuotas).
+	Namespace string
+	// The labels attached by WithLabels. Nil if there are none.
+	Labels map[string]string
+	// The estimated time until the task is dispatched, based on its position in the inbound queue, the interval,
+	// the drain batch and the throughput over the sliding window. The position assumes FIFO order, so the estimate
+	// is rough with priorities.
+	ETA time.Duration
+}
+
+// Peek returns the metadata of the pending task with the identifier, e.g. for debugging endpoints and admission
+// decisions. Returns false if there is no task with the identifier waiting to be dispatched.
+func (p *UniqPool[T]) Peek(id T) (TaskInfo, bool) {
+	id = p.normalizeKey(id)
+	s := p.stripe(id)
+	s.mutex.Lock()
+	k, ok := s.keys[id]
+	// with ReleaseOnCompletion the identifier of the executing task is kept in the dedup set
+	if !ok || (p.keyRelease == ReleaseOnCompletion && s.running[id] > 0) {
+		s.mutex.Unlock()
+		return TaskInfo{}, false
+	}
+	info := TaskInfo{
+		SubmittedAt: k.submittedAt,
+		Priority:    s.priorities[id],
+		Coalesced:   k.coalesced,
+	}
+	s.mutex.Unlock()
+
+	if k.labels != nil {
+		info.Labels = make(map[string]string, len(k.labels))
+		for name, value := range k.labels {
+			info.Labels[name] = value
+		}
+	}
+	if p.namespaceClassifier != nil {
+		info.Namespace = p.namespaceClassifier(id)
+	}
+	info.ETA = p.eta(k)
+
+	return info, true
+}
+
+// eta estimates the time until the pending task is dispatched: the rest of the current interval plus an interval
+// per full drain batch ahead of the task, or the time to dispatch the tasks ahead at the recent throughput
+// if it is longer.
+func (p *UniqPool[T]) eta(k pendingKey) time.Duration {
+	// the tasks accepted before this one and not dispatched yet, up to the tasks in the inbound queue
+	ahead := int64(k.seq) - 1 - int64(atomic.LoadUint64(&p.counters.dispatched))
+	if queued := int64(p.inboundQueue.len()) - 1; ahead > queued {
+		ahead = queued
+	}
+	if ahead < 0 {
+		ahead = 0
+	}
+
+	eta := p.interval
+	if elapsed := p.clock.Now().Sub(k.submittedAt); elapsed > 0 {
+		eta -= elapsed % p.interval
+	}
+	if maxBatch := atomic.LoadInt64(&p.maxBatch); maxBatch > 0 {
+		eta += time.Duration(ahead/maxBatch) * p.interval
+	}
+
+	if throughput := p.throughput(); throughput > 0 {
+		if drain := time.Duration(float64(ahead) / throughput * float64(time.Second)); drain > eta {
+			eta = drain
+		}
+	}
+
+	return eta
+}
@@ -0,0 +1,56 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPeek checks that the metadata of the pending task is reported until it is dispatched.
+func TestPeek(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(2, 2, 10, time.Hour, WithClock[string](clock), WithPriorities[string](0))
+
+	_, ok := pool.Peek("task1")
+	require.False(t, ok)
+
+	pool.Submit("task1", func() {}, WithLabels(map[string]string{"tenant": "a"}))
+	pool.Submit("task1", func() {}, WithLabels(map[string]string{"tenant": "b"}))
+	pool.SubmitWithPriority("task1", 5, func() {})
+
+	info, ok := pool.Peek("task1")
+	require.True(t, ok)
+	require.False(t, info.SubmittedAt.IsZero())
+	require.Equal(t, 5, info.Priority)
+	require.Equal(t, 2, info.Coalesced)
+	require.Equal(t, map[string]string{"tenant": "a"}, info.Labels)
+
+	clock.tickChan <- time.Now()
+	pool.StopAndWait()
+
+	_, ok = pool.Peek("task1")
+	require.False(t, ok)
+}
+
+// TestPeekETA checks that the estimated time to dispatch grows with the number of drain batches ahead of the task.
+func TestPeekETA(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock), WithMaxDrainBatch[string](1))
+
+	pool.Submit("task1", func() {})
+	pool.Submit("task2", func() {})
+	pool.Submit("task3", func() {})
+
+	first, ok := pool.Peek("task1")
+	require.True(t, ok)
+	require.LessOrEqual(t, first.ETA, time.Hour)
+	require.Greater(t, first.ETA, time.Hour-time.Minute)
+
+	last, ok := pool.Peek("task3")
+	require.True(t, ok)
+	require.LessOrEqual(t, last.ETA, 3*time.Hour)
+	require.Greater(t, last.ETA, 3*time.Hour-time.Minute)
+
+	pool.StopAndWait()
+}
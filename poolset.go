@@ -0,0 +1,223 @@
+package uniqpool
+
+import (
+	"sync"
+	"time"
+)
+
+// PoolSet manages a separate UniqPool for each tenant behind a single API. Tenant pools are created
+// lazily on the first submission and stopped after being idle for the configured timeout.
+// All tenant pools share one worker pool, so the total number of workers is limited regardless of
+// the number of tenants, while each tenant has its own inbound queue capacity.
+type PoolSet[T comparable] struct {
+	// Capacity of the inbound queue of each tenant pool.
+	tenantQueueCapacity int
+	// The parameters of the shared worker pool, passed to the tenant pools for the options depending on them,
+	// e.g. WithWeightedTasks.
+	poolWorkersCount int
+	poolCapacity     int
+	// The interval during which tasks will accumulate in tenant pools.
+	interval time.Duration
+	// The tenant pool is stopped after being idle for this timeout.
+	idleTimeout time.Duration
+	// Options for the tenant pools.
+	opts []Option[T]
+	// The clock of the tenant pools, used to find the idle ones.
+	clock Clock
+
+	// The worker pool shared by all tenant pools.
+	executor executor[T]
+
+	// Tenant pools by tenant name.
+	tenants map[string]*tenantPool[T]
+	// Mutex for working with tenants.
+	mutex   sync.Mutex
+	stopped bool
+	// Statistics of the removed tenant pools.
+	retiredStats Stats
+
+	// Wait group for waiting for the janitor goroutine.
+	stopWaitGroup sync.WaitGroup
+	// Channel for stopping the janitor goroutine.
+	stopChan chan struct{}
+}
+
+type tenantPool[T comparable] struct {
+	pool *UniqPool[T]
+	// The time of the last submission.
+	lastUsed time.Time
+	// The number of submissions in progress.
+	active int
+}
+
+// NewPoolSet creates a new PoolSet. Each tenant pool has an inbound queue with tenantQueueCapacity.
+// poolWorkersCount and poolCapacity define the worker pool shared by all tenants. Tenant pools without
+// pending tasks are stopped after idleTimeout. opts are applied to each tenant pool. The options of the executor,
+// e.g. WithKeySharding or WithOrderedDispatch, configure the shared worker pool, so they hold across tenants.
+func NewPoolSet[T comparable](tenantQueueCapacity, poolWorkersCount, poolCapacity int,
+	interval, idleTimeout time.Duration, opts ...Option[T],
+) *PoolSet[T] {
+	if tenantQueueCapacity <= 0 || poolWorkersCount <= 0 || poolCapacity <= 0 || interval <= 0 || idleTimeout <= 0 {
+		panic("invalid parameters")
+	}
+
+	// the shared executor and the clock are created according to the options, e.g. WithKeySharding and WithClock
+	template := &UniqPool[T]{workersCount: poolWorkersCount, capacity: poolCapacity, clock: realClock{}}
+	for _, opt := range opts {
+		opt(template)
+	}
+	if template.optionErr != nil {
+		panic(template.optionErr)
+	}
+	e := template.newExecutor()
+
+	s := &PoolSet[T]{
+		tenantQueueCapacity: tenantQueueCapacity,
+		poolWorkersCount:    poolWorkersCount,
+		poolCapacity:        poolCapacity,
+		interval:            interval,
+		idleTimeout:         idleTimeout,
+		opts:                append(append([]Option[T]{}, opts...), withSharedExecutor[T](e)),
+		clock:               template.clock,
+		executor:            e,
+		tenants:             make(map[string]*tenantPool[T]),
+		stopChan:            make(chan struct{}),
+	}
+
+	s.stopWaitGroup.Add(1)
+	go s.removeIdleTenants()
+
+	return s
+}
+
+// Submit adds a task to the pool of the tenant. Will block if the inbound queue of the tenant is full.
+func (s *PoolSet[T]) Submit(tenant string, id T, fn func()) {
+	t := s.acquire(tenant)
+	defer s.releaseTenant(t)
+
+	t.pool.Submit(id, fn)
+}
+
+// TrySubmit adds a task to the pool of the tenant. Returns false if the inbound queue of the tenant is full.
+func (s *PoolSet[T]) TrySubmit(tenant string, id T, fn func()) bool {
+	t := s.acquire(tenant)
+	defer s.releaseTenant(t)
+
+	return t.pool.TrySubmit(id, fn)
+}
+
+// Tenants returns the number of active tenant pools.
+func (s *PoolSet[T]) Tenants() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.tenants)
+}
+
+// Stats returns the sum of the statistics of all tenant pools, including the removed ones.
+func (s *PoolSet[T]) Stats() Stats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := s.retiredStats
+	stats.Stopped = s.stopped
+	for _, t := range s.tenants {
+		stats.add(t.pool.Stats())
+	}
+	stats.setWorkers(s.executor.stats())
+
+	return stats
+}
+
+// Stopped returns true if the pool set is stopped.
+func (s *PoolSet[T]) Stopped() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.stopped
+}
+
+// StopAndWait stops all tenant pools and waits for all tasks to be executed.
+func (s *PoolSet[T]) StopAndWait() {
+	close(s.stopChan)
+	s.stopWaitGroup.Wait()
+
+	s.mutex.Lock()
+	s.stopped = true
+	tenants := s.tenants
+	s.tenants = make(map[string]*tenantPool[T])
+	s.mutex.Unlock()
+
+	for _, t := range tenants {
+		s.retire(t)
+	}
+	s.executor.stopAndWait()
+}
+
+// acquire returns the pool of the tenant, creating it if necessary, and marks it as in use.
+func (s *PoolSet[T]) acquire(tenant string) *tenantPool[T] {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		panic("pool is stopped")
+	}
+
+	t, ok := s.tenants[tenant]
+	if !ok {
+		pool := MustNew(s.tenantQueueCapacity, s.poolWorkersCount, s.poolCapacity, s.interval, s.opts...)
+		t = &tenantPool[T]{pool: pool}
+		s.tenants[tenant] = t
+	}
+	t.active++
+	t.lastUsed = s.clock.Now()
+
+	return t
+}
+
+// releaseTenant marks the end of the submission to the tenant pool.
+func (s *PoolSet[T]) releaseTenant(t *tenantPool[T]) {
+	s.mutex.Lock()
+	t.active--
+	s.mutex.Unlock()
+}
+
+// removeIdleTenants periodically stops the tenant pools that are idle for longer than idleTimeout.
+func (s *PoolSet[T]) removeIdleTenants() {
+	defer s.stopWaitGroup.Done()
+
+	ticker := s.clock.NewTicker(s.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C():
+		}
+
+		var idle []*tenantPool[T]
+		s.mutex.Lock()
+		for tenant, t := range s.tenants {
+			if t.active == 0 && s.clock.Now().Sub(t.lastUsed) >= s.idleTimeout && t.pool.pending() == 0 {
+				idle = append(idle, t)
+				delete(s.tenants, tenant)
+			}
+		}
+		s.mutex.Unlock()
+
+		for _, t := range idle {
+			s.retire(t)
+		}
+	}
+}
+
+// retire stops the removed tenant pool and keeps its statistics.
+func (s *PoolSet[T]) retire(t *tenantPool[T]) {
+	t.pool.StopAndWait()
+	stats := t.pool.Stats()
+
+	s.mutex.Lock()
+	s.retiredStats.add(stats)
+	s.mutex.Unlock()
+}
@@ -0,0 +1,82 @@
+package uniqpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPoolSet checks that tenants have separate dedup sets and idle tenant pools are removed.
+func TestPoolSet(t *testing.T) {
+	set := NewPoolSet[string](2, 2, 10, time.Millisecond*10, time.Millisecond*100)
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	set.Submit("tenant1", "task1", fn)
+	set.Submit("tenant1", "task1", fn)
+	// the same identifier in another tenant is not a duplicate
+	set.Submit("tenant2", "task1", fn)
+	require.Equal(t, 2, set.Tenants())
+
+	// per-tenant capacity
+	require.True(t, set.TrySubmit("tenant1", "task2", fn))
+	require.False(t, set.TrySubmit("tenant1", "task3", fn))
+
+	// idle tenants are removed
+	require.Eventually(t, func() bool { return set.Tenants() == 0 }, time.Second, time.Millisecond*10)
+	require.Equal(t, int32(3), atomic.LoadInt32(&processed))
+
+	// the tenant pool is created again
+	set.Submit("tenant1", "task1", fn)
+	require.Equal(t, 1, set.Tenants())
+
+	set.StopAndWait()
+
+	require.Equal(t, int32(4), processed)
+	require.Panics(t, func() { set.Submit("tenant1", "task1", fn) })
+}
+
+// TestPoolSetOptions checks that the options of the executor configure the worker pool shared by the tenants.
+func TestPoolSetOptions(t *testing.T) {
+	panics := make(chan any, 1)
+	set := NewPoolSet[string](10, 4, 10, time.Millisecond, time.Hour, WithOrderedDispatch[string](),
+		WithPanicHandler[string](func(panicValue any) { panics <- panicValue }))
+
+	var (
+		mu      sync.Mutex
+		running int
+		maxSeen int
+	)
+	fn := func() {
+		mu.Lock()
+		running++
+		if running > maxSeen {
+			maxSeen = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+	for _, tenant := range []string{"tenant1", "tenant2", "tenant3"} {
+		for _, id := range []string{"task1", "task2"} {
+			set.Submit(tenant, id, fn)
+		}
+	}
+	set.Submit("tenant1", "failed", func() { panic("task failed") })
+	select {
+	case panicValue := <-panics:
+		require.Equal(t, "task failed", panicValue)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the panic is not passed to the handler")
+	}
+
+	set.StopAndWait()
+	// the tasks of all the tenants are executed one by one by the single worker of the ordered dispatch
+	require.Equal(t, 1, maxSeen)
+}
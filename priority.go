@@ -0,0 +1,230 @@
+package uniqpool
+
+import (
+	"container/heap"
+	"math"
+	"time"
+)
+
+// priorityQueue orders the tasks taken from the inbound queue by priority. With aging, a pending task gains
+// one priority level per aging period, so low-priority tasks eventually outrank a constant stream
+// of high-priority ones. As all tasks age at the same rate, the order of two tasks never changes over time,
+// so the rank of a task is computed once.
+type priorityQueue[T comparable] struct {
+	// The period during which a pending task gains one priority level. Zero disables aging.
+	aging time.Duration
+	// The origin of the submission times, so the ranks stay small.
+	start time.Time
+	// The sequence number of the last pushed task, to keep FIFO order for equal ranks.
+	seq   uint64
+	items []priorityItem[T]
+	// Indexes of the items by task identifier.
+	index map[T]int
+}
+
+type priorityItem[T comparable] struct {
+	t    task[T]
+	rank int64
+	seq  uint64
+}
+
+func newPriorityQueue[T comparable](aging time.Duration, start time.Time) *priorityQueue[T] {
+	return &priorityQueue[T]{aging: aging, start: start, index: make(map[T]int)}
+}
+
+// push adds the task to the queue.
+func (q *priorityQueue[T]) push(t task[T]) {
+	q.seq++
+	heap.Push(q, priorityItem[T]{t: t, rank: q.rank(t), seq: q.seq})
+}
+
+// setPriority changes the priority of the queued task with the identifier. Returns false if there is no such task.
+func (q *priorityQueue[T]) setPriority(id T, priority int) bool {
+	i, ok := q.index[id]
+	if !ok {
+		return false
+	}
+
+	item := &q.items[i]
+	if priority != item.t.priority {
+		item.t.priority = priority
+		item.rank = q.rank(item.t)
+		heap.Fix(q, i)
+	}
+
+	return true
+}
+
+// remove removes the task with the identifier. Returns false if there is no such task.
+func (q *priorityQueue[T]) remove(id T) (task[T], bool) {
+	i, ok := q.index[id]
+	if !ok {
+		return task[T]{}, false
+	}
+
+	return heap.Remove(q, i).(priorityItem[T]).t, true
+}
+
+// removeOldest removes the task pushed first. Returns false if the queue is empty.
+func (q *priorityQueue[T]) removeOldest() (task[T], bool) {
+	if len(q.items) == 0 {
+		return task[T]{}, false
+	}
+
+	oldest := 0
+	for i := range q.items {
+		if q.items[i].seq < q.items[oldest].seq {
+			oldest = i
+		}
+	}
+
+	return heap.Remove(q, oldest).(priorityItem[T]).t, true
+}
+
+// rank returns the rank of the task: its priority adjusted by aging. The rank saturates instead of overflowing,
+// so huge priorities or aging periods may make ranks equal, but never reverse the order.
+func (q *priorityQueue[T]) rank(t task[T]) int64 {
+	rank := int64(t.priority)
+	if q.aging > 0 {
+		rank = saturatingSub(saturatingMul(rank, int64(q.aging)), int64(t.submittedAt.Sub(q.start)))
+	}
+
+	return rank
+}
+
+// saturatingMul returns a*b clamped to the range of int64. b must be positive.
+func saturatingMul(a, b int64) int64 {
+	switch {
+	case a > math.MaxInt64/b:
+		return math.MaxInt64
+	case a < math.MinInt64/b:
+		return math.MinInt64
+	default:
+		return a * b
+	}
+}
+
+// saturatingSub returns a-b clamped to the range of int64.
+func saturatingSub(a, b int64) int64 {
+	c := a - b
+	switch {
+	case b > 0 && c > a:
+		return math.MinInt64
+	case b < 0 && c < a:
+		return math.MaxInt64
+	default:
+		return c
+	}
+}
+
+// pop removes the task with the highest rank from the queue.
+func (q *priorityQueue[T]) pop() task[T] {
+	return heap.Pop(q).(priorityItem[T]).t
+}
+
+// Len implements heap.Interface.
+func (q *priorityQueue[T]) Len() int {
+	return len(q.items)
+}
+
+// Less implements heap.Interface.
+func (q *priorityQueue[T]) Less(i, j int) bool {
+	if q.items[i].rank != q.items[j].rank {
+		return q.items[i].rank > q.items[j].rank
+	}
+
+	return q.items[i].seq < q.items[j].seq
+}
+
+// Swap implements heap.Interface.
+func (q *priorityQueue[T]) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.index[q.items[i].t.id] = i
+	q.index[q.items[j].t.id] = j
+}
+
+// Push implements heap.Interface.
+func (q *priorityQueue[T]) Push(x any) {
+	item := x.(priorityItem[T])
+	q.index[item.t.id] = len(q.items)
+	q.items = append(q.items, item)
+}
+
+// Pop implements heap.Interface.
+func (q *priorityQueue[T]) Pop() any {
+	n := len(q.items)
+	item := q.items[n-1]
+	delete(q.index, item.t.id)
+	// the slot is cleared to not retain the task closure
+	q.items[n-1] = priorityItem[T]{}
+	q.items = q.items[:n-1]
+
+	return item
+}
+
+// upgradePriority raises the priority of the pending task if its duplicate has a higher priority, so urgency
+// is not lost to coalescing. Must be called under the mutex of the stripe s holding the identifier.
+func (p *UniqPool[T]) upgradePriority(s *dedupStripe[T], t task[T]) {
+	if priority, ok := s.priorities[t.id]; !ok || t.priority <= priority {
+		return
+	}
+	s.priorities[t.id] = t.priority
+	p.reprioritize(t.id)
+}
+
+// SetPriority changes the priority of the pending task with the identifier, e.g. so operators can bump stuck
+// important work. Returns false if priorities are not enabled (see WithPriorities) or there is no pending task
+// with the identifier.
+func (p *UniqPool[T]) SetPriority(id T, priority int) bool {
+	if p.priorityQueue == nil {
+		return false
+	}
+
+	id = p.normalizeKey(id)
+	s := p.stripe(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.priorities[id]; !ok {
+		return false
+	}
+	s.priorities[id] = priority
+	p.reprioritize(id)
+
+	return true
+}
+
+// reprioritize schedules applying the changed priority of the pending task to the priority queue.
+func (p *UniqPool[T]) reprioritize(id T) {
+	p.reprioritizedMutex.Lock()
+	p.reprioritized = append(p.reprioritized, id)
+	p.reprioritizedMutex.Unlock()
+}
+
+// applyPriorities applies the changed priorities to the tasks in the priority queue. The changes of the tasks
+// that are still being pushed into the inbound queue are kept until the next call.
+// Must be called only by the processTasks goroutine.
+func (p *UniqPool[T]) applyPriorities() {
+	p.reprioritizedMutex.Lock()
+	ids := p.reprioritized
+	p.reprioritized = nil
+	p.reprioritizedMutex.Unlock()
+
+	var kept []T
+	for _, id := range ids {
+		s := p.stripe(id)
+		s.mutex.Lock()
+		priority, pending := s.priorities[id]
+		s.mutex.Unlock()
+
+		if pending && !p.priorityQueue.setPriority(id, priority) {
+			kept = append(kept, id)
+		}
+	}
+
+	if len(kept) > 0 {
+		p.reprioritizedMutex.Lock()
+		p.reprioritized = append(p.reprioritized, kept...)
+		p.reprioritizedMutex.Unlock()
+	}
+}
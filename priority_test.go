@@ -0,0 +1,115 @@
+package uniqpool
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPriorityQueue checks the order of the tasks with and without aging.
+func TestPriorityQueue(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	q := newPriorityQueue[string](0, start)
+	q.push(task[string]{id: "low", priority: 1, submittedAt: start})
+	q.push(task[string]{id: "high", priority: 5, submittedAt: start.Add(time.Hour)})
+	q.push(task[string]{id: "low2", priority: 1, submittedAt: start})
+	require.Equal(t, "high", q.pop().id)
+	require.Equal(t, "low", q.pop().id)
+	require.Equal(t, "low2", q.pop().id)
+
+	// the low-priority task waited for 5 aging periods and outranks the new high-priority one
+	q = newPriorityQueue[string](time.Minute, start)
+	q.push(task[string]{id: "low", priority: 1, submittedAt: start})
+	q.push(task[string]{id: "high", priority: 5, submittedAt: start.Add(time.Minute * 5)})
+	q.push(task[string]{id: "high2", priority: 5, submittedAt: start.Add(time.Minute * 3)})
+	require.Equal(t, "high2", q.pop().id)
+	require.Equal(t, "low", q.pop().id)
+	require.Equal(t, "high", q.pop().id)
+
+	// the ranks of huge priorities with a long aging period saturate instead of overflowing,
+	// the saturated ones are ordered by age
+	q = newPriorityQueue[string](time.Hour*24*365, start)
+	q.push(task[string]{id: "low", priority: math.MinInt, submittedAt: start.Add(time.Minute)})
+	q.push(task[string]{id: "high", priority: math.MaxInt, submittedAt: start.Add(time.Minute)})
+	q.push(task[string]{id: "normal", priority: 1, submittedAt: start.Add(time.Minute)})
+	q.push(task[string]{id: "high2", priority: math.MaxInt / 2, submittedAt: start})
+	require.Equal(t, "high2", q.pop().id)
+	require.Equal(t, "high", q.pop().id)
+	require.Equal(t, "normal", q.pop().id)
+	require.Equal(t, "low", q.pop().id)
+}
+
+// TestPriorities checks that the pool dispatches the tasks in priority order.
+func TestPriorities(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[string](clock), WithPriorities[string](0),
+		WithMaxDrainBatch[string](1), WithOrderedDispatch[string]())
+
+	order := make(chan string, 3)
+	pool.SubmitWithPriority("low", 1, func() { order <- "low" })
+	pool.SubmitWithPriority("high", 10, func() { order <- "high" })
+	require.True(t, pool.TrySubmitWithPriority("mid", 5, func() { order <- "mid" }))
+
+	clock.tickChan <- time.Now()
+	require.Equal(t, "high", <-order)
+	require.Equal(t, 2, pool.Stats().Pending)
+
+	pool.StopAndWait()
+	require.Equal(t, "mid", <-order)
+	require.Equal(t, "low", <-order)
+}
+
+// TestPriorityUpgrade checks that a duplicate with a higher priority upgrades the pending task.
+func TestPriorityUpgrade(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[string](clock), WithPriorities[string](0),
+		WithMaxDrainBatch[string](1), WithOrderedDispatch[string]())
+
+	order := make(chan string, 3)
+	pool.SubmitWithPriority("a", 1, func() { order <- "a" })
+	pool.SubmitWithPriority("b", 5, func() { order <- "b" })
+	clock.tickChan <- time.Now()
+	require.Equal(t, "b", <-order)
+
+	pool.SubmitWithPriority("c", 5, func() { order <- "c" })
+	pool.SubmitWithPriority("a", 10, func() { order <- "a2" })
+	require.Equal(t, uint64(1), pool.Stats().Coalesced)
+
+	clock.tickChan <- time.Now()
+	require.Equal(t, "a", <-order)
+
+	pool.StopAndWait()
+	require.Equal(t, "c", <-order)
+}
+
+// TestSetPriority checks that the priority of a pending task can be raised and lowered.
+func TestSetPriority(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[string](clock), WithPriorities[string](0),
+		WithMaxDrainBatch[string](1), WithOrderedDispatch[string]())
+
+	order := make(chan string, 3)
+	for id, priority := range map[string]int{"a": 1, "b": 5, "c": 3} {
+		id := id
+		pool.SubmitWithPriority(id, priority, func() { order <- id })
+	}
+
+	require.True(t, pool.SetPriority("a", 10))
+	require.True(t, pool.SetPriority("b", 0))
+	require.False(t, pool.SetPriority("unknown", 10))
+
+	clock.tickChan <- time.Now()
+	require.Equal(t, "a", <-order)
+
+	pool.StopAndWait()
+	require.Equal(t, "c", <-order)
+	require.Equal(t, "b", <-order)
+
+	fifo := MustNew[string](10, 1, 10, time.Hour)
+	fifo.Submit("a", func() {})
+	require.False(t, fifo.SetPriority("a", 10))
+	fifo.StopAndWait()
+}
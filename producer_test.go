@@ -0,0 +1,51 @@
+package uniqpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestProducer checks that the buffered submissions are flushed on size and after the flush interval.
+func TestProducer(t *testing.T) {
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	pool := MustNew(100, 4, 100, time.Millisecond, WithDedupStripes[int](4))
+	pr := pool.Producer(3, time.Millisecond*50)
+
+	pr.Submit(1, fn)
+	pr.Submit(1, fn)
+	pr.Submit(2, fn)
+	require.Zero(t, pool.Stats().Submitted)
+	require.Equal(t, uint64(1), pool.Stats().Coalesced)
+
+	// the buffer is full
+	pr.Submit(3, fn)
+	require.Equal(t, uint64(3), pool.Stats().Submitted)
+
+	// the flush interval
+	pr.Submit(4, fn)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 4 }, time.Second, time.Millisecond)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			pr := pool.Producer(10, time.Hour)
+			for i := 0; i < 50; i++ {
+				pr.Submit(100+g*50+i, fn)
+			}
+			pr.Flush()
+		}(g)
+	}
+	wg.Wait()
+
+	pool.StopAndWait()
+	require.Equal(t, int32(404), atomic.LoadInt32(&processed))
+	require.Panics(t, func() { pr.Submit(5, fn) })
+}
@@ -0,0 +1,116 @@
+package uniqpool
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// NamespaceProfile overrides the configuration of the pool for the tasks of a namespace
+// (see WithNamespaceProfiles). Zero fields keep the configuration of the pool.
+type NamespaceProfile struct {
+	// The maximum time the tasks of the namespace wait in the inbound queue. The inbound queue is flushed early
+	// for them, but cannot hold them back: New returns an error wrapping ErrInvalidParameters if it is longer
+	// than the interval of the pool or of one of its lanes.
+	Interval time.Duration
+	// The number of the pending tasks of the namespace at which the inbound queue is flushed right away.
+	FlushThreshold int
+	// Replaces the window of WithSuppressionWindow for the identifiers of the namespace.
+	SuppressionWindow time.Duration
+	// The priority of the tasks of the namespace submitted without a priority. Used only if priorities are enabled.
+	Priority int
+}
+
+// resolveClassifier resolves the namespace classifier shared by WithNamespaceQuotas and WithNamespaceProfiles.
+// Returns an error if both or neither options set a classifier.
+func (p *UniqPool[T]) resolveClassifier() error {
+	if p.profileClassifier != nil {
+		if p.namespaceClassifier != nil {
+			return fmt.Errorf("%w: both WithNamespaceQuotas and WithNamespaceProfiles set a namespace classifier",
+				ErrInvalidParameters)
+		}
+		p.namespaceClassifier = p.profileClassifier
+	}
+	if p.namespaceClassifier == nil && (p.namespaceSlots != nil || p.profiles != nil) {
+		return fmt.Errorf("%w: neither WithNamespaceQuotas nor WithNamespaceProfiles set a namespace classifier",
+			ErrInvalidParameters)
+	}
+
+	return nil
+}
+
+// checkProfiles returns an error if the interval of a namespace profile is longer than the interval of the pool
+// or of one of its lanes.
+func (p *UniqPool[T]) checkProfiles() error {
+	for namespace, profile := range p.profiles {
+		if profile.Interval > p.interval {
+			return fmt.Errorf("%w: interval %v of namespace %q is longer than the interval of the pool %v",
+				ErrInvalidParameters, profile.Interval, namespace, p.interval)
+		}
+		for _, spec := range p.laneSpecs {
+			if profile.Interval > spec.interval {
+				return fmt.Errorf("%w: interval %v of namespace %q is longer than the interval of lane %q %v",
+					ErrInvalidParameters, profile.Interval, namespace, spec.name, spec.interval)
+			}
+		}
+	}
+
+	return nil
+}
+
+// profilePushed applies the profile of the namespace of the task pushed into the inbound queue.
+func (p *UniqPool[T]) profilePushed(t task[T]) {
+	profile, ok := p.profiles[t.namespace]
+	if !ok {
+		return
+	}
+
+	// checked by New to be not longer than the interval of the pool
+	if profile.Interval > 0 && profile.Interval < p.interval {
+		p.flushBy(t.submittedAt.Add(profile.Interval))
+	}
+	if pending := p.profilePending[t.namespace]; pending != nil &&
+		atomic.AddInt64(pending, 1) >= int64(profile.FlushThreshold) {
+		p.requestFlush()
+	}
+}
+
+// freeSlots frees the namespace quota slot, the memory budget and the namespace counter of the task
+// that has left the inbound queue.
+func (p *UniqPool[T]) freeSlots(t task[T]) {
+	if slots := p.namespaceSlots[t.namespace]; slots != nil {
+		<-slots
+	}
+	p.releaseMemory(t)
+	if pending := p.profilePending[t.namespace]; pending != nil {
+		atomic.AddInt64(pending, -1)
+	}
+}
+
+// suppressionWindowOf returns the suppression window of the identifier, taking the namespace profiles into account.
+func (p *UniqPool[T]) suppressionWindowOf(id T) time.Duration {
+	if p.profileWindows {
+		return p.namespaceWindow(p.namespaceClassifier(id))
+	}
+
+	return p.suppressionWindow
+}
+
+// taskSuppressionWindow returns the suppression window of the task. Unlike suppressionWindowOf, it takes
+// the namespace resolved on submission, so it does not call the classifier, which may panic.
+func (p *UniqPool[T]) taskSuppressionWindow(t *task[T]) time.Duration {
+	if p.profileWindows {
+		return p.namespaceWindow(t.namespace)
+	}
+
+	return p.suppressionWindow
+}
+
+// namespaceWindow returns the suppression window of the namespace.
+func (p *UniqPool[T]) namespaceWindow(namespace string) time.Duration {
+	if window := p.profiles[namespace].SuppressionWindow; window > 0 {
+		return window
+	}
+
+	return p.suppressionWindow
+}
@@ -0,0 +1,95 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNamespaceProfiles checks the interval, the flush threshold and the suppression window of the namespaces.
+func TestNamespaceProfiles(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Hour, WithNamespaceProfiles[string](PrefixClassifier("fresh:", "batch:", "rare:"),
+		map[string]NamespaceProfile{
+			"fresh:": {Interval: time.Millisecond * 10},
+			"batch:": {FlushThreshold: 3},
+			"rare:":  {Interval: time.Millisecond, SuppressionWindow: time.Hour},
+		}))
+
+	var executed int32
+	fn := func() { atomic.AddInt32(&executed, 1) }
+
+	// the interval of the namespace is shorter than the interval of the pool
+	pool.Submit("fresh:1", fn)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&executed) == 1 }, time.Second, time.Millisecond)
+
+	// the inbound queue is flushed once the namespace reaches the threshold
+	pool.Submit("batch:1", fn)
+	pool.Submit("batch:2", fn)
+	time.Sleep(time.Millisecond * 20)
+	require.Equal(t, int32(1), atomic.LoadInt32(&executed))
+	pool.Submit("batch:3", fn)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&executed) == 4 }, time.Second, time.Millisecond)
+
+	// the window of the namespace suppresses the identifier executed recently
+	pool.Submit("rare:1", fn)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&executed) == 5 }, time.Second, time.Millisecond)
+	outcome, err := pool.SubmitEx("rare:1", fn)
+	require.NoError(t, err)
+	require.Equal(t, Suppressed, outcome)
+	outcome, err = pool.SubmitEx("other", fn)
+	require.NoError(t, err)
+	require.Equal(t, Enqueued, outcome)
+
+	pool.StopAndWait()
+	require.Equal(t, int32(6), atomic.LoadInt32(&executed))
+
+	// the priority of the namespace is the default one
+	pool = MustNew(10, 2, 10, time.Hour, WithPriorities[string](0),
+		WithNamespaceProfiles[string](PrefixClassifier("urgent:"), map[string]NamespaceProfile{"urgent:": {Priority: 5}}))
+	pool.Submit("urgent:1", fn)
+	pool.SubmitWithPriority("urgent:2", 1, fn)
+	info, ok := pool.Peek("urgent:1")
+	require.True(t, ok)
+	require.Equal(t, 5, info.Priority)
+	info, ok = pool.Peek("urgent:2")
+	require.True(t, ok)
+	require.Equal(t, 1, info.Priority)
+	pool.StopAndWait()
+}
+
+// TestNamespaceClassifierShared checks that the namespace quotas and the profiles share one classifier.
+func TestNamespaceClassifierShared(t *testing.T) {
+	classify := PrefixClassifier("urgent:")
+	profiles := map[string]NamespaceProfile{"urgent:": {Priority: 5}}
+
+	_, err := New(10, 2, 10, time.Hour, WithNamespaceQuotas(classify, map[string]int{"urgent:": 1}),
+		WithNamespaceProfiles(classify, profiles))
+	require.ErrorIs(t, err, ErrInvalidParameters)
+
+	// the profiles use the classifier of the quotas
+	pool := MustNew(10, 2, 10, time.Hour, WithPriorities[string](0),
+		WithNamespaceQuotas(classify, map[string]int{"urgent:": 1}), WithNamespaceProfiles[string](nil, profiles))
+	require.True(t, pool.TrySubmit("urgent:1", func() {}))
+	require.False(t, pool.TrySubmit("urgent:2", func() {}))
+	info, ok := pool.Peek("urgent:1")
+	require.True(t, ok)
+	require.Equal(t, 5, info.Priority)
+	pool.StopAndWait()
+}
+
+// TestNamespaceProfileLongInterval checks that a profile cannot set an interval longer than the interval of the pool.
+func TestNamespaceProfileLongInterval(t *testing.T) {
+	profiles := map[string]NamespaceProfile{"slow:": {Interval: time.Second}}
+
+	_, err := New(10, 2, 10, time.Millisecond*100, WithNamespaceProfiles(PrefixClassifier("slow:"), profiles))
+	require.ErrorIs(t, err, ErrInvalidParameters)
+	_, err = New(10, 2, 10, time.Hour, WithNamespaceProfiles(PrefixClassifier("slow:"), profiles),
+		WithLane[string]("interactive", time.Millisecond*10, 10))
+	require.ErrorIs(t, err, ErrInvalidParameters)
+
+	pool, err := New(10, 2, 10, time.Second, WithNamespaceProfiles(PrefixClassifier("slow:"), profiles))
+	require.NoError(t, err)
+	pool.StopAndWait()
+}
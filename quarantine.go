@@ -0,0 +1,134 @@
+package uniqpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// quarantine tracks the failures of the tasks and quarantines the identifiers that fail repeatedly.
+type quarantine[T comparable] struct {
+	// The number of failures within the window that quarantines the identifier.
+	failures int
+	window   time.Duration
+	// Called when an identifier is quarantined. Nil if not set.
+	onQuarantine func(id T, err error)
+
+	// The times of the recent failures by identifier.
+	failedAt map[T][]time.Time
+	// Quarantined identifiers.
+	keys  map[T]struct{}
+	mutex sync.Mutex
+}
+
+func newQuarantine[T comparable](failures int, window time.Duration, onQuarantine func(id T, err error),
+) *quarantine[T] {
+	return &quarantine[T]{
+		failures:     failures,
+		window:       window,
+		onQuarantine: onQuarantine,
+		failedAt:     make(map[T][]time.Time),
+		keys:         make(map[T]struct{}),
+	}
+}
+
+// completed records the result of the task and quarantines its identifier if it fails too often.
+// A success clears the failures of the identifier.
+func (q *quarantine[T]) completed(id T, err error, now time.Time) {
+	q.mutex.Lock()
+	if err == nil {
+		delete(q.failedAt, id)
+		q.mutex.Unlock()
+		return
+	}
+
+	recent := q.failedAt[id][:0]
+	for _, at := range q.failedAt[id] {
+		if now.Sub(at) < q.window {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+
+	quarantined := len(recent) >= q.failures
+	if quarantined {
+		delete(q.failedAt, id)
+		q.keys[id] = struct{}{}
+	} else {
+		q.failedAt[id] = recent
+	}
+	q.mutex.Unlock()
+
+	if quarantined && q.onQuarantine != nil {
+		q.onQuarantine(id, err)
+	}
+}
+
+// contains returns true if the identifier is quarantined.
+func (q *quarantine[T]) contains(id T) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	_, ok := q.keys[id]
+	return ok
+}
+
+// Quarantined returns the quarantined task identifiers (see WithQuarantine).
+func (p *UniqPool[T]) Quarantined() []T {
+	if p.quarantine == nil {
+		return nil
+	}
+
+	p.quarantine.mutex.Lock()
+	defer p.quarantine.mutex.Unlock()
+
+	ids := make([]T, 0, len(p.quarantine.keys))
+	for id := range p.quarantine.keys {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// Unquarantine releases the identifier from the quarantine, e.g. after the malformed entity is fixed.
+// Returns false if the identifier is not quarantined.
+func (p *UniqPool[T]) Unquarantine(id T) bool {
+	if p.quarantine == nil {
+		return false
+	}
+
+	id = p.normalizeKey(id)
+
+	p.quarantine.mutex.Lock()
+	defer p.quarantine.mutex.Unlock()
+
+	if _, ok := p.quarantine.keys[id]; !ok {
+		return false
+	}
+	delete(p.quarantine.keys, id)
+
+	return true
+}
+
+// skip drops the dispatched task of the quarantined identifier.
+func (p *UniqPool[T]) skip(t task[T]) {
+	if t.probe {
+		// the probe is not executed, so the breaker must dispatch another one
+		p.breaker.cancelProbe()
+	}
+	atomic.AddUint64(&p.counters.suppressed, 1)
+
+	waiters := t.waiters
+	if p.keyRelease == ReleaseOnCompletion {
+		waiters = append(waiters, p.releaseCompleted(t.id)...)
+	}
+	for _, w := range waiters {
+		w(t.execID, nil, ErrQuarantined)
+	}
+	p.observe(EventDropped, t.id, ErrQuarantined)
+
+	if t.chainFn != nil {
+		// no follow-up tasks will be pushed
+		p.pushed()
+	}
+}
@@ -0,0 +1,43 @@
+package uniqpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuarantine checks that an identifier failing repeatedly is quarantined and its tasks are not executed.
+func TestQuarantine(t *testing.T) {
+	errFailed := errors.New("failed")
+	quarantined := make(chan string, 1)
+	pool := MustNew(10, 2, 10, time.Millisecond,
+		WithRequeue[string](time.Millisecond, time.Millisecond, nil),
+		WithQuarantine(3, time.Minute, func(id string, err error) {
+			require.Equal(t, errFailed, err)
+			quarantined <- id
+		}))
+
+	var attempts int32
+	pool.SubmitRetryable("poison", func(context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return errFailed
+	})
+	require.Equal(t, "poison", <-quarantined)
+	require.Equal(t, []string{"poison"}, pool.Quarantined())
+
+	outcome, err := pool.SubmitEx("poison", func() { atomic.AddInt32(&attempts, 1) })
+	require.NoError(t, err)
+	require.Equal(t, Suppressed, outcome)
+
+	require.True(t, pool.Unquarantine("poison"))
+	require.False(t, pool.Unquarantine("poison"))
+	pool.Submit("poison", func() { atomic.AddInt32(&attempts, 1) })
+
+	pool.StopAndWait()
+	require.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+	require.Empty(t, pool.Quarantined())
+}
@@ -0,0 +1,218 @@
+package uniqpool
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// inboundQueue is a bounded lock-free multi-producer single-consumer FIFO queue of tasks.
+// Producers reserve a slot before pushing, so the capacity is never exceeded.
+// By default the queue is an intrusive linked list (Vyukov MPSC): producers atomically swap the head,
+// the single consumer moves the tail. Optionally the tasks are stored in a preallocated ring buffer instead.
+type inboundQueue[T comparable] struct {
+	// The last pushed node. Updated by producers.
+	head atomic.Pointer[queueNode[T]]
+	// The node before the first task. Used only by the consumer.
+	tail *queueNode[T]
+
+	// The maximum number of reserved slots. Updated atomically.
+	capacity int64
+	// The number of reserved slots: pushed tasks plus producers about to push.
+	reserved int64
+
+	// The number of producers waiting for a free slot.
+	waiters int32
+	// Closed and replaced when slots are freed, to wake up the waiting producers.
+	spaceChan  chan struct{}
+	spaceMutex sync.Mutex
+
+	// Free nodes, so pushing does not allocate in the steady state.
+	nodePool sync.Pool
+
+	// Stores the tasks instead of the linked list. Nil if the linked list is used.
+	ring *ringBuffer[T]
+}
+
+type queueNode[T comparable] struct {
+	next atomic.Pointer[queueNode[T]]
+	t    task[T]
+}
+
+func newInboundQueue[T comparable](capacity int) *inboundQueue[T] {
+	stub := &queueNode[T]{}
+	q := &inboundQueue[T]{
+		tail:      stub,
+		capacity:  int64(capacity),
+		spaceChan: make(chan struct{}),
+	}
+	q.head.Store(stub)
+	q.nodePool.New = func() any { return &queueNode[T]{} }
+
+	return q
+}
+
+// newRingInboundQueue creates an inbound queue storing the tasks in a preallocated ring buffer.
+func newRingInboundQueue[T comparable](capacity int) *inboundQueue[T] {
+	q := newInboundQueue[T](capacity)
+	q.ring = newRingBuffer[T](capacity)
+
+	return q
+}
+
+// tryReserve reserves a slot for a task without blocking. Returns false if the queue is full.
+func (q *inboundQueue[T]) tryReserve() bool {
+	for {
+		reserved := atomic.LoadInt64(&q.reserved)
+		if reserved >= atomic.LoadInt64(&q.capacity) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&q.reserved, reserved, reserved+1) {
+			return true
+		}
+	}
+}
+
+// reserve reserves a slot for a task, waiting until one is free.
+func (q *inboundQueue[T]) reserve() {
+	atomic.AddInt32(&q.waiters, 1)
+	defer atomic.AddInt32(&q.waiters, -1)
+
+	for {
+		if q.tryReserve() {
+			return
+		}
+
+		q.spaceMutex.Lock()
+		spaceChan := q.spaceChan
+		q.spaceMutex.Unlock()
+
+		// check again, the slot could be freed before spaceChan was taken
+		if q.tryReserve() {
+			return
+		}
+
+		<-spaceChan
+	}
+}
+
+// push adds the task to the queue. A slot must be reserved before.
+func (q *inboundQueue[T]) push(t task[T]) {
+	if q.ring != nil {
+		q.ring.push(t)
+		return
+	}
+
+	n := q.nodePool.Get().(*queueNode[T])
+	n.t = t
+	prev := q.head.Swap(n)
+	// until this store the consumer does not see the node and the following ones
+	prev.next.Store(n)
+}
+
+// pop removes the first task from the queue and frees its slot. Must be called only by the consumer.
+// Returns false if the queue is empty.
+func (q *inboundQueue[T]) pop() (task[T], bool) {
+	t, ok := q.popHeld()
+	if ok {
+		q.release(1)
+	}
+
+	return t, ok
+}
+
+// popHeld removes the first task from the queue, but keeps its slot until release is called.
+// Must be called only by the consumer. Returns false if the queue is empty.
+func (q *inboundQueue[T]) popHeld() (task[T], bool) {
+	if q.ring != nil {
+		return q.ring.pop()
+	}
+
+	next := q.tail.next.Load()
+	if next == nil {
+		return task[T]{}, false
+	}
+
+	t := next.t
+	// the node becomes the new stub, so the task is cleared to not retain its closure
+	next.t = task[T]{}
+
+	// the producer that linked the next node has finished with the old stub, so it can be reused
+	stub := q.tail
+	q.tail = next
+	stub.next.Store(nil)
+	q.nodePool.Put(stub)
+
+	return t, true
+}
+
+// release frees the slots of n tasks removed by popHeld.
+func (q *inboundQueue[T]) release(n int) {
+	atomic.AddInt64(&q.reserved, -int64(n))
+}
+
+// notifySpace wakes up the producers waiting for a free slot.
+func (q *inboundQueue[T]) notifySpace() {
+	if atomic.LoadInt32(&q.waiters) == 0 {
+		return
+	}
+
+	q.spaceMutex.Lock()
+	close(q.spaceChan)
+	q.spaceChan = make(chan struct{})
+	q.spaceMutex.Unlock()
+}
+
+// len returns the number of tasks in the queue, including the ones with reserved slots that are not pushed yet.
+func (q *inboundQueue[T]) len() int {
+	return int(atomic.LoadInt64(&q.reserved))
+}
+
+// cap returns the capacity of the queue.
+func (q *inboundQueue[T]) cap() int {
+	return int(atomic.LoadInt64(&q.capacity))
+}
+
+// setCap changes the capacity of the queue. If the queue holds more tasks than the new capacity,
+// they are kept and new tasks wait until the queue is drained below it.
+func (q *inboundQueue[T]) setCap(capacity int) {
+	atomic.StoreInt64(&q.capacity, int64(capacity))
+	q.notifySpace()
+}
+
+// SetQueueCapacity changes the capacity of the inbound queue at runtime, e.g. to absorb bigger bursts without
+// restarting the service. Growing takes effect immediately and wakes up the blocked submitters. Shrinking is lazy:
+// the pending tasks above the new capacity are kept, and new tasks wait until the queue is drained below it.
+// Unless set by WithMaxDrainBatch, the number of tasks dispatched per interval follows the capacity.
+// Returns an error wrapping ErrInvalidParameters if capacity is not positive, if the inbound queue is unbounded,
+// or if it exceeds the preallocated size of the ring buffer (see WithRingBuffer).
+func (p *UniqPool[T]) SetQueueCapacity(capacity int) error {
+	switch {
+	case capacity <= 0:
+		return fmt.Errorf("%w: inbound queue capacity must be positive, got %d", ErrInvalidParameters, capacity)
+	case p.unbounded:
+		return fmt.Errorf("%w: inbound queue is unbounded", ErrInvalidParameters)
+	case p.inboundQueue.ring != nil && capacity > len(p.inboundQueue.ring.cells):
+		return fmt.Errorf("%w: inbound queue capacity must not exceed the ring buffer size %d, got %d",
+			ErrInvalidParameters, len(p.inboundQueue.ring.cells), capacity)
+	}
+
+	if !p.maxBatchFixed {
+		atomic.StoreInt64(&p.maxBatch, int64(capacity))
+	}
+	p.inboundQueue.setCap(capacity)
+
+	return nil
+}
+
+// checkSoftCap calls the soft cap hook once the unbounded inbound queue exceeds the soft cap.
+// The hook is called again only after the queue is drained below the soft cap.
+func (p *UniqPool[T]) checkSoftCap() {
+	if p.softCap <= 0 {
+		return
+	}
+
+	if pending := p.inboundQueue.len(); pending > p.softCap && atomic.CompareAndSwapInt32(&p.overSoftCap, 0, 1) {
+		p.onSoftCap(pending)
+	}
+}
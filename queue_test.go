@@ -0,0 +1,206 @@
+package uniqpool
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// queueKinds are the constructors of the inbound queue implementations.
+var queueKinds = map[string]func(capacity int) *inboundQueue[int]{
+	"linked": newInboundQueue[int],
+	"ring":   newRingInboundQueue[int],
+}
+
+// TestInboundQueue checks that the inbound queue keeps the order of each producer and never exceeds its capacity.
+func TestInboundQueue(t *testing.T) {
+	for name, newQueue := range queueKinds {
+		newQueue := newQueue
+		t.Run(name, func(t *testing.T) {
+			testInboundQueue(t, newQueue(16))
+		})
+	}
+}
+
+func testInboundQueue(t *testing.T, q *inboundQueue[int]) {
+	const (
+		producers   = 8
+		perProducer = 1000
+		capacity    = 16
+	)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				q.reserve()
+				q.push(task[int]{id: p*perProducer + i})
+			}
+		}(p)
+	}
+
+	last := make([]int, producers)
+	for p := range last {
+		last[p] = -1
+	}
+
+	for received := 0; received < producers*perProducer; {
+		tsk, ok := q.pop()
+		if !ok {
+			q.notifySpace()
+			continue
+		}
+		received++
+
+		p, i := tsk.id/perProducer, tsk.id%perProducer
+		require.Greater(t, i, last[p])
+		last[p] = i
+	}
+
+	wg.Wait()
+
+	_, ok := q.pop()
+	require.False(t, ok)
+	require.Zero(t, q.reserved)
+
+	for i := 0; i < capacity; i++ {
+		require.True(t, q.tryReserve())
+	}
+	require.False(t, q.tryReserve())
+}
+
+// TestInboundQueueAllocs checks that the inbound queue does not allocate in the steady state.
+func TestInboundQueueAllocs(t *testing.T) {
+	fn := func() {}
+	for name, newQueue := range queueKinds {
+		q := newQueue(1)
+		allocs := testing.AllocsPerRun(1000, func() {
+			q.reserve()
+			q.push(task[int]{id: 1, fn: fn})
+			q.pop()
+		})
+		require.Zero(t, allocs, name)
+	}
+}
+
+// BenchmarkInboundQueue compares the inbound queue implementations with a channel: parallel producers
+// push the tasks, a single consumer pops them.
+func BenchmarkInboundQueue(b *testing.B) {
+	const capacity = 1024
+	fn := func() {}
+
+	for _, name := range []string{"linked", "ring"} {
+		newQueue := queueKinds[name]
+		b.Run(name, func(b *testing.B) {
+			q := newQueue(capacity)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				// like the dispatcher, the consumer drains the queue and then wakes up the waiting producers
+				for popped := 0; popped < b.N; {
+					n := 0
+					for _, ok := q.pop(); ok; _, ok = q.pop() {
+						n++
+					}
+					if n == 0 {
+						runtime.Gosched()
+						continue
+					}
+					popped += n
+					q.notifySpace()
+				}
+			}()
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					q.reserve()
+					q.push(task[int]{id: 1, fn: fn})
+				}
+			})
+			<-done
+		})
+	}
+
+	b.Run("channel", func(b *testing.B) {
+		ch := make(chan task[int], capacity)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for popped := 0; popped < b.N; popped++ {
+				<-ch
+			}
+		}()
+
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				ch <- task[int]{id: 1, fn: fn}
+			}
+		})
+		<-done
+	})
+}
+
+// TestUnboundedQueue checks that the unbounded inbound queue accepts tasks above the capacity
+// and calls the soft cap hook once per overflow.
+func TestUnboundedQueue(t *testing.T) {
+	var warnings []int
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(2, 2, 10, time.Hour, WithClock[int](clock), WithMaxDrainBatch[int](10),
+		WithUnboundedQueue[int](3, func(pending int) { warnings = append(warnings, pending) }))
+
+	for i := 0; i < 5; i++ {
+		require.True(t, pool.TrySubmit(i, func() {}))
+	}
+	require.Equal(t, 5, pool.Stats().Pending)
+	require.Equal(t, []int{4}, warnings)
+
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 5 }, time.Second, time.Millisecond)
+
+	for i := 5; i < 9; i++ {
+		pool.Submit(i, func() {})
+	}
+	require.Equal(t, []int{4, 4}, warnings)
+
+	pool.StopAndWait()
+	require.Equal(t, uint64(9), pool.Stats().Dispatched)
+}
+
+// TestSetQueueCapacity checks that the capacity of the inbound queue grows right away and shrinks lazily.
+func TestSetQueueCapacity(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(2, 2, 10, time.Hour, WithClock[int](clock))
+
+	require.True(t, pool.TrySubmit(1, func() {}))
+	require.True(t, pool.TrySubmit(2, func() {}))
+	require.False(t, pool.TrySubmit(3, func() {}))
+
+	require.NoError(t, pool.SetQueueCapacity(4))
+	require.True(t, pool.TrySubmit(3, func() {}))
+	require.True(t, pool.TrySubmit(4, func() {}))
+	require.Equal(t, 4, pool.Stats().Pending)
+
+	// the tasks above the new capacity are kept
+	require.NoError(t, pool.SetQueueCapacity(1))
+	require.False(t, pool.TrySubmit(5, func() {}))
+	require.Equal(t, 4, pool.Stats().Pending)
+
+	// the number of tasks dispatched per tick follows the capacity
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 1 }, time.Second, time.Millisecond)
+
+	require.ErrorIs(t, pool.SetQueueCapacity(0), ErrInvalidParameters)
+	ring := MustNew(2, 2, 10, time.Hour, WithRingBuffer[int]())
+	require.ErrorIs(t, ring.SetQueueCapacity(3), ErrInvalidParameters)
+	ring.StopAndWait()
+
+	pool.StopAndWait()
+	require.Equal(t, uint64(4), pool.Stats().Dispatched)
+}
@@ -0,0 +1,122 @@
+package uniqpool
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultStatsWindow is the default sliding window of the rolling statistics.
+	defaultStatsWindow = time.Second * 10
+	// rateSamplesPerWindow is the number of samples of the counters kept per window.
+	rateSamplesPerWindow = 10
+)
+
+// rateSample is the state of the counters at some moment.
+type rateSample struct {
+	at         time.Time
+	submitted  uint64
+	coalesced  uint64
+	dispatched uint64
+	executed   uint64
+}
+
+// rateWindow computes the rolling rates of the counters over a sliding window from their periodic samples.
+type rateWindow struct {
+	mutex  sync.Mutex
+	window time.Duration
+	// Samples ordered by time. The first one is the latest sample taken before the window.
+	samples []rateSample
+}
+
+// observe records the sample and returns the sample taken the window ago or the closest one before it.
+func (w *rateWindow) observe(s rateSample) rateSample {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if n := len(w.samples); n == 0 || s.at.Sub(w.samples[n-1].at) >= w.window/rateSamplesPerWindow {
+		w.samples = append(w.samples, s)
+	}
+
+	start := s.at.Add(-w.window)
+	drop := 0
+	for drop+1 < len(w.samples) && !w.samples[drop+1].at.After(start) {
+		drop++
+	}
+	if drop > 0 {
+		w.samples = append(w.samples[:0], w.samples[drop:]...)
+	}
+
+	return w.samples[0]
+}
+
+// sample returns the current state of the counters.
+func (p *UniqPool[T]) sample() rateSample {
+	return rateSample{
+		at:         p.clock.Now(),
+		submitted:  atomic.LoadUint64(&p.counters.submitted),
+		coalesced:  atomic.LoadUint64(&p.counters.coalesced),
+		dispatched: atomic.LoadUint64(&p.counters.dispatched),
+		executed:   atomic.LoadUint64(&p.counters.executed),
+	}
+}
+
+// rollingStats fills in the rolling statistics over the sliding window.
+func (p *UniqPool[T]) rollingStats(stats *Stats) {
+	now := p.sample()
+	base := p.rates.observe(now)
+
+	stats.windowSubmitted = now.submitted - base.submitted
+	stats.windowCoalesced = now.coalesced - base.coalesced
+	stats.DedupRatio = dedupRatio(stats.windowSubmitted, stats.windowCoalesced)
+
+	if elapsed := now.at.Sub(base.at); elapsed > 0 {
+		stats.Throughput = float64(now.dispatched-base.dispatched) / elapsed.Seconds()
+	}
+}
+
+// throughput returns the number of tasks dispatched per second over the sliding window.
+func (p *UniqPool[T]) throughput() float64 {
+	now := p.sample()
+	base := p.rates.observe(now)
+
+	elapsed := now.at.Sub(base.at)
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return float64(now.dispatched-base.dispatched) / elapsed.Seconds()
+}
+
+// EstimatedDrain estimates the time to execute the backlog: the tasks in the inbound queue and the dispatched
+// tasks waiting for a worker, divided by the number of tasks started per second over the sliding window
+// (see WithStatsWindow). Stopping the pool dispatches the backlog right away, so orchestration can use it to decide
+// whether a graceful shutdown fits in its termination grace period. Returns zero if the backlog is empty,
+// and math.MaxInt64 if no task was started over the window, so the drain time is unknown.
+func (p *UniqPool[T]) EstimatedDrain() time.Duration {
+	backlog := p.inboundQueue.len() + int(atomic.LoadInt64(&p.executorBacklog))
+	if backlog <= 0 {
+		return 0
+	}
+
+	now := p.sample()
+	base := p.rates.observe(now)
+	elapsed := now.at.Sub(base.at)
+	if elapsed <= 0 || now.executed == base.executed {
+		return math.MaxInt64
+	}
+
+	rate := float64(now.executed-base.executed) / elapsed.Seconds()
+	return time.Duration(float64(backlog) / rate * float64(time.Second))
+}
+
+// dedupRatio returns the fraction of the submissions coalesced with the pending tasks.
+func dedupRatio(submitted, coalesced uint64) float64 {
+	if submitted+coalesced == 0 {
+		return 0
+	}
+
+	return float64(coalesced) / float64(submitted+coalesced)
+}
@@ -0,0 +1,69 @@
+package uniqpool
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateWindow checks that the base sample is taken from the start of the window.
+func TestRateWindow(t *testing.T) {
+	w := rateWindow{window: time.Second * 10}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i <= 30; i++ {
+		base := w.observe(rateSample{at: start.Add(time.Second * time.Duration(i)), dispatched: uint64(i * 5)})
+		if i >= 10 {
+			require.Equal(t, start.Add(time.Second*time.Duration(i-10)), base.at)
+			require.Equal(t, uint64((i-10)*5), base.dispatched)
+		}
+	}
+	require.Len(t, w.samples, 11)
+}
+
+// TestRollingStats checks the dedup ratio and the throughput reported by Stats.
+func TestRollingStats(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Millisecond*10)
+
+	fn := func() {}
+	pool.Submit("task1", fn)
+	pool.Submit("task1", fn)
+	pool.Submit("task1", fn)
+	pool.Submit("task1", fn)
+
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 1 }, time.Second, time.Millisecond)
+
+	stats := pool.Stats()
+	require.Equal(t, 0.75, stats.DedupRatio)
+	require.Positive(t, stats.Throughput)
+
+	pool.StopAndWait()
+}
+
+// TestEstimatedDrain checks that the drain time is estimated once tasks are executed over the window.
+func TestEstimatedDrain(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[string](clock))
+	require.Zero(t, pool.EstimatedDrain())
+
+	gate := make(chan struct{})
+	pool.Submit("task1", func() {})
+	pool.Submit("task2", func() { <-gate })
+	require.Equal(t, time.Duration(math.MaxInt64), pool.EstimatedDrain())
+
+	clock.tickChan <- time.Now()
+	pool.Submit("task3", func() {})
+	require.Eventually(t, func() bool { return atomic.LoadUint64(&pool.counters.executed) == 2 },
+		time.Second, time.Millisecond)
+
+	drain := pool.EstimatedDrain()
+	require.Positive(t, drain)
+	require.Less(t, drain, time.Duration(math.MaxInt64))
+
+	close(gate)
+	pool.StopAndWait()
+	require.Zero(t, pool.EstimatedDrain())
+}
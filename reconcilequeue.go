@@ -0,0 +1,129 @@
+package uniqpool
+
+import (
+	"sync"
+	"time"
+)
+
+// ReconcileQueue provides the rate-limiting part of the workqueue of k8s.io/client-go on top of the pool, so
+// the reconcile loops of Kubernetes-style controllers can adopt it with dedup for free. An item that failed is
+// requeued with AddRateLimited after a per-item exponential backoff, and Forget resets the backoff after the item
+// is processed successfully. Unlike the client-go workqueue, it is push-based: items are task identifiers processed
+// by a single handler called by the pool, so there is no Get, Done or ShutDown: the queue is shut down by stopping
+// the pool. With WithSerialKeys an item added while it is being processed is processed again after the handler
+// returns, like an item added between Get and Done. The identifiers are normalized by the normalizer of the pool,
+// if any.
+type ReconcileQueue[T comparable] struct {
+	pool    *UniqPool[T]
+	handler func(id T)
+
+	// The backoff of the first requeue.
+	baseDelay time.Duration
+	// The maximum backoff.
+	maxDelay time.Duration
+
+	// The number of requeues by identifier.
+	requeues map[T]int
+	// Items waiting to be added by identifier.
+	waiting map[T]*delayedItem
+	mutex   sync.Mutex
+}
+
+type delayedItem struct {
+	timer   Timer
+	readyAt time.Time
+}
+
+// NewReconcileQueue creates a new ReconcileQueue on top of the pool. handler is called for each processed item.
+// The backoff of an item starts with baseDelay and doubles with each requeue up to maxDelay.
+func NewReconcileQueue[T comparable](pool *UniqPool[T], handler func(id T), baseDelay, maxDelay time.Duration,
+) *ReconcileQueue[T] {
+	return &ReconcileQueue[T]{
+		pool:      pool,
+		handler:   handler,
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+		requeues:  make(map[T]int),
+		waiting:   make(map[T]*delayedItem),
+	}
+}
+
+// Add adds the item to the pool. The item is dropped if the pool is stopped.
+func (q *ReconcileQueue[T]) Add(id T) {
+	id = q.pool.normalizeKey(id)
+	_, _ = q.pool.SubmitEx(id, func() { q.handler(id) })
+}
+
+// AddAfter adds the item to the pool after the delay. If the item is already waiting,
+// it is added at the earlier of the two times.
+func (q *ReconcileQueue[T]) AddAfter(id T, delay time.Duration) {
+	if delay <= 0 {
+		q.Add(id)
+		return
+	}
+
+	id = q.pool.normalizeKey(id)
+	readyAt := q.pool.clock.Now().Add(delay)
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if item, ok := q.waiting[id]; ok {
+		if !readyAt.Before(item.readyAt) || !item.timer.Stop() {
+			// waits for less time or is being added right now
+			return
+		}
+		item.readyAt = readyAt
+		item.timer.Reset(delay)
+		return
+	}
+
+	item := &delayedItem{readyAt: readyAt}
+	item.timer = q.pool.clock.AfterFunc(delay, func() { q.fire(id, item) })
+	q.waiting[id] = item
+}
+
+// AddRateLimited adds the item to the pool after its backoff and increases the backoff.
+func (q *ReconcileQueue[T]) AddRateLimited(id T) {
+	id = q.pool.normalizeKey(id)
+
+	q.mutex.Lock()
+	requeues := q.requeues[id]
+	q.requeues[id] = requeues + 1
+	q.mutex.Unlock()
+
+	q.AddAfter(id, backoff(q.baseDelay, q.maxDelay, requeues))
+}
+
+// Forget resets the backoff of the item. Should be called when the item is processed successfully.
+func (q *ReconcileQueue[T]) Forget(id T) {
+	id = q.pool.normalizeKey(id)
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.requeues, id)
+}
+
+// NumRequeues returns the number of times the item has been requeued by AddRateLimited since the last Forget.
+func (q *ReconcileQueue[T]) NumRequeues(id T) int {
+	id = q.pool.normalizeKey(id)
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.requeues[id]
+}
+
+// fire adds the waiting item to the pool.
+func (q *ReconcileQueue[T]) fire(id T, item *delayedItem) {
+	q.mutex.Lock()
+	if q.waiting[id] != item {
+		q.mutex.Unlock()
+		return
+	}
+	delete(q.waiting, id)
+	q.mutex.Unlock()
+
+	q.Add(id)
+}
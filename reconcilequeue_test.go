@@ -0,0 +1,53 @@
+package uniqpool
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcileQueue checks the exponential backoff of the requeued items.
+func TestReconcileQueue(t *testing.T) {
+	var processed int32
+	pool := MustNew[string](10, 2, 10, time.Millisecond)
+	q := NewReconcileQueue(pool, func(string) { atomic.AddInt32(&processed, 1) }, time.Millisecond*10, time.Millisecond*40)
+
+	require.Equal(t, time.Millisecond*10, backoff(time.Millisecond*10, time.Millisecond*40, 0))
+	require.Equal(t, time.Millisecond*20, backoff(time.Millisecond*10, time.Millisecond*40, 1))
+	require.Equal(t, time.Millisecond*40, backoff(time.Millisecond*10, time.Millisecond*40, 2))
+	require.Equal(t, time.Millisecond*40, backoff(time.Millisecond*10, time.Millisecond*40, 10))
+
+	q.AddRateLimited("item")
+	q.AddRateLimited("item")
+	require.Equal(t, 2, q.NumRequeues("item"))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 1 }, time.Second, time.Millisecond)
+
+	q.Forget("item")
+	require.Zero(t, q.NumRequeues("item"))
+
+	q.Add("item")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 2 }, time.Second, time.Millisecond)
+
+	pool.StopAndWait()
+}
+
+// TestReconcileQueueKeyNormalizer checks that the identifiers equal after the normalization share one backoff.
+func TestReconcileQueueKeyNormalizer(t *testing.T) {
+	processed := make(chan string, 1)
+	pool := MustNew(10, 2, 10, time.Millisecond, WithKeyNormalizer(strings.ToLower))
+	q := NewReconcileQueue(pool, func(id string) { processed <- id }, time.Hour, time.Hour)
+
+	q.AddRateLimited("Item")
+	q.AddRateLimited("ITEM")
+	require.Equal(t, 2, q.NumRequeues("item"))
+	q.Forget("iTeM")
+	require.Zero(t, q.NumRequeues("Item"))
+
+	q.AddAfter("Item", time.Millisecond)
+	require.Equal(t, "item", <-processed)
+
+	pool.StopAndWait()
+}
@@ -0,0 +1,197 @@
+// Package redisstore implements uniqpool.UniqStore on top of Redis, so several replicas of a service
+// share one set of pending task identifiers: a task pending on any replica suppresses its duplicates
+// on all replicas.
+//
+// The package does not depend on a particular Redis driver. The Client interface is satisfied by a few lines
+// of glue code, e.g. for github.com/redis/go-redis:
+//
+//	type goRedisClient struct{ *redis.Client }
+//
+//	func (c goRedisClient) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+//		return c.Client.SetNX(ctx, key, value, ttl).Result()
+//	}
+//
+//	var delIfValue = redis.NewScript(redisstore.DelIfValueScript)
+//
+//	func (c goRedisClient) DelIfValue(ctx context.Context, key, value string) (bool, error) {
+//		n, err := delIfValue.Run(ctx, c.Client, []string{key}, value).Int()
+//		return n > 0, err
+//	}
+//
+//	func (c goRedisClient) Exists(ctx context.Context, key string) (bool, error) {
+//		n, err := c.Client.Exists(ctx, key).Result()
+//		return n > 0, err
+//	}
+package redisstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DelIfValueScript is the Lua script deleting the key KEYS[1] only if its value is ARGV[1], for implementing
+// Client.DelIfValue with EVAL. Returns the number of the deleted keys.
+const DelIfValueScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// Client is the subset of Redis commands used by the store.
+type Client interface {
+	// SetNX sets the key if it does not exist. Returns true if the key was set.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// DelIfValue atomically deletes the key if its value equals value, e.g. with DelIfValueScript.
+	// Returns true if the key was deleted.
+	DelIfValue(ctx context.Context, key, value string) (bool, error)
+	// Exists returns true if the key exists.
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// Store is a set of pending task identifiers stored in Redis. The value of each key is the owner token
+// of the store, so the store removes only the identifiers it has added. Each pool needs its own store.
+// If Redis is unavailable, the store fails open: identifiers are considered unique, so tasks are not lost,
+// but may be executed by several replicas.
+type Store[T comparable] struct {
+	client Client
+	// The random value of the keys added by this store.
+	owner string
+	// Prefix of the Redis keys.
+	prefix string
+	// TTL of the Redis keys, protects against keys left by crashed replicas.
+	ttl time.Duration
+	// Timeout of a single Redis command.
+	timeout time.Duration
+	// Converts the task identifier to a part of the Redis key.
+	keyFunc func(T) string
+	// Called on Redis errors.
+	errorHandler func(error)
+
+	// The number of identifiers added by this store and not removed yet.
+	// The identifiers expired by the TTL stay counted.
+	count int64
+}
+
+// Option configures a Store.
+type Option[T comparable] func(*Store[T])
+
+// WithKeyFunc sets the function converting the task identifier to a part of the Redis key.
+// By default fmt.Sprint is used.
+func WithKeyFunc[T comparable](keyFunc func(T) string) Option[T] {
+	return func(s *Store[T]) {
+		s.keyFunc = keyFunc
+	}
+}
+
+// WithTimeout sets the timeout of a single Redis command. The default is one second.
+func WithTimeout[T comparable](timeout time.Duration) Option[T] {
+	return func(s *Store[T]) {
+		s.timeout = timeout
+	}
+}
+
+// WithErrorHandler sets the function called on Redis errors.
+func WithErrorHandler[T comparable](errorHandler func(error)) Option[T] {
+	return func(s *Store[T]) {
+		s.errorHandler = errorHandler
+	}
+}
+
+// New creates a new Store. Keys are stored as prefix + identifier with the given TTL, which should be
+// greater than the time a task can stay pending.
+func New[T comparable](client Client, prefix string, ttl time.Duration, opts ...Option[T]) *Store[T] {
+	if client == nil || ttl <= 0 {
+		panic("invalid parameters")
+	}
+
+	s := &Store[T]{
+		client:  client,
+		owner:   newOwner(),
+		prefix:  prefix,
+		ttl:     ttl,
+		timeout: time.Second,
+		keyFunc: func(id T) string { return fmt.Sprint(id) },
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Add adds the identifier to the set. Returns false if the identifier is already in the set.
+func (s *Store[T]) Add(id T) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	ok, err := s.client.SetNX(ctx, s.key(id), s.owner, s.ttl)
+	if err != nil {
+		s.handleError(err)
+		// fail open: the pool will remove the identifier as if it was added, which does nothing
+		return true
+	}
+
+	if ok {
+		atomic.AddInt64(&s.count, 1)
+	}
+
+	return ok
+}
+
+// Remove removes the identifier from the set if it was added by this store. The identifier added by another
+// replica is kept.
+func (s *Store[T]) Remove(id T) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	deleted, err := s.client.DelIfValue(ctx, s.key(id), s.owner)
+	if err != nil {
+		s.handleError(err)
+		return
+	}
+
+	if deleted {
+		atomic.AddInt64(&s.count, -1)
+	}
+}
+
+// Contains returns true if the identifier is in the set.
+func (s *Store[T]) Contains(id T) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	ok, err := s.client.Exists(ctx, s.key(id))
+	if err != nil {
+		s.handleError(err)
+		return false
+	}
+
+	return ok
+}
+
+// Len returns the number of identifiers added by this store and not removed yet.
+// Identifiers added by other replicas are not counted.
+func (s *Store[T]) Len() int {
+	return int(atomic.LoadInt64(&s.count))
+}
+
+func (s *Store[T]) key(id T) string {
+	return s.prefix + s.keyFunc(id)
+}
+
+func (s *Store[T]) handleError(err error) {
+	if s.errorHandler != nil {
+		s.errorHandler(err)
+	}
+}
+
+// newOwner returns a random owner token.
+func newOwner() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("redisstore: cannot generate the owner token: %v", err))
+	}
+
+	return hex.EncodeToString(b)
+}
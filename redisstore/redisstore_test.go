@@ -0,0 +1,121 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n-r-w/uniqpool"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient is an in-memory Client.
+type fakeClient struct {
+	mu   sync.Mutex
+	keys map[string]string
+	err  error
+}
+
+func (c *fakeClient) SetNX(_ context.Context, key, value string, _ time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return false, c.err
+	}
+	if _, ok := c.keys[key]; ok {
+		return false, nil
+	}
+	c.keys[key] = value
+	return true, nil
+}
+
+func (c *fakeClient) DelIfValue(_ context.Context, key, value string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return false, c.err
+	}
+	if v, ok := c.keys[key]; !ok || v != value {
+		return false, nil
+	}
+	delete(c.keys, key)
+	return true, nil
+}
+
+func (c *fakeClient) Exists(_ context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.keys[key]
+	return ok, c.err
+}
+
+// TestSharedStore checks that a task pending in one pool suppresses its duplicates in another pool.
+func TestSharedStore(t *testing.T) {
+	client := &fakeClient{keys: make(map[string]string)}
+
+	replica1 := uniqpool.MustNew(10, 2, 10, time.Millisecond*100,
+		uniqpool.WithUniqStore[int](New[int](client, "pool:", time.Minute)))
+	replica2 := uniqpool.MustNew(10, 2, 10, time.Millisecond*100,
+		uniqpool.WithUniqStore[int](New[int](client, "pool:", time.Minute)))
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	replica1.Submit(1, fn)
+	replica2.Submit(1, fn)
+	replica2.Submit(2, fn)
+
+	require.Contains(t, client.keys, "pool:1")
+	require.Contains(t, client.keys, "pool:2")
+
+	replica1.StopAndWait()
+	replica2.StopAndWait()
+
+	require.Equal(t, int32(2), processed)
+	require.Empty(t, client.keys)
+	require.Equal(t, uint64(1), replica2.Stats().Coalesced)
+}
+
+// TestStoreFailOpen checks that Redis errors do not block the tasks.
+func TestStoreFailOpen(t *testing.T) {
+	var errorsCount int32
+	client := &fakeClient{keys: make(map[string]string), err: errors.New("connection refused")}
+	store := New(client, "pool:", time.Minute, WithErrorHandler[string](func(error) {
+		atomic.AddInt32(&errorsCount, 1)
+	}))
+
+	require.True(t, store.Add("task1"))
+	require.True(t, store.Add("task1"))
+	require.False(t, store.Contains("task1"))
+	require.Equal(t, int32(3), errorsCount)
+}
+
+// TestStoreOwner checks that a store removes only the identifiers it has added and counts only the removed ones.
+func TestStoreOwner(t *testing.T) {
+	client := &fakeClient{keys: make(map[string]string)}
+	store1 := New[string](client, "pool:", time.Minute)
+	store2 := New[string](client, "pool:", time.Minute)
+
+	require.True(t, store1.Add("task1"))
+	require.False(t, store2.Add("task1"))
+	store2.Remove("task1")
+	require.True(t, store2.Contains("task1"))
+	require.Equal(t, 1, store1.Len())
+	require.Zero(t, store2.Len())
+
+	store1.Remove("task1")
+	store1.Remove("task1")
+	require.False(t, store1.Contains("task1"))
+	require.Zero(t, store1.Len())
+
+	client.err = errors.New("connection refused")
+	require.True(t, store1.Add("task2"))
+	store1.Remove("task2")
+	require.Zero(t, store1.Len())
+}
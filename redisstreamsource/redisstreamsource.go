@@ -0,0 +1,97 @@
+// Package redisstreamsource adapts a Redis stream consumer group to uniqpool.Source.
+//
+// The package does not depend on a particular Redis driver. The Client interface is satisfied by a few lines
+// of glue code, e.g. for github.com/redis/go-redis:
+//
+//	type goRedisClient struct{ *redis.Client }
+//
+//	func (c goRedisClient) ReadGroup(ctx context.Context, stream, group, consumer string, count int64,
+//	) ([]redisstreamsource.Message, error) {
+//		streams, err := c.XReadGroup(ctx, &redis.XReadGroupArgs{
+//			Group: group, Consumer: consumer, Streams: []string{stream, ">"}, Count: count,
+//		}).Result()
+//		if err != nil {
+//			return nil, err
+//		}
+//		var msgs []redisstreamsource.Message
+//		for _, s := range streams {
+//			for _, m := range s.Messages {
+//				msgs = append(msgs, redisstreamsource.Message{ID: m.ID, Values: m.Values})
+//			}
+//		}
+//		return msgs, nil
+//	}
+//
+//	func (c goRedisClient) Ack(ctx context.Context, stream, group string, ids ...string) error {
+//		return c.XAck(ctx, stream, group, ids...).Err()
+//	}
+package redisstreamsource
+
+import "context"
+
+// Message is a message of a Redis stream.
+type Message struct {
+	// The stream entry ID.
+	ID string
+	// The fields of the entry.
+	Values map[string]any
+}
+
+// Client is the subset of Redis stream commands used by the source.
+type Client interface {
+	// ReadGroup reads up to count new messages of the stream for the consumer of the group (XREADGROUP).
+	// Blocks until at least one message is available.
+	ReadGroup(ctx context.Context, stream, group, consumer string, count int64) ([]Message, error)
+	// Ack acknowledges the messages (XACK).
+	Ack(ctx context.Context, stream, group string, ids ...string) error
+}
+
+// Source is a uniqpool.Source reading messages from a Redis stream consumer group.
+// Source is not safe for concurrent Receive calls.
+type Source struct {
+	client   Client
+	stream   string
+	group    string
+	consumer string
+	// The number of messages read at once.
+	batchSize int64
+
+	// Messages read but not returned by Receive yet.
+	buffer []Message
+}
+
+// New creates a new Source. batchSize is the number of messages read from Redis at once.
+func New(client Client, stream, group, consumer string, batchSize int64) *Source {
+	if client == nil || batchSize <= 0 {
+		panic("invalid parameters")
+	}
+
+	return &Source{
+		client:    client,
+		stream:    stream,
+		group:     group,
+		consumer:  consumer,
+		batchSize: batchSize,
+	}
+}
+
+// Receive blocks until the next message is available.
+func (s *Source) Receive(ctx context.Context) (Message, error) {
+	for len(s.buffer) == 0 {
+		msgs, err := s.client.ReadGroup(ctx, s.stream, s.group, s.consumer, s.batchSize)
+		if err != nil {
+			return Message{}, err
+		}
+		s.buffer = msgs
+	}
+
+	msg := s.buffer[0]
+	s.buffer = s.buffer[1:]
+
+	return msg, nil
+}
+
+// Ack acknowledges the message.
+func (s *Source) Ack(ctx context.Context, msg Message) error {
+	return s.client.Ack(ctx, s.stream, s.group, msg.ID)
+}
@@ -0,0 +1,46 @@
+package redisstreamsource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	batches [][]Message
+	acked   []string
+}
+
+func (c *fakeClient) ReadGroup(context.Context, string, string, string, int64) ([]Message, error) {
+	if len(c.batches) == 0 {
+		return nil, context.Canceled
+	}
+	batch := c.batches[0]
+	c.batches = c.batches[1:]
+	return batch, nil
+}
+
+func (c *fakeClient) Ack(_ context.Context, _, _ string, ids ...string) error {
+	c.acked = append(c.acked, ids...)
+	return nil
+}
+
+// TestSource checks that the messages are returned one by one and acknowledged by ID.
+func TestSource(t *testing.T) {
+	client := &fakeClient{batches: [][]Message{{{ID: "1-0"}, {ID: "2-0"}}, {}, {{ID: "3-0"}}}}
+	src := New(client, "stream", "group", "consumer", 10)
+
+	var ids []string
+	for {
+		msg, err := src.Receive(context.Background())
+		if err != nil {
+			break
+		}
+		ids = append(ids, msg.ID)
+		require.NoError(t, src.Ack(context.Background(), msg))
+	}
+
+	require.Equal(t, []string{"1-0", "2-0", "3-0"}, ids)
+	require.Equal(t, ids, client.acked)
+}
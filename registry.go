@@ -0,0 +1,69 @@
+package uniqpool
+
+import (
+	"sort"
+	"sync"
+)
+
+// Pool is the part of the pool API that does not depend on the type of the task identifier.
+// It is implemented by UniqPool and PoolSet.
+type Pool interface {
+	// Stats returns the statistics of the pool.
+	Stats() Stats
+	// Stopped returns true if the pool is stopped.
+	Stopped() bool
+}
+
+var registry = struct {
+	sync.RWMutex
+	pools map[string]Pool
+}{pools: make(map[string]Pool)}
+
+// Register adds the pool to the process-wide registry under the given name, so operational tooling can
+// enumerate all pools and collect their statistics. Panics if the name is already registered.
+func Register(name string, pool Pool) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.pools[name]; ok {
+		panic("pool " + name + " is already registered")
+	}
+	registry.pools[name] = pool
+}
+
+// Unregister removes the pool from the registry.
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	delete(registry.pools, name)
+}
+
+// Lookup returns the registered pool by name.
+func Lookup(name string) (Pool, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	pool, ok := registry.pools[name]
+	return pool, ok
+}
+
+// Each calls fn for each registered pool in the order of names.
+func Each(fn func(name string, pool Pool)) {
+	registry.RLock()
+	names := make([]string, 0, len(registry.pools))
+	for name := range registry.pools {
+		names = append(names, name)
+	}
+	pools := make([]Pool, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		pools = append(pools, registry.pools[name])
+	}
+	registry.RUnlock()
+
+	// fn is called without the lock, so it can use the registry
+	for i, name := range names {
+		fn(name, pools[i])
+	}
+}
@@ -0,0 +1,48 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegistry checks registration, lookup and enumeration of the pools.
+func TestRegistry(t *testing.T) {
+	pool1 := MustNew[string](10, 2, 10, time.Millisecond*10)
+	pool2 := MustNew[int](10, 2, 10, time.Millisecond*10)
+	set := NewPoolSet[string](10, 2, 10, time.Millisecond*10, time.Second)
+
+	Register("pool1", pool1)
+	Register("pool2", pool2)
+	Register("set", set)
+	defer func() {
+		Unregister("pool1")
+		Unregister("pool2")
+		Unregister("set")
+	}()
+
+	require.Panics(t, func() { Register("pool1", pool2) })
+
+	p, ok := Lookup("pool2")
+	require.True(t, ok)
+	require.Same(t, pool2, p)
+	_, ok = Lookup("unknown")
+	require.False(t, ok)
+
+	pool1.Submit("task1", func() {})
+	pool2.Submit(1, func() {})
+	set.Submit("tenant1", "task1", func() {})
+
+	pool1.StopAndWait()
+	pool2.StopAndWait()
+	set.StopAndWait()
+
+	var names []string
+	Each(func(name string, pool Pool) {
+		names = append(names, name)
+		require.True(t, pool.Stopped())
+		require.Equal(t, uint64(1), pool.Stats().Dispatched)
+	})
+	require.Equal(t, []string{"pool1", "pool2", "set"}, names)
+}
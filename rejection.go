@@ -0,0 +1,123 @@
+package uniqpool
+
+import "sync/atomic"
+
+// RejectionAction is what happens to a task that does not fit into the full inbound queue.
+type RejectionAction int
+
+const (
+	// Block waits for a free slot in the inbound queue.
+	Block RejectionAction = iota
+	// Reject drops the task, it is reported as Rejected.
+	Reject
+	// DropOldest drops the oldest pending task to make space for the new one. The result waiters of the dropped
+	// task receive ErrQueueFull. Then the task waits for the space like with Block.
+	DropOldest
+	// CallerRuns executes the task in the goroutine of the submitter if the worker pool is saturated too,
+	// otherwise the task waits for a free slot like with Block. A panic of the task is propagated to the caller.
+	CallerRuns
+)
+
+// RejectionPolicy decides what happens to a task submitted by a blocking call, e.g. Submit or SubmitEx,
+// when the inbound queue is full. Non-blocking calls such as TrySubmit always reject the task, and a task
+// that exceeds the namespace quota always waits. The policy is called after the dedup, under the lock
+// of the dedup state, so it must be fast and must not call the pool.
+type RejectionPolicy[T comparable] interface {
+	// Decide returns the action for the task with the identifier. saturated is true if all the workers
+	// are busy and the queue of the worker pool is full.
+	Decide(id T, saturated bool) RejectionAction
+}
+
+// RejectionPolicyFunc is an adapter to use a function as a RejectionPolicy.
+type RejectionPolicyFunc[T comparable] func(id T, saturated bool) RejectionAction
+
+// Decide calls f(id, saturated).
+func (f RejectionPolicyFunc[T]) Decide(id T, saturated bool) RejectionAction {
+	return f(id, saturated)
+}
+
+// BlockPolicy returns the policy that waits for a free slot. This is the default behavior.
+func BlockPolicy[T comparable]() RejectionPolicy[T] {
+	return actionPolicy[T](Block)
+}
+
+// RejectPolicy returns the policy that drops the new task.
+func RejectPolicy[T comparable]() RejectionPolicy[T] {
+	return actionPolicy[T](Reject)
+}
+
+// DropOldestPolicy returns the policy that drops the oldest pending task to make space for the new one.
+func DropOldestPolicy[T comparable]() RejectionPolicy[T] {
+	return actionPolicy[T](DropOldest)
+}
+
+// CallerRunsPolicy returns the policy that executes the task in the goroutine of the submitter
+// when the worker pool is saturated too.
+func CallerRunsPolicy[T comparable]() RejectionPolicy[T] {
+	return actionPolicy[T](CallerRuns)
+}
+
+// actionPolicy is the policy that always returns the same action.
+type actionPolicy[T comparable] RejectionAction
+
+func (a actionPolicy[T]) Decide(T, bool) RejectionAction {
+	return RejectionAction(a)
+}
+
+// requestEviction asks the dispatcher to drop the oldest pending task (see DropOldest).
+func (p *UniqPool[T]) requestEviction() {
+	atomic.AddInt32(&p.evictions, 1)
+
+	select {
+	case p.evictChan <- struct{}{}:
+	default:
+	}
+}
+
+// overPendingKeys returns true if the accepted task makes the number of the pending tasks exceed maxPendingKeys,
+// taking into account the evictions that are already requested. queueSlot is true if the slot of the task
+// is reserved, so it is counted in the inbound queue.
+func (p *UniqPool[T]) overPendingKeys(queueSlot bool) bool {
+	pending := p.inboundQueue.len() - int(atomic.LoadInt32(&p.evictions))
+	if !queueSlot {
+		pending++
+	}
+
+	return pending > p.maxPendingKeys
+}
+
+// evict drops the oldest pending tasks requested by the submitters. Must be called only by the processTasks goroutine.
+func (p *UniqPool[T]) evict() {
+	for n := atomic.SwapInt32(&p.evictions, 0); n > 0; n-- {
+		t, ok := p.popOldest()
+		if !ok {
+			break
+		}
+
+		p.freeSlots(t)
+		atomic.AddUint64(&p.counters.rejected, 1)
+		p.drop(t, ErrQueueFull)
+	}
+
+	p.inboundQueue.notifySpace()
+}
+
+// popOldest removes the oldest pending task and frees its slot. Returns false if there are no pending tasks.
+// Must be called only by the processTasks goroutine.
+func (p *UniqPool[T]) popOldest() (task[T], bool) {
+	if p.priorityQueue == nil {
+		if p.carried.len() > 0 {
+			t := p.takeCarried(nil, 1)[0]
+			return t, true
+		}
+		return p.inboundQueue.pop()
+	}
+
+	p.holdQueued()
+	t, ok := p.priorityQueue.removeOldest()
+	if ok {
+		p.inboundQueue.release(1)
+	}
+
+	return t, ok
+}
@@ -0,0 +1,69 @@
+package uniqpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRejectionPolicy checks the reject, drop-oldest and custom rejection policies.
+func TestRejectionPolicy(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(2, 1, 10, time.Hour, WithClock[string](clock), WithRejectionPolicy(RejectPolicy[string]()))
+	pool.Submit("task1", func() {})
+	pool.Submit("task2", func() {})
+	outcome, err := pool.SubmitEx("task3", func() {})
+	require.NoError(t, err)
+	require.Equal(t, Rejected, outcome)
+	pool.StopAndWait()
+
+	pool = MustNew(2, 1, 10, time.Hour, WithClock[string](clock), WithRejectionPolicy(DropOldestPolicy[string]()))
+	dropped := make(chan error, 1)
+	pool.SubmitWithResult("task1", func() (any, error) { return nil, nil }, func(_ any, err error) { dropped <- err })
+	executed := make(chan string, 3)
+	pool.Submit("task2", func() { executed <- "task2" })
+	pool.Submit("task3", func() { executed <- "task3" })
+	require.True(t, errors.Is(<-dropped, ErrQueueFull))
+	require.Equal(t, uint64(1), pool.Stats().Rejected)
+	pool.StopAndWait()
+	require.Equal(t, []string{"task2", "task3"}, []string{<-executed, <-executed})
+
+	var decided []string
+	pool = MustNew(1, 1, 10, time.Hour, WithClock[string](clock),
+		WithRejectionPolicy[string](RejectionPolicyFunc[string](func(id string, saturated bool) RejectionAction {
+			decided = append(decided, id)
+			require.False(t, saturated)
+			return Reject
+		})))
+	pool.Submit("task1", func() {})
+	pool.Submit("task2", func() {})
+	pool.StopAndWait()
+	require.Equal(t, []string{"task2"}, decided)
+}
+
+// TestMaxPendingKeys checks that the oldest pending task is dropped when the number of the pending tasks exceeds the cap.
+func TestMaxPendingKeys(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[string](clock), WithUnboundedQueue[string](0, nil),
+		WithMaxPendingKeys[string](2))
+
+	dropped := make(chan error, 1)
+	executed := make(chan string, 3)
+	pool.Submit("task1", func() { executed <- "task1" }, WithOnDone(func(err error, _ time.Duration) { dropped <- err }))
+	pool.Submit("task2", func() { executed <- "task2" })
+	pool.Submit("task3", func() { executed <- "task3" })
+
+	require.ErrorIs(t, <-dropped, ErrQueueFull)
+	require.Equal(t, 2, pool.Stats().Pending)
+
+	clock.tickChan <- time.Now()
+	pool.StopAndWait()
+	close(executed)
+	var got []string
+	for id := range executed {
+		got = append(got, id)
+	}
+	require.Equal(t, []string{"task2", "task3"}, got)
+}
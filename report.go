@@ -0,0 +1,61 @@
+package uniqpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ShutdownReport is the end-of-run accounting of the pool returned by StopAndReport.
+// The counters are accumulated since the pool was created or the statistics were reset by ResetStats.
+type ShutdownReport[T comparable] struct {
+	// The number of executed tasks.
+	Executed uint64
+	// The number of tasks coalesced with the pending tasks with the same identifier.
+	Coalesced uint64
+	// The number of tasks dropped because of the suppression window or the quarantine,
+	// or rejected because the inbound queue, the namespace quota or the memory budget was full.
+	Dropped uint64
+	// The identifiers of the tasks that were still pending when the deadline expired and were discarded.
+	PendingAtDeadline []T
+	// The time since the pool was created or restarted until it stopped.
+	Runtime time.Duration
+}
+
+// StopAndReport stops the pool like StopAndWait and returns the summary of the run, useful for batch jobs.
+// If ctx is done before the backlog is finished, the rest of it is discarded like by StopNow
+// and reported as pending at the deadline.
+func (p *UniqPool[T]) StopAndReport(ctx context.Context) ShutdownReport[T] {
+	stopped := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-ctx.Done():
+			p.abort()
+		case <-stopped:
+		}
+	}()
+
+	p.shutdown()
+	close(stopped)
+	<-watched
+
+	p.discardedMutex.Lock()
+	discarded := append([]T(nil), p.discarded...)
+	p.discardedMutex.Unlock()
+
+	stats := p.Stats()
+	executed := atomic.LoadUint64(&p.counters.executed)
+	if base := p.statsBase.Load(); base != nil {
+		executed -= base.counters.executed
+	}
+
+	return ShutdownReport[T]{
+		Executed:          executed,
+		Coalesced:         stats.Coalesced,
+		Dropped:           stats.Suppressed + stats.Rejected,
+		PendingAtDeadline: discarded,
+		Runtime:           p.clock.Now().Sub(p.startedAt),
+	}
+}
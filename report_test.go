@@ -0,0 +1,36 @@
+package uniqpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStopAndReport checks the summary of the run, including the tasks discarded at the deadline.
+func TestStopAndReport(t *testing.T) {
+	pool := MustNew[int](10, 1, 10, time.Hour)
+	pool.Submit(1, func() {})
+	pool.Submit(1, func() {})
+	pool.Submit(2, func() {})
+
+	report := pool.StopAndReport(context.Background())
+	require.Equal(t, uint64(2), report.Executed)
+	require.Equal(t, uint64(1), report.Coalesced)
+	require.Empty(t, report.PendingAtDeadline)
+	require.Positive(t, report.Runtime)
+
+	require.NoError(t, pool.Start())
+	block := make(chan struct{})
+	pool.Submit(3, func() { <-block })
+	pool.Submit(4, func() {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	time.AfterFunc(time.Millisecond*100, func() { close(block) })
+
+	report = pool.StopAndReport(ctx)
+	require.Equal(t, uint64(3), report.Executed)
+	require.Equal(t, []int{4}, report.PendingAtDeadline)
+}
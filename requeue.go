@@ -0,0 +1,88 @@
+package uniqpool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// requeuer resubmits the failed tasks added by SubmitRetryable after a per-identifier exponential backoff.
+type requeuer[T comparable] struct {
+	// The backoff of the first requeue.
+	baseDelay time.Duration
+	// The maximum backoff.
+	maxDelay time.Duration
+	// Returns true if the failed task must not be requeued. Nil if the tasks are requeued until they succeed.
+	stop func(id T, err error, requeues int) bool
+
+	// The number of requeues by identifier.
+	requeues map[T]int
+	mutex    sync.Mutex
+}
+
+// SubmitRetryable adds a task that can fail. Will block if the inbound queue is full. If requeuing is enabled
+// (see WithRequeue) and the task returns an error or panics, it is submitted again after a backoff, so it is
+// coalesced with a newer submission of the identifier, if any. The context carries the number of the attempt
+// (see AttemptFromContext) and is cancelled by StopNow.
+func (p *UniqPool[T]) SubmitRetryable(id T, fn func(ctx context.Context) error) {
+	mustSubmit(p.submit(task[T]{id: id, errFn: fn}, true))
+}
+
+type attemptKey struct{}
+
+// AttemptFromContext returns the number of the attempt to execute the task from the context passed to the task
+// added by SubmitRetryable, starting with 1. The attempts are counted per identifier since its last success,
+// so the task can change its behavior on later attempts, e.g. switch to a fallback path.
+func AttemptFromContext(ctx context.Context) (int, bool) {
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+	return attempt, ok
+}
+
+// withAttempt returns the context carrying the number of the attempt to execute the task.
+func (p *UniqPool[T]) withAttempt(ctx context.Context, id T) context.Context {
+	attempt := 1
+	if p.requeue != nil {
+		p.requeue.mutex.Lock()
+		attempt += p.requeue.requeues[id]
+		p.requeue.mutex.Unlock()
+	}
+
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// completed resets the backoff of the succeeded task or schedules the requeue of the failed one.
+func (r *requeuer[T]) completed(p *UniqPool[T], t task[T], err error) {
+	r.mutex.Lock()
+	requeues := r.requeues[t.id]
+	r.mutex.Unlock()
+
+	// stop is called without the mutex, so a slow hook does not block the other completions and attempts
+	if err == nil || (p.quarantine != nil && p.quarantine.contains(t.id)) ||
+		(r.stop != nil && r.stop(t.id, err, requeues)) {
+		r.mutex.Lock()
+		delete(r.requeues, t.id)
+		r.mutex.Unlock()
+		return
+	}
+
+	r.mutex.Lock()
+	r.requeues[t.id] = requeues + 1
+	r.mutex.Unlock()
+
+	// the requeued task is dropped if the pool is stopped by then
+	retry := task[T]{id: t.id, errFn: t.errFn}
+	p.clock.AfterFunc(backoff(r.baseDelay, r.maxDelay, requeues), func() { p.submit(retry, true) })
+}
+
+// backoff returns the exponential backoff of the requeue with the given number.
+func backoff(baseDelay, maxDelay time.Duration, requeues int) time.Duration {
+	delay := baseDelay
+	for i := 0; i < requeues && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,82 @@
+package uniqpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequeue checks that a failed task is requeued until it succeeds or the stop hook gives up.
+func TestRequeue(t *testing.T) {
+	errFailed := errors.New("failed")
+	pool := MustNew(10, 2, 10, time.Millisecond,
+		WithRequeue(time.Millisecond, time.Millisecond*5, func(id string, err error, requeues int) bool {
+			return id == "hopeless" && requeues == 2
+		}))
+
+	var attempts int32
+	pool.SubmitRetryable("flaky", func(ctx context.Context) error {
+		attempt, ok := AttemptFromContext(ctx)
+		require.True(t, ok)
+		require.Equal(t, int(atomic.AddInt32(&attempts, 1)), attempt)
+		if attempt < 3 {
+			return errFailed
+		}
+		return nil
+	})
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&attempts) == 3 }, time.Second, time.Millisecond)
+
+	var hopeless int32
+	pool.SubmitRetryable("hopeless", func(context.Context) error {
+		atomic.AddInt32(&hopeless, 1)
+		return errFailed
+	})
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&hopeless) == 3 }, time.Second, time.Millisecond)
+
+	time.Sleep(time.Millisecond * 50)
+	pool.StopAndWait()
+	require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	require.Equal(t, int32(3), atomic.LoadInt32(&hopeless))
+	require.Empty(t, pool.requeue.requeues)
+}
+
+// TestRequeueInvalidDelays checks that New returns an error with invalid delays.
+func TestRequeueInvalidDelays(t *testing.T) {
+	for _, delays := range [][2]time.Duration{{0, time.Second}, {-time.Second, time.Second}, {time.Second, time.Millisecond}} {
+		_, err := New(10, 2, 10, time.Millisecond, WithRequeue[string](delays[0], delays[1], nil))
+		require.ErrorIs(t, err, ErrInvalidParameters, delays)
+	}
+}
+
+// TestRequeueSlowStop checks that the stop hook does not block the attempts of the other tasks.
+func TestRequeueSlowStop(t *testing.T) {
+	gate := make(chan struct{})
+	stopping := make(chan struct{})
+	pool := MustNew(10, 2, 10, time.Millisecond,
+		WithRequeue(time.Millisecond, time.Millisecond, func(string, error, int) bool {
+			close(stopping)
+			<-gate
+			return true
+		}))
+
+	pool.SubmitRetryable("failed", func(context.Context) error { return errors.New("failed") })
+	<-stopping
+
+	done := make(chan struct{})
+	pool.SubmitRetryable("other", func(context.Context) error {
+		close(done)
+		return nil
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the attempt is blocked by the stop hook")
+	}
+
+	close(gate)
+	pool.StopAndWait()
+}
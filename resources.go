@@ -0,0 +1,68 @@
+package uniqpool
+
+import (
+	"math"
+	"runtime/metrics"
+	"sync/atomic"
+)
+
+const (
+	// resourceSlowdown is the fraction of a resource limit from which the dispatching is slowed down.
+	resourceSlowdown = 0.8
+
+	metricHeapBytes  = "/memory/classes/heap/objects:bytes"
+	metricGoroutines = "/sched/goroutines:goroutines"
+)
+
+// resourceLimits slows down and pauses the dispatching when the process uses too much memory or runs
+// too many goroutines (see WithResourceLimits).
+type resourceLimits struct {
+	// The limits of the resources. Zero if the resource is not limited.
+	maxHeapBytes  uint64
+	maxGoroutines uint64
+
+	// Used only by the processTasks goroutine.
+	samples []metrics.Sample
+	// 1 if the last check slowed down or paused the dispatching. Updated atomically.
+	throttled int32
+}
+
+func newResourceLimits(maxHeapBytes uint64, maxGoroutines int) *resourceLimits {
+	return &resourceLimits{
+		maxHeapBytes:  maxHeapBytes,
+		maxGoroutines: uint64(maxGoroutines),
+		samples:       []metrics.Sample{{Name: metricHeapBytes}, {Name: metricGoroutines}},
+	}
+}
+
+// allow returns the number of tasks that can be dispatched now, up to limit: all of them below resourceSlowdown
+// of every limit, proportionally fewer above it and none once a limit is reached.
+// Must be called only by the processTasks goroutine.
+func (r *resourceLimits) allow(limit int) int {
+	metrics.Read(r.samples)
+
+	usage := 0.0
+	if r.maxHeapBytes > 0 && r.samples[0].Value.Kind() == metrics.KindUint64 {
+		usage = math.Max(usage, float64(r.samples[0].Value.Uint64())/float64(r.maxHeapBytes))
+	}
+	if r.maxGoroutines > 0 && r.samples[1].Value.Kind() == metrics.KindUint64 {
+		usage = math.Max(usage, float64(r.samples[1].Value.Uint64())/float64(r.maxGoroutines))
+	}
+
+	switch {
+	case usage < resourceSlowdown:
+		atomic.StoreInt32(&r.throttled, 0)
+		return limit
+	case usage >= 1:
+		atomic.StoreInt32(&r.throttled, 1)
+		return 0
+	default:
+		atomic.StoreInt32(&r.throttled, 1)
+		return int(math.Ceil(float64(limit) * (1 - usage) / (1 - resourceSlowdown)))
+	}
+}
+
+// isThrottled returns true if the last check slowed down or paused the dispatching.
+func (r *resourceLimits) isThrottled() bool {
+	return atomic.LoadInt32(&r.throttled) == 1
+}
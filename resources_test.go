@@ -0,0 +1,30 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestResourceLimits checks that the dispatching is paused while a resource limit is exceeded
+// and the backlog is dispatched on stop.
+func TestResourceLimits(t *testing.T) {
+	r := newResourceLimits(0, 1000000)
+	require.Equal(t, 10, r.allow(10))
+	require.False(t, r.isThrottled())
+
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock), WithResourceLimits[string](0, 1))
+
+	var executed int32
+	pool.Submit("task1", func() { atomic.StoreInt32(&executed, 1) })
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return pool.Stats().ResourceThrottled }, time.Second, time.Millisecond)
+	require.Zero(t, atomic.LoadInt32(&executed))
+	require.Equal(t, 1, pool.Stats().Pending)
+
+	pool.StopAndWait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&executed))
+}
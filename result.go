@@ -0,0 +1,135 @@
+package uniqpool
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTaskPanicked is passed to the result callbacks if the task panicked.
+var ErrTaskPanicked = errors.New("task panicked")
+
+// SubmitWithResult adds a task that produces a result to the pool. Will block if the inbound queue is full.
+// onResult is called with the result of the task after it is executed. If a task with the same identifier
+// is already pending, onResult is attached to it, so all the coalesced submitters receive the result
+// of a single execution. If the task was coalesced with a task added by Submit or TrySubmit, onResult
+// receives a nil value and error.
+// If the result cache is enabled (see WithResultCache) and contains a fresh result for the identifier,
+// onResult is called immediately in the caller's goroutine and the task is not executed.
+// Otherwise onResult is called in the worker goroutine.
+func (p *UniqPool[T]) SubmitWithResult(id T, fn func() (any, error), onResult func(value any, err error)) {
+	p.SubmitWithResultContext(id,
+		func(context.Context) (any, error) {
+			return fn()
+		},
+		func(_ ExecutionID, value any, err error) {
+			onResult(value, err)
+		})
+}
+
+// SubmitWithResultContext is SubmitWithResult for a task that receives a context. The context carries the ID
+// of the execution (see ExecutionIDFromContext), which is also passed to onResult of all the coalesced submitters,
+// so they can be correlated with a single execution. The ID is zero if the task was not executed: the result
+// is taken from the cache, the task was suppressed, rejected or discarded by StopNow. onResult of the rejected
+// task, e.g. by a namespace quota or load shedding, receives ErrQueueFull.
+// The context is cancelled by StopNow.
+func (p *UniqPool[T]) SubmitWithResultContext(id T, fn func(ctx context.Context) (any, error),
+	onResult func(exec ExecutionID, value any, err error),
+) {
+	id = p.normalizeKey(id)
+	if value, ok := p.cachedResult(id); ok && !p.Stopped() {
+		onResult(0, value, nil)
+		return
+	}
+
+	mustSubmit(p.submitWaiter(task[T]{id: id, resultFn: fn}, true, onResult, nil))
+}
+
+// cachedResult returns the cached result of the task.
+func (p *UniqPool[T]) cachedResult(id T) (any, bool) {
+	if p.resultCache == nil {
+		return nil, false
+	}
+
+	p.resultCache.mutex.Lock()
+	defer p.resultCache.mutex.Unlock()
+
+	return p.resultCache.get(id, p.clock.Now())
+}
+
+// cacheResult saves the result of the task to the cache.
+func (p *UniqPool[T]) cacheResult(id T, value any) {
+	if p.resultCache == nil {
+		return
+	}
+
+	p.resultCache.mutex.Lock()
+	p.resultCache.put(id, value, p.clock.Now())
+	p.resultCache.mutex.Unlock()
+}
+
+// resultCache is a LRU cache of task results with a TTL.
+type resultCache[T comparable] struct {
+	mutex sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[T]*list.Element
+	// Entries ordered from the most recently used to the least recently used.
+	order *list.List
+}
+
+type resultCacheEntry[T comparable] struct {
+	id        T
+	value     any
+	expiresAt time.Time
+}
+
+func newResultCache[T comparable](size int, ttl time.Duration) *resultCache[T] {
+	return &resultCache[T]{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[T]*list.Element, size),
+		order: list.New(),
+	}
+}
+
+// get returns the cached value if it is not expired at now.
+func (c *resultCache[T]) get(id T, now time.Time) (any, bool) {
+	e, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*resultCacheEntry[T])
+	if !now.Before(entry.expiresAt) {
+		c.order.Remove(e)
+		delete(c.items, id)
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+// put saves the value, evicting the least recently used entry if the cache is full.
+func (c *resultCache[T]) put(id T, value any, now time.Time) {
+	expiresAt := now.Add(c.ttl)
+
+	if e, ok := c.items[id]; ok {
+		entry := e.Value.(*resultCacheEntry[T])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(e)
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*resultCacheEntry[T]).id)
+	}
+
+	c.items[id] = c.order.PushFront(&resultCacheEntry[T]{id: id, value: value, expiresAt: expiresAt})
+}
@@ -0,0 +1,158 @@
+package uniqpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubmitWithResult checks that coalesced submitters receive the result of a single execution.
+func TestSubmitWithResult(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Millisecond*10)
+
+	var (
+		executed int32
+		wg       sync.WaitGroup
+		results  = make(chan any, 3)
+	)
+
+	fn := func() (any, error) {
+		atomic.AddInt32(&executed, 1)
+		return "result", nil
+	}
+	onResult := func(value any, err error) {
+		require.NoError(t, err)
+		results <- value
+		wg.Done()
+	}
+
+	wg.Add(3)
+	pool.SubmitWithResult("task1", fn, onResult)
+	pool.SubmitWithResult("task1", fn, onResult)
+	pool.SubmitWithResult("task1", fn, onResult)
+	wg.Wait()
+
+	pool.StopAndWait()
+
+	require.Equal(t, int32(1), executed)
+	close(results)
+	for value := range results {
+		require.Equal(t, "result", value)
+	}
+	require.Empty(t, pool.stripes[0].resultWaiters)
+}
+
+// TestResultCache checks that cached results are returned without execution until they expire.
+func TestResultCache(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Millisecond*10, WithResultCache[int](2, time.Millisecond*200))
+
+	var executed int32
+	submit := func(id int) (any, error) {
+		done := make(chan struct{})
+		var (
+			value any
+			err   error
+		)
+		pool.SubmitWithResult(id, func() (any, error) {
+			atomic.AddInt32(&executed, 1)
+			if id < 0 {
+				return nil, errors.New("failed")
+			}
+			return id * 10, nil
+		}, func(v any, e error) {
+			value, err = v, e
+			close(done)
+		})
+		<-done
+		return value, err
+	}
+
+	value, err := submit(1)
+	require.NoError(t, err)
+	require.Equal(t, 10, value)
+	require.Equal(t, int32(1), atomic.LoadInt32(&executed))
+
+	// cached
+	value, err = submit(1)
+	require.NoError(t, err)
+	require.Equal(t, 10, value)
+	require.Equal(t, int32(1), atomic.LoadInt32(&executed))
+
+	// errors are not cached
+	_, err = submit(-1)
+	require.Error(t, err)
+	_, err = submit(-1)
+	require.Error(t, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&executed))
+
+	// evicts the least recently used result 1
+	_, _ = submit(2)
+	_, _ = submit(3)
+	_, _ = submit(1)
+	require.Equal(t, int32(6), atomic.LoadInt32(&executed))
+
+	// expired
+	time.Sleep(time.Millisecond * 250)
+	_, _ = submit(1)
+	require.Equal(t, int32(7), atomic.LoadInt32(&executed))
+
+	pool.StopAndWait()
+}
+
+// TestExecutionID checks that the coalesced submitters receive the ID of a single execution.
+func TestExecutionID(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Hour)
+
+	var (
+		mutex   sync.Mutex
+		fromCtx ExecutionID
+		ids     []ExecutionID
+	)
+	fn := func(ctx context.Context) (any, error) {
+		id, ok := ExecutionIDFromContext(ctx)
+		require.True(t, ok)
+		mutex.Lock()
+		fromCtx = id
+		mutex.Unlock()
+		return nil, nil
+	}
+	onResult := func(exec ExecutionID, _ any, _ error) {
+		mutex.Lock()
+		ids = append(ids, exec)
+		mutex.Unlock()
+	}
+
+	pool.SubmitWithResultContext("task1", fn, onResult)
+	pool.SubmitWithResultContext("task1", fn, onResult)
+	pool.SubmitWithResultContext("task2", fn, onResult)
+	pool.StopAndWait()
+
+	require.Len(t, ids, 3)
+	require.NotZero(t, fromCtx)
+	require.Equal(t, 2, len(map[ExecutionID]bool{ids[0]: true, ids[1]: true, ids[2]: true}))
+}
+
+// TestSubmitWithResultRejected checks that the rejected task with a result is reported with ErrQueueFull.
+func TestSubmitWithResultRejected(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(1, 2, 10, time.Hour, WithClock[string](clock), WithRejectionPolicy(RejectPolicy[string]()))
+
+	pool.Submit("task1", func() {})
+
+	var rejectedErr error
+	pool.SubmitWithResult("task2", func() (any, error) { return nil, nil }, func(_ any, err error) { rejectedErr = err })
+	require.ErrorIs(t, rejectedErr, ErrQueueFull)
+
+	done := make(chan struct{})
+	pool.SubmitWithResult("task1", func() (any, error) { return nil, nil }, func(any, error) { close(done) })
+	require.Equal(t, uint64(1), pool.Stats().Coalesced)
+
+	pool.StopAndWait()
+	<-done
+	require.Equal(t, uint64(1), pool.Stats().Rejected)
+}
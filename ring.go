@@ -0,0 +1,59 @@
+package uniqpool
+
+import "sync/atomic"
+
+// ringBuffer is a preallocated multi-producer single-consumer ring buffer of tasks with a power-of-two size.
+// It does not check for overflow: the inbound queue reserves a slot before each push, so the number of
+// the tasks pushed and not popped yet never exceeds the capacity, and the cell of a new task is always free.
+type ringBuffer[T comparable] struct {
+	cells []ringCell[T]
+	mask  uint64
+	// The position of the next pushed task. Updated by producers.
+	head uint64
+	// The position of the next popped task. Used only by the consumer.
+	tail uint64
+}
+
+type ringCell[T comparable] struct {
+	// The position of the task in the cell plus one. Zero if the cell is empty.
+	seq atomic.Uint64
+	t   task[T]
+}
+
+func newRingBuffer[T comparable](capacity int) *ringBuffer[T] {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+
+	return &ringBuffer[T]{
+		cells: make([]ringCell[T], size),
+		mask:  uint64(size - 1),
+	}
+}
+
+// push adds the task to the buffer.
+func (r *ringBuffer[T]) push(t task[T]) {
+	pos := atomic.AddUint64(&r.head, 1) - 1
+	c := &r.cells[pos&r.mask]
+	c.t = t
+	// until this store the consumer does not see the task and the following ones
+	c.seq.Store(pos + 1)
+}
+
+// pop removes the first task from the buffer. Must be called only by the consumer.
+// Returns false if the buffer is empty or the first task is not pushed completely yet.
+func (r *ringBuffer[T]) pop() (task[T], bool) {
+	c := &r.cells[r.tail&r.mask]
+	if c.seq.Load() != r.tail+1 {
+		return task[T]{}, false
+	}
+
+	t := c.t
+	// the task is cleared to not retain its closure
+	c.t = task[T]{}
+	c.seq.Store(0)
+	r.tail++
+
+	return t, true
+}
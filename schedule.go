@@ -0,0 +1,88 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// scheduledTask is a task held until its desired execution time (see SubmitAt).
+type scheduledTask[T comparable] struct {
+	t     task[T]
+	at    time.Time
+	timer Timer
+}
+
+// SubmitAt adds a task that is held until the time at, then submits it like Submit. Scheduled tasks with the same
+// identifier are coalesced: a duplicate with an earlier time moves the submission of the pending task forward
+// to the earliest requested time, while a duplicate with a later time is ignored. The scheduled task is not
+// deduplicated against the inbound queue until it is submitted. When the pool is stopped, the scheduled tasks
+// are submitted right away, so StopAndWait does not wait for their time.
+func (p *UniqPool[T]) SubmitAt(id T, at time.Time, fn func()) {
+	id = p.normalizeKey(id)
+
+	p.scheduleMutex.Lock()
+	defer p.scheduleMutex.Unlock()
+
+	if p.Stopped() {
+		panic("pool is stopped")
+	}
+
+	delay := at.Sub(p.clock.Now())
+
+	if st, ok := p.schedule[id]; ok {
+		atomic.AddUint64(&p.counters.coalesced, 1)
+		p.observe(EventCoalesced, id, nil)
+
+		if at.Before(st.at) && st.timer.Stop() {
+			st.at = at
+			st.timer.Reset(delay)
+		}
+		return
+	}
+
+	if p.schedule == nil {
+		p.schedule = make(map[T]*scheduledTask[T])
+	}
+
+	// the dispatcher does not stop until the scheduled task is submitted
+	atomic.AddInt32(&p.pendingPushes, 1)
+
+	st := &scheduledTask[T]{t: task[T]{id: id, fn: fn, followUp: true}, at: at}
+	st.timer = p.clock.AfterFunc(delay, func() { p.fireScheduled(st) })
+	p.schedule[id] = st
+}
+
+// fireScheduled submits the scheduled task when its time comes.
+func (p *UniqPool[T]) fireScheduled(st *scheduledTask[T]) {
+	p.scheduleMutex.Lock()
+	if p.schedule[st.t.id] != st {
+		p.scheduleMutex.Unlock()
+		return
+	}
+	delete(p.schedule, st.t.id)
+	p.scheduleMutex.Unlock()
+
+	p.submit(st.t, true)
+	p.pushed()
+}
+
+// flushSchedule submits all the scheduled tasks without waiting for their time. Called when the pool is stopped.
+func (p *UniqPool[T]) flushSchedule() {
+	p.scheduleMutex.Lock()
+	defer p.scheduleMutex.Unlock()
+
+	for id, st := range p.schedule {
+		if !st.timer.Stop() {
+			// is being submitted right now
+			continue
+		}
+		delete(p.schedule, id)
+
+		st := st
+		// the inbound queue may be full, so the dispatcher must not wait for the submission
+		go func() {
+			p.submit(st.t, true)
+			p.pushed()
+		}()
+	}
+}
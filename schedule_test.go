@@ -0,0 +1,29 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubmitAt checks that a scheduled task is submitted at the earliest requested time.
+func TestSubmitAt(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Millisecond)
+
+	var executed int32
+	start := time.Now()
+	pool.SubmitAt("task1", start.Add(time.Hour), func() { atomic.AddInt32(&executed, 1) })
+	pool.SubmitAt("task1", start.Add(time.Millisecond*100), func() { atomic.AddInt32(&executed, 10) })
+	pool.SubmitAt("task1", start.Add(time.Hour*2), func() { atomic.AddInt32(&executed, 100) })
+	require.Equal(t, uint64(2), pool.Stats().Coalesced)
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&executed) == 1 }, time.Second, time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(start), time.Millisecond*100)
+
+	// the scheduled tasks are submitted on stop
+	pool.SubmitAt("task2", start.Add(time.Hour), func() { atomic.AddInt32(&executed, 1) })
+	pool.StopAndWait()
+	require.Equal(t, int32(2), atomic.LoadInt32(&executed))
+}
@@ -0,0 +1,142 @@
+package uniqpool
+
+import (
+	"fmt"
+	"time"
+)
+
+// ShardedUniqPool fans the submissions out across several UniqPools by the hash of the task identifier, while
+// presenting one API. Each pool has its own inbound queue and dispatcher goroutine, so the pool scales when
+// a single dispatcher becomes the bottleneck. Tasks with the same identifier always go to the same pool, so they
+// are deduplicated as usual. All the pools share one worker pool.
+type ShardedUniqPool[T comparable] struct {
+	shards []*UniqPool[T]
+	// Consistent with the equality of the identifiers, so equal identifiers always go to the same pool.
+	hash func(T) uint64
+	// The worker pool shared by all the pools.
+	executor executor[T]
+}
+
+// NewShardedUniqPool creates a new ShardedUniqPool of shardsCount pools. Each pool has an inbound queue with
+// shardQueueCapacity. poolWorkersCount and poolCapacity define the worker pool shared by all the pools.
+// opts are applied to each pool. The limits of WithMemoryBudget, WithNamespaceQuotas and WithMaxPendingKeys are
+// split evenly between the pools, rounded up, so they hold for the whole ShardedUniqPool; as the identifiers are
+// not distributed perfectly evenly, a pool may reach its share of a limit before the others.
+// Returns an error wrapping ErrInvalidParameters if the parameters are invalid.
+func NewShardedUniqPool[T comparable](shardsCount, shardQueueCapacity, poolWorkersCount, poolCapacity int,
+	interval time.Duration, opts ...Option[T],
+) (*ShardedUniqPool[T], error) {
+	if shardsCount <= 0 {
+		return nil, fmt.Errorf("%w: shards count must be positive, got %d", ErrInvalidParameters, shardsCount)
+	}
+	if err := validateParameters(shardQueueCapacity, poolWorkersCount, poolCapacity, interval); err != nil {
+		return nil, err
+	}
+
+	// the shared executor is created according to the options, e.g. WithKeySharding
+	template := &UniqPool[T]{workersCount: poolWorkersCount, capacity: poolCapacity}
+	for _, opt := range opts {
+		opt(template)
+	}
+	if template.optionErr != nil {
+		return nil, template.optionErr
+	}
+	e := template.newExecutor()
+
+	s := &ShardedUniqPool[T]{
+		shards:   make([]*UniqPool[T], shardsCount),
+		hash:     newDefaultHasher[T](),
+		executor: e,
+	}
+	shardOpts := append(append([]Option[T]{}, opts...), withSharedExecutor[T](e), withShardLimits[T](shardsCount))
+	for i := range s.shards {
+		s.shards[i] = MustNew(shardQueueCapacity, poolWorkersCount, poolCapacity, interval, shardOpts...)
+	}
+
+	return s, nil
+}
+
+// Submit adds a task to the pool of its identifier. Will block if the inbound queue of the pool is full.
+func (s *ShardedUniqPool[T]) Submit(id T, fn func(), opts ...SubmitOption) {
+	s.shard(id).Submit(id, fn, opts...)
+}
+
+// TrySubmit adds a task to the pool of its identifier. Returns false if the inbound queue of the pool is full.
+func (s *ShardedUniqPool[T]) TrySubmit(id T, fn func(), opts ...SubmitOption) bool {
+	return s.shard(id).TrySubmit(id, fn, opts...)
+}
+
+// SubmitEx adds a task to the pool of its identifier and reports what happened to it, like UniqPool.SubmitEx.
+func (s *ShardedUniqPool[T]) SubmitEx(id T, fn func()) (Outcome, error) {
+	return s.shard(id).SubmitEx(id, fn)
+}
+
+// Shards returns the number of the pools.
+func (s *ShardedUniqPool[T]) Shards() int {
+	return len(s.shards)
+}
+
+// Stats returns the sum of the statistics of all the pools.
+func (s *ShardedUniqPool[T]) Stats() Stats {
+	var stats Stats
+	for _, p := range s.shards {
+		stats.add(p.Stats())
+	}
+	stats.Stopped = s.Stopped()
+	stats.setWorkers(s.executor.stats())
+
+	return stats
+}
+
+// Stopped returns true if all the pools are stopped.
+func (s *ShardedUniqPool[T]) Stopped() bool {
+	for _, p := range s.shards {
+		if !p.Stopped() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// StopAndWait stops all the pools and waits for all tasks to be executed.
+func (s *ShardedUniqPool[T]) StopAndWait() {
+	for _, p := range s.shards {
+		p.StopAndWait()
+	}
+	s.executor.stopAndWait()
+}
+
+// StopNow stops all the pools like UniqPool.StopNow and returns the identifiers of the discarded tasks.
+func (s *ShardedUniqPool[T]) StopNow() []T {
+	var discarded []T
+	for _, p := range s.shards {
+		discarded = append(discarded, p.StopNow()...)
+	}
+	s.executor.stopAndWait()
+
+	return discarded
+}
+
+// shard returns the pool of the task identifier.
+func (s *ShardedUniqPool[T]) shard(id T) *UniqPool[T] {
+	// the identifiers are normalized the same way by all the pools, so equal identifiers go to the same pool
+	id = s.shards[0].normalizeKey(id)
+
+	return s.shards[s.hash(id)%uint64(len(s.shards))]
+}
+
+// withShardLimits splits the limits configured by the previous options between n pools.
+func withShardLimits[T comparable](n int) Option[T] {
+	return func(p *UniqPool[T]) {
+		if p.memoryBudget > 0 {
+			p.memoryBudget = (p.memoryBudget + int64(n) - 1) / int64(n)
+		}
+		if p.maxPendingKeys > 0 {
+			p.maxPendingKeys = (p.maxPendingKeys + n - 1) / n
+		}
+		for namespace, slots := range p.namespaceSlots {
+			p.namespaceSlots[namespace] = make(chan struct{}, (cap(slots)+n-1)/n)
+		}
+	}
+}
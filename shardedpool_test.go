@@ -0,0 +1,62 @@
+package uniqpool
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestShardedUniqPool checks that the tasks are deduplicated across the pools and the statistics are summed.
+func TestShardedUniqPool(t *testing.T) {
+	_, err := NewShardedUniqPool[string](0, 10, 2, 10, time.Millisecond)
+	require.ErrorIs(t, err, ErrInvalidParameters)
+
+	pool, err := NewShardedUniqPool[string](4, 100, 2, 10, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 4, pool.Shards())
+
+	var executed int32
+	fn := func() { atomic.AddInt32(&executed, 1) }
+	for i := 0; i < 50; i++ {
+		pool.Submit("task"+strconv.Itoa(i), fn)
+		require.True(t, pool.TrySubmit("task"+strconv.Itoa(i), fn))
+	}
+	outcome, err := pool.SubmitEx("task0", fn)
+	require.NoError(t, err)
+	require.Equal(t, Coalesced, outcome)
+
+	stats := pool.Stats()
+	require.Equal(t, 50, stats.Pending)
+	require.Equal(t, uint64(51), stats.Coalesced)
+
+	pool.StopAndWait()
+	require.Equal(t, int32(50), atomic.LoadInt32(&executed))
+	require.True(t, pool.Stats().Stopped)
+}
+
+// TestShardedUniqPoolRouting checks that a pointer identifier goes to the same pool after its pointee changes
+// and that the limits are split between the pools.
+func TestShardedUniqPoolRouting(t *testing.T) {
+	type node struct{ name string }
+
+	pool, err := NewShardedUniqPool[*node](8, 10, 2, 10, time.Hour, WithMaxPendingKeys[*node](20),
+		WithNamespaceQuotas[*node](func(*node) string { return "" }, map[string]int{"": 9}))
+	require.NoError(t, err)
+	for _, shard := range pool.shards {
+		require.Equal(t, 3, shard.maxPendingKeys)
+		require.Equal(t, 2, cap(shard.namespaceSlots[""]))
+	}
+
+	n := &node{name: "a"}
+	pool.Submit(n, func() {})
+	n.name = "b"
+	outcome, err := pool.SubmitEx(n, func() {})
+	require.NoError(t, err)
+	require.Equal(t, Coalesced, outcome)
+
+	pool.StopAndWait()
+	require.Equal(t, uint64(1), pool.Stats().Dispatched)
+}
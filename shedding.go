@@ -0,0 +1,37 @@
+package uniqpool
+
+import "math/rand"
+
+// loadShedding drops a fraction of the new tasks as the inbound queue fills up (see WithLoadShedding).
+type loadShedding struct {
+	// The fill of the inbound queue from which the tasks are dropped.
+	minFill float64
+	// The fill of the inbound queue from which all tasks are dropped.
+	maxFill float64
+	// The drop probability at maxFill.
+	maxProb float64
+}
+
+// probability returns the drop probability at the fill of the inbound queue.
+func (l *loadShedding) probability(fill float64) float64 {
+	switch {
+	case fill < l.minFill:
+		return 0
+	case fill >= l.maxFill:
+		return 1
+	default:
+		return l.maxProb * (fill - l.minFill) / (l.maxFill - l.minFill)
+	}
+}
+
+// shed returns true if the new task must be dropped because of the fill of the inbound queue.
+func (p *UniqPool[T]) shed() bool {
+	if p.unbounded {
+		return false
+	}
+
+	fill := float64(p.inboundQueue.len()) / float64(p.inboundQueue.cap())
+	probability := p.shedding.probability(fill)
+
+	return probability > 0 && rand.Float64() < probability
+}
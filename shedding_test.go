@@ -0,0 +1,29 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadShedding checks that new tasks are dropped once the inbound queue fills up, while duplicates are coalesced.
+func TestLoadShedding(t *testing.T) {
+	l := loadShedding{minFill: 0.2, maxFill: 0.6, maxProb: 0.5}
+	require.Zero(t, l.probability(0.1))
+	require.InDelta(t, 0.25, l.probability(0.4), 1e-9)
+	require.Equal(t, 1.0, l.probability(0.6))
+
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock), WithLoadShedding[string](0.1, 0.2, 1))
+
+	require.True(t, pool.TrySubmit("task1", func() {}))
+	require.True(t, pool.TrySubmit("task2", func() {}))
+	require.ErrorIs(t, pool.TrySubmitErr("task3", func() {}), ErrQueueFull)
+	outcome, _ := pool.SubmitEx("task1", func() {})
+	require.Equal(t, Coalesced, outcome)
+
+	clock.tickChan <- time.Now()
+	pool.StopAndWait()
+	require.Equal(t, uint64(1), pool.Stats().Rejected)
+}
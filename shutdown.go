@@ -0,0 +1,82 @@
+package uniqpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrShutdownTimeout is returned by Shutdown if the pool has not stopped before the deadline.
+var ErrShutdownTimeout = errors.New("shutdown timed out")
+
+// shutdownReportedKeys is the maximum number of the identifiers of the unfinished tasks listed by Shutdown.
+const shutdownReportedKeys = 10
+
+// Shutdown stops the pool like StopAndWait and verifies that the dispatcher and all the workers have exited
+// before ctx is done. Otherwise it returns an error wrapping ErrShutdownTimeout that lists what is still running:
+// the dispatcher, the busy workers, the identifiers of the unfinished tasks and the backlogs. It is invaluable
+// when hunting goroutine leaks in tests and services. The pool keeps stopping in the background after the error.
+func (p *UniqPool[T]) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		p.shutdown()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	return fmt.Errorf("%w: %s", ErrShutdownTimeout, p.describeRunning())
+}
+
+// describeRunning returns the description of the parts of the pool that have not stopped yet.
+func (p *UniqPool[T]) describeRunning() string {
+	var parts []string
+	if atomic.LoadInt32(&p.dispatching) == 1 {
+		parts = append(parts, "dispatcher is running")
+	}
+
+	if workers := p.executor.stats(); workers.RunningWorkers > workers.IdleWorkers {
+		parts = append(parts, fmt.Sprintf("%d workers are busy", workers.RunningWorkers-workers.IdleWorkers))
+	}
+
+	var (
+		ids     []string
+		running int
+	)
+	for _, s := range p.stripes {
+		s.mutex.Lock()
+		for id, n := range s.running {
+			running += n
+			if len(ids) < shutdownReportedKeys {
+				ids = append(ids, fmt.Sprintf("%v", id))
+			}
+		}
+		s.mutex.Unlock()
+	}
+	if running > 0 {
+		list := strings.Join(ids, ", ")
+		if len(ids) == shutdownReportedKeys {
+			list += ", ..."
+		}
+		parts = append(parts, fmt.Sprintf("%d tasks are unfinished: %s", running, list))
+	}
+
+	if backlog := atomic.LoadInt64(&p.executorBacklog); backlog > 0 {
+		parts = append(parts, fmt.Sprintf("%d dispatched tasks wait for a worker", backlog))
+	}
+	if pending := p.inboundQueue.len(); pending > 0 {
+		parts = append(parts, fmt.Sprintf("%d tasks are pending", pending))
+	}
+
+	if len(parts) == 0 {
+		return "pool is finishing the stop"
+	}
+
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,29 @@
+package uniqpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestShutdown checks that a stuck task is reported when the pool does not stop before the deadline.
+func TestShutdown(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Millisecond)
+	require.NoError(t, pool.Shutdown(context.Background()))
+
+	pool = MustNew[string](10, 2, 10, time.Millisecond)
+	gate := make(chan struct{})
+	pool.Submit("stuck", func() { <-gate })
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err := pool.Shutdown(ctx)
+	require.ErrorIs(t, err, ErrShutdownTimeout)
+	require.Contains(t, err.Error(), "1 tasks are unfinished: stuck")
+
+	close(gate)
+	<-pool.Done()
+}
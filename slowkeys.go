@@ -0,0 +1,128 @@
+package uniqpool
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyDuration is the longest execution time of the tasks with an identifier (see SlowestKeys).
+type KeyDuration[T comparable] struct {
+	ID       T
+	Duration time.Duration
+}
+
+// slowKeys keeps the identifiers with the longest execution times over a sliding window: the executions
+// are recorded into the current bucket, which replaces the previous one every window, so the report covers
+// from one to two windows. Each bucket holds at most n identifiers.
+type slowKeys[T comparable] struct {
+	mutex    sync.Mutex
+	n        int
+	window   time.Duration
+	current  slowBucket[T]
+	previous slowBucket[T]
+}
+
+type slowBucket[T comparable] struct {
+	start     time.Time
+	durations map[T]time.Duration
+}
+
+func newSlowKeys[T comparable](n int, window time.Duration) *slowKeys[T] {
+	return &slowKeys[T]{
+		n:      n,
+		window: window,
+	}
+}
+
+// record records the execution time of the task with the identifier completed at now.
+func (s *slowKeys[T]) record(id T, d time.Duration, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rotate(now)
+
+	durations := s.current.durations
+	if prev, ok := durations[id]; ok || len(durations) < s.n {
+		if d > prev {
+			durations[id] = d
+		}
+		return
+	}
+
+	// the bucket is full, the fastest identifier gives way to a slower one
+	var (
+		fastest   T
+		fastestAt time.Duration = -1
+	)
+	for k, v := range durations {
+		if fastestAt < 0 || v < fastestAt {
+			fastest, fastestAt = k, v
+		}
+	}
+	if d > fastestAt {
+		delete(durations, fastest)
+		durations[id] = d
+	}
+}
+
+// rotate starts a new bucket if the current one is older than the window. Must be called under mutex.
+func (s *slowKeys[T]) rotate(now time.Time) {
+	if s.current.durations != nil && now.Sub(s.current.start) < s.window {
+		return
+	}
+
+	if s.current.durations != nil && now.Sub(s.current.start) < 2*s.window {
+		s.previous = s.current
+	} else {
+		// the current bucket is out of the window too
+		s.previous = slowBucket[T]{}
+	}
+	s.current = slowBucket[T]{start: now, durations: make(map[T]time.Duration, s.n)}
+}
+
+// slowest returns up to n identifiers with the longest execution times in descending order.
+func (s *slowKeys[T]) slowest(now time.Time) []KeyDuration[T] {
+	s.mutex.Lock()
+	s.rotate(now)
+	merged := make(map[T]time.Duration, len(s.current.durations)+len(s.previous.durations))
+	for _, b := range []slowBucket[T]{s.previous, s.current} {
+		for id, d := range b.durations {
+			if d > merged[id] {
+				merged[id] = d
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	slowest := make([]KeyDuration[T], 0, len(merged))
+	for id, d := range merged {
+		slowest = append(slowest, KeyDuration[T]{ID: id, Duration: d})
+	}
+	sort.Slice(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	if len(slowest) > s.n {
+		slowest = slowest[:s.n]
+	}
+
+	return slowest
+}
+
+// SlowestKeys returns the identifiers whose tasks took the longest to execute over the recent window,
+// in descending order of their longest execution time, so it is easy to find the entities that make
+// the pool fall behind (see WithSlowKeys). Returns nil if the tracking is not enabled.
+func (p *UniqPool[T]) SlowestKeys() []KeyDuration[T] {
+	if p.slowKeys == nil {
+		return nil
+	}
+
+	return p.slowKeys.slowest(p.clock.Now())
+}
+
+// watchSlowTask starts the timer calling onSlowTask if the task with the identifier is still running after
+// slowTaskThreshold. The timer must be stopped when the task completes.
+func (p *UniqPool[T]) watchSlowTask(id T) Timer {
+	startedAt := p.clock.Now()
+	return p.clock.AfterFunc(p.slowTaskThreshold, func() {
+		p.onSlowTask(id, p.clock.Now().Sub(startedAt))
+	})
+}
@@ -0,0 +1,50 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSlowKeys checks that the slowest identifiers are kept over the window and forgotten after it.
+func TestSlowKeys(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newSlowKeys[string](2, time.Minute)
+
+	s.record("fast", time.Millisecond, start)
+	s.record("slow", time.Second, start)
+	s.record("slow", time.Millisecond, start)
+	s.record("slower", time.Second*2, start)
+	require.Equal(t, []KeyDuration[string]{{ID: "slower", Duration: time.Second * 2}, {ID: "slow", Duration: time.Second}},
+		s.slowest(start))
+
+	s.record("fast", time.Millisecond, start.Add(time.Minute))
+	require.Len(t, s.slowest(start.Add(time.Minute)), 2)
+	require.Empty(t, s.slowest(start.Add(time.Minute*3)))
+
+	pool := MustNew[string](10, 2, 10, time.Millisecond, WithSlowKeys[string](1, time.Hour))
+	pool.Submit("task1", func() { time.Sleep(time.Millisecond * 20) })
+	pool.Submit("task2", func() {})
+	pool.StopAndWait()
+
+	slowest := pool.SlowestKeys()
+	require.Len(t, slowest, 1)
+	require.Equal(t, "task1", slowest[0].ID)
+	require.GreaterOrEqual(t, slowest[0].Duration, time.Millisecond*20)
+}
+
+// TestSlowTaskThreshold checks that the hook is called while the task is still running.
+func TestSlowTaskThreshold(t *testing.T) {
+	slow := make(chan string, 1)
+	pool := MustNew[string](10, 2, 10, time.Millisecond,
+		WithSlowTaskThreshold(time.Millisecond*10, func(id string, _ time.Duration) { slow <- id }))
+
+	gate := make(chan struct{})
+	pool.Submit("stuck", func() { <-gate })
+	require.Equal(t, "stuck", <-slow)
+
+	close(gate)
+	pool.StopAndWait()
+	require.Empty(t, slow)
+}
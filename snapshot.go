@@ -0,0 +1,50 @@
+package uniqpool
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// snapshotEntry is a pending task in the snapshot of a PayloadPool.
+type snapshotEntry[K comparable, V any] struct {
+	ID       K
+	Payloads []V
+}
+
+// Snapshot writes the pending tasks of the pool to w in submission order. Tasks that are already
+// executing are not included. Identifiers and payloads must be encodable with encoding/gob.
+// The snapshot can be loaded with Restore, so the backlog survives planned restarts and can be
+// migrated between instances.
+func (p *PayloadPool[K, V]) Snapshot(w io.Writer) error {
+	p.mutex.Lock()
+	ids := p.pendingIDs()
+	entries := make([]snapshotEntry[K, V], 0, len(ids))
+	for _, id := range ids {
+		payloads := p.pending[id].payloads
+		entries = append(entries, snapshotEntry[K, V]{ID: id, Payloads: append([]V(nil), payloads...)})
+	}
+	p.mutex.Unlock()
+
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// Restore reads the snapshot written by Snapshot from r and submits its tasks to the pool.
+// Will block if the inbound queue is full. Restore takes no handler: the functions of the tasks are not
+// serialized, and the restored payloads are executed by the handler of the pool, like the submitted ones,
+// since they are coalesced with the pending tasks of the same identifiers and may not have a handler of their own.
+func (p *PayloadPool[K, V]) Restore(r io.Reader) error {
+	var entries []snapshotEntry[K, V]
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		for _, payload := range entry.Payloads {
+			if err := p.Submit(entry.ID, payload); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package uniqpool
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSnapshotRestore checks that the pending tasks are migrated between pools.
+func TestSnapshotRestore(t *testing.T) {
+	source, err := NewPayloadPool(MustNew[int](10, 2, 10, time.Hour), func(int, string) {})
+	require.NoError(t, err)
+
+	require.NoError(t, source.Submit(1, "a"))
+	require.NoError(t, source.Submit(2, "b"))
+	require.NoError(t, source.Submit(1, "c"))
+
+	var buf bytes.Buffer
+	require.NoError(t, source.Snapshot(&buf))
+
+	var (
+		mu       sync.Mutex
+		executed []int
+		payloads = make(map[int][]string)
+	)
+	target, err := NewPayloadPool(MustNew[int](10, 2, 10, time.Hour), func(id int, payload string) {
+		mu.Lock()
+		executed = append(executed, id)
+		mu.Unlock()
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, target.Restore(&buf))
+	target.mutex.Lock()
+	for id, pending := range target.pending {
+		payloads[id] = pending.payloads
+	}
+	target.mutex.Unlock()
+	require.Equal(t, map[int][]string{1: {"a", "c"}, 2: {"b"}}, payloads)
+
+	require.NoError(t, target.StopAndWait())
+	require.ElementsMatch(t, []int{1, 2}, executed)
+
+	require.NoError(t, source.StopAndWait())
+}
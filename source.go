@@ -0,0 +1,56 @@
+package uniqpool
+
+import (
+	"context"
+	"errors"
+)
+
+// Source is a source of messages, e.g. a Kafka topic or a Redis stream.
+// See the kafkasource and redisstreamsource packages for the reference adapters.
+type Source[M any] interface {
+	// Receive blocks until the next message is available.
+	Receive(ctx context.Context) (M, error)
+	// Ack acknowledges the message after it is processed.
+	Ack(ctx context.Context, msg M) error
+}
+
+// Consume reads messages from the source and submits them to the pool until ctx is cancelled or the source
+// returns an error. key derives the task identifier from the message, so messages with the same identifier
+// are coalesced: handler is called once with the first of them. All the coalesced messages are acknowledged
+// after handler returns without an error. If handler returns an error, the messages are not acknowledged,
+// so the source can redeliver them. Messages are acknowledged even after ctx is cancelled, so the tasks
+// executed while the pool is stopping are not redelivered. Acknowledgement errors are passed to onAckError,
+// if it is not nil.
+// Consume does not stop the pool. Returns nil if ctx is cancelled.
+func Consume[K comparable, M any](ctx context.Context, pool *UniqPool[K], src Source[M], key func(msg M) K,
+	handler func(ctx context.Context, id K, msg M) error, onAckError func(error),
+) error {
+	payloads, err := NewBatchPayloadPool(pool, func(id K, msgs []M) {
+		if err := handler(ctx, id, msgs[0]); err != nil {
+			return
+		}
+
+		for _, msg := range msgs {
+			if err := src.Ack(context.Background(), msg); err != nil && onAckError != nil {
+				onAckError(err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := src.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+				return nil
+			}
+			return err
+		}
+
+		if err := payloads.Submit(key(msg), msg); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package uniqpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testMessage struct {
+	key   string
+	value int
+}
+
+// chanSource is a Source reading messages from a channel.
+type chanSource struct {
+	msgs  chan testMessage
+	mu    sync.Mutex
+	acked []int
+}
+
+func (s *chanSource) Receive(ctx context.Context) (testMessage, error) {
+	select {
+	case msg := <-s.msgs:
+		return msg, nil
+	case <-ctx.Done():
+		return testMessage{}, ctx.Err()
+	}
+}
+
+func (s *chanSource) Ack(_ context.Context, msg testMessage) error {
+	s.mu.Lock()
+	s.acked = append(s.acked, msg.value)
+	s.mu.Unlock()
+	return nil
+}
+
+// TestConsume checks that coalesced messages are acknowledged after the execution and failed ones are not.
+func TestConsume(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Millisecond*50)
+	src := &chanSource{msgs: make(chan testMessage, 10)}
+
+	var (
+		mu       sync.Mutex
+		executed []int
+	)
+
+	src.msgs <- testMessage{key: "a", value: 1}
+	src.msgs <- testMessage{key: "a", value: 2}
+	src.msgs <- testMessage{key: "b", value: 3}
+	src.msgs <- testMessage{key: "fail", value: 4}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Consume(ctx, pool, Source[testMessage](src), func(msg testMessage) string { return msg.key },
+			func(_ context.Context, id string, msg testMessage) error {
+				mu.Lock()
+				executed = append(executed, msg.value)
+				mu.Unlock()
+				if id == "fail" {
+					return errors.New("failed")
+				}
+				return nil
+			}, nil)
+	}()
+
+	require.Eventually(t, func() bool {
+		src.mu.Lock()
+		defer src.mu.Unlock()
+		return len(src.acked) == 3
+	}, time.Second, time.Millisecond*10)
+
+	cancel()
+	require.NoError(t, <-done)
+	pool.StopAndWait()
+
+	require.ElementsMatch(t, []int{1, 3, 4}, executed)
+	require.ElementsMatch(t, []int{1, 2, 3}, src.acked)
+}
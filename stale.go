@@ -0,0 +1,74 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// staleChecksPerThreshold is the number of checks of the backlog age per stale threshold.
+const staleChecksPerThreshold = 4
+
+// startStaleWatch starts checking the age of the oldest pending task (see WithStaleBacklog).
+// The goroutine exits when the pool is stopped.
+func (p *UniqPool[T]) startStaleWatch() {
+	if p.staleThreshold <= 0 {
+		return
+	}
+
+	ticker := p.clock.NewTicker(p.staleThreshold / staleChecksPerThreshold)
+	stopChan := p.stopChan
+	p.stopWaitGroup.Add(1)
+	go func() {
+		defer p.stopWaitGroup.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopChan:
+				atomic.StoreInt32(&p.stale, 0)
+				return
+			case <-ticker.C():
+				p.checkStale()
+			}
+		}
+	}()
+}
+
+// checkStale calls onStale when the oldest pending task becomes older than the stale threshold.
+// The callback is called again only after the backlog has recovered.
+func (p *UniqPool[T]) checkStale() {
+	age := p.oldestPendingAge()
+	if age <= p.staleThreshold {
+		atomic.StoreInt32(&p.stale, 0)
+		return
+	}
+
+	if atomic.CompareAndSwapInt32(&p.stale, 0, 1) && p.onStale != nil {
+		p.onStale(age)
+	}
+}
+
+// oldestPendingAge returns the time the oldest task waiting to be dispatched has been pending.
+// Zero if there are no such tasks.
+func (p *UniqPool[T]) oldestPendingAge() time.Duration {
+	var oldest time.Time
+	for _, s := range p.stripes {
+		s.mutex.Lock()
+		for id, k := range s.keys {
+			// with ReleaseOnCompletion the identifier of the executing task is kept in the dedup set
+			if p.keyRelease == ReleaseOnCompletion && s.running[id] > 0 {
+				continue
+			}
+			if oldest.IsZero() || k.submittedAt.Before(oldest) {
+				oldest = k.submittedAt
+			}
+		}
+		s.mutex.Unlock()
+	}
+
+	if oldest.IsZero() {
+		return 0
+	}
+
+	return p.clock.Now().Sub(oldest)
+}
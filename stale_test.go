@@ -0,0 +1,26 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStaleBacklog checks that the callback is called once when the oldest pending task exceeds the threshold.
+func TestStaleBacklog(t *testing.T) {
+	stale := make(chan time.Duration, 10)
+	pool := MustNew[string](10, 2, 10, time.Hour,
+		WithStaleBacklog[string](time.Millisecond*20, func(age time.Duration) { stale <- age }))
+	require.False(t, pool.Stats().StaleBacklog)
+
+	pool.Submit("task1", func() {})
+	require.Greater(t, <-stale, time.Millisecond*20)
+	require.True(t, pool.Stats().StaleBacklog)
+
+	time.Sleep(time.Millisecond * 20)
+	require.Empty(t, stale)
+
+	pool.StopAndWait()
+	require.False(t, pool.Stats().StaleBacklog)
+}
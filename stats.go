@@ -0,0 +1,235 @@
+package uniqpool
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stats contains the statistics of the pool.
+type Stats struct {
+	// The number of tasks in the inbound queue. A growing inbound backlog means that the interval is too long
+	// or the drain batch is too small (see WithMaxDrainBatch).
+	Pending int
+	// The number of the dispatched tasks waiting for a worker, including the ones being handed over to the full
+	// worker pool. A growing executor backlog means that the workers are too few or too slow.
+	ExecutorBacklog int
+	// The estimated memory of the tasks in the inbound queue in bytes. Zero if the memory budget is not used.
+	PendingBytes int64
+	// The number of tasks accepted to the inbound queue.
+	Submitted uint64
+	// The number of tasks coalesced with the pending tasks with the same identifier.
+	Coalesced uint64
+	// The number of tasks dropped because of the suppression window or the quarantine.
+	Suppressed uint64
+	// The number of tasks rejected because the inbound queue or the namespace quota was full.
+	Rejected uint64
+	// The number of tasks dispatched to the worker pool.
+	Dispatched uint64
+	// Estimated percentiles of the time from the submission of a task to the start of its execution,
+	// including the accumulation interval and the wait in the worker pool queue.
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+	LatencyP99 time.Duration
+	// The number of tasks dispatched per second over the sliding window (see WithStatsWindow).
+	Throughput float64
+	// The fraction of the submissions coalesced with the pending tasks over the sliding window.
+	// Values close to 1 mean that most of the submissions are duplicates.
+	DedupRatio float64
+	// The statistics of the worker pool, so one call gives the picture of both stages of the pipeline.
+	// Not affected by ResetStats.
+	// The number of the running workers, including the idle ones.
+	RunningWorkers int
+	// The number of the workers waiting for tasks.
+	IdleWorkers int
+	// The number of the dispatched tasks waiting for a worker.
+	WaitingTasks uint64
+	// The number of the tasks submitted to the worker pool.
+	SubmittedTasks uint64
+	// The number of the tasks completed by the workers without a panic.
+	SuccessfulTasks uint64
+	// The number of the tasks that panicked.
+	FailedTasks uint64
+	// The current number of the tasks allowed to execute at once. Zero if it is not tuned
+	// (see WithAdaptiveConcurrency).
+	ConcurrencyLimit int
+	// True if the circuit breaker paused the dispatching (see WithCircuitBreaker).
+	CircuitOpen bool
+	// True if the resource limits slowed down or paused the dispatching (see WithResourceLimits).
+	ResourceThrottled bool
+	// True if the oldest pending task is older than the stale threshold (see WithStaleBacklog).
+	StaleBacklog bool
+	// The number of the tasks executing longer than the hard limit (see WithWorkerSupervisor).
+	HungTasks int
+	// True if the pool is stopped.
+	Stopped bool
+
+	// Counters over the sliding window, for summing DedupRatio of several pools.
+	windowSubmitted uint64
+	windowCoalesced uint64
+}
+
+// counters contains the statistics counters of the pool. Updated atomically.
+type counters struct {
+	submitted  uint64
+	coalesced  uint64
+	suppressed uint64
+	rejected   uint64
+	dispatched uint64
+	// The number of tasks started by the workers, unlike dispatched excluding the discarded and skipped ones.
+	executed uint64
+}
+
+// statsSnapshot is the state of the statistics counters and the latency histogram at a point in time.
+type statsSnapshot struct {
+	counters counters
+	latency  latencyCounts
+}
+
+// Stats returns the statistics of the pool. The counters and the latency percentiles are accumulated since
+// the pool was created or the statistics were reset by ResetStats.
+func (p *UniqPool[T]) Stats() Stats {
+	var base statsSnapshot
+	if b := p.statsBase.Load(); b != nil {
+		base = *b
+	}
+
+	stats := p.statsSince(p.snapshotStats(), base)
+	for _, l := range p.lanes {
+		stats.add(l.Stats())
+	}
+
+	return stats
+}
+
+// ResetStats returns the statistics of the pool and resets its counters and latency percentiles, so services
+// can compute per-interval values without tracking the deltas themselves. The rolling throughput and dedup ratio
+// are not affected (see WithStatsWindow).
+func (p *UniqPool[T]) ResetStats() Stats {
+	p.statsResetMutex.Lock()
+	defer p.statsResetMutex.Unlock()
+
+	var base statsSnapshot
+	if b := p.statsBase.Load(); b != nil {
+		base = *b
+	}
+
+	now := p.snapshotStats()
+	p.statsBase.Store(&now)
+
+	stats := p.statsSince(now, base)
+	for _, l := range p.lanes {
+		stats.add(l.ResetStats())
+	}
+
+	return stats
+}
+
+// snapshotStats returns the current state of the statistics counters.
+func (p *UniqPool[T]) snapshotStats() statsSnapshot {
+	return statsSnapshot{
+		counters: counters{
+			submitted:  atomic.LoadUint64(&p.counters.submitted),
+			coalesced:  atomic.LoadUint64(&p.counters.coalesced),
+			suppressed: atomic.LoadUint64(&p.counters.suppressed),
+			rejected:   atomic.LoadUint64(&p.counters.rejected),
+			dispatched: atomic.LoadUint64(&p.counters.dispatched),
+			executed:   atomic.LoadUint64(&p.counters.executed),
+		},
+		latency: p.latency.snapshot(),
+	}
+}
+
+// statsSince returns the statistics of the pool accumulated between the base and the now snapshots.
+func (p *UniqPool[T]) statsSince(now, base statsSnapshot) Stats {
+	latency := now.latency.sub(base.latency)
+	stats := Stats{
+		Pending:         p.pending(),
+		PendingBytes:    atomic.LoadInt64(&p.pendingBytes),
+		ExecutorBacklog: int(atomic.LoadInt64(&p.executorBacklog)),
+		Submitted:       now.counters.submitted - base.counters.submitted,
+		Coalesced:       now.counters.coalesced - base.counters.coalesced,
+		Suppressed:      now.counters.suppressed - base.counters.suppressed,
+		Rejected:        now.counters.rejected - base.counters.rejected,
+		Dispatched:      now.counters.dispatched - base.counters.dispatched,
+		LatencyP50:      latency.quantile(0.5),
+		LatencyP95:      latency.quantile(0.95),
+		LatencyP99:      latency.quantile(0.99),
+		StaleBacklog:    atomic.LoadInt32(&p.stale) == 1,
+		HungTasks:       int(atomic.LoadInt64(&p.hungTasks)),
+		Stopped:         p.Stopped(),
+	}
+	if p.breaker != nil {
+		stats.CircuitOpen = p.breaker.isOpen()
+	}
+	if p.resources != nil {
+		stats.ResourceThrottled = p.resources.isThrottled()
+	}
+	if p.limiter != nil {
+		stats.ConcurrencyLimit = p.limiter.current()
+	}
+	stats.setWorkers(p.executor.stats())
+	p.rollingStats(&stats)
+
+	return stats
+}
+
+// String returns a human-readable summary of the pool for logs and debugger watches, e.g.
+// "uniqpool(name=orders pending=42 running=8 coalesced=1023 stopped=false)". The name is omitted if it is not set.
+func (p *UniqPool[T]) String() string {
+	workers := p.executor.stats()
+
+	var b strings.Builder
+	b.WriteString("uniqpool(")
+	if p.name != "" {
+		fmt.Fprintf(&b, "name=%s ", p.name)
+	}
+	fmt.Fprintf(&b, "pending=%d running=%d coalesced=%d stopped=%t)",
+		p.pending(), workers.RunningWorkers-workers.IdleWorkers, atomic.LoadUint64(&p.counters.coalesced), p.Stopped())
+
+	return b.String()
+}
+
+// setWorkers sets the statistics of the worker pool.
+func (s *Stats) setWorkers(workers executorStats) {
+	s.RunningWorkers = workers.RunningWorkers
+	s.IdleWorkers = workers.IdleWorkers
+	s.WaitingTasks = workers.WaitingTasks
+	s.SubmittedTasks = workers.SubmittedTasks
+	s.SuccessfulTasks = workers.SuccessfulTasks
+	s.FailedTasks = workers.FailedTasks
+}
+
+// add adds the statistics of another pool. Latency percentiles cannot be summed, the maximum is taken instead.
+// The statistics of the worker pool are not added, as the pools of a PoolSet share one worker pool.
+func (s *Stats) add(other Stats) {
+	s.Pending += other.Pending
+	s.ExecutorBacklog += other.ExecutorBacklog
+	s.PendingBytes += other.PendingBytes
+	s.ConcurrencyLimit += other.ConcurrencyLimit
+	s.CircuitOpen = s.CircuitOpen || other.CircuitOpen
+	s.ResourceThrottled = s.ResourceThrottled || other.ResourceThrottled
+	s.StaleBacklog = s.StaleBacklog || other.StaleBacklog
+	s.HungTasks += other.HungTasks
+	s.Submitted += other.Submitted
+	s.Coalesced += other.Coalesced
+	s.Suppressed += other.Suppressed
+	s.Rejected += other.Rejected
+	s.Dispatched += other.Dispatched
+	s.LatencyP50 = maxDuration(s.LatencyP50, other.LatencyP50)
+	s.LatencyP95 = maxDuration(s.LatencyP95, other.LatencyP95)
+	s.LatencyP99 = maxDuration(s.LatencyP99, other.LatencyP99)
+	s.Throughput += other.Throughput
+	s.windowSubmitted += other.windowSubmitted
+	s.windowCoalesced += other.windowCoalesced
+	s.DedupRatio = dedupRatio(s.windowSubmitted, s.windowCoalesced)
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,66 @@
+package uniqpool
+
+import "sync"
+
+// UniqStore is a set of identifiers of the pending tasks that can be shared between pools,
+// e.g. between the replicas of a service. A task whose identifier is already in the store is coalesced,
+// even if it is pending in another pool. Implementations must be safe for concurrent use.
+type UniqStore[T comparable] interface {
+	// Add adds the identifier to the set. Returns false if the identifier is already in the set.
+	Add(id T) bool
+	// Remove removes the identifier from the set.
+	Remove(id T)
+	// Contains returns true if the identifier is in the set.
+	Contains(id T) bool
+	// Len returns the number of identifiers in the set.
+	Len() int
+}
+
+// MemoryStore is an in-memory UniqStore, e.g. to deduplicate the tasks of several pools of one process.
+// It is the reference implementation for alternative stores: sharded, approximate or persistent ones.
+type MemoryStore[T comparable] struct {
+	mutex sync.Mutex
+	ids   map[T]struct{}
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore[T comparable]() *MemoryStore[T] {
+	return &MemoryStore[T]{ids: make(map[T]struct{})}
+}
+
+// Add implements UniqStore.
+func (s *MemoryStore[T]) Add(id T) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	s.ids[id] = struct{}{}
+
+	return true
+}
+
+// Remove implements UniqStore.
+func (s *MemoryStore[T]) Remove(id T) {
+	s.mutex.Lock()
+	delete(s.ids, id)
+	s.mutex.Unlock()
+}
+
+// Contains implements UniqStore.
+func (s *MemoryStore[T]) Contains(id T) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.ids[id]
+	return ok
+}
+
+// Len implements UniqStore.
+func (s *MemoryStore[T]) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.ids)
+}
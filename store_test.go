@@ -0,0 +1,83 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryStore checks that the pools sharing a MemoryStore coalesce the tasks pending in each other.
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore[string]()
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool1 := MustNew(10, 2, 10, time.Hour, WithClock[string](clock), WithUniqStore[string](store))
+	pool2 := MustNew(10, 2, 10, time.Hour, WithUniqStore[string](store))
+
+	outcome, _ := pool1.SubmitEx("task1", func() {})
+	require.Equal(t, Enqueued, outcome)
+	outcome, _ = pool2.SubmitEx("task1", func() {})
+	require.Equal(t, Coalesced, outcome)
+	require.True(t, store.Contains("task1"))
+	require.Equal(t, 1, store.Len())
+
+	pool1.StopAndWait()
+	pool2.StopAndWait()
+	require.Zero(t, store.Len())
+}
+
+// blockingStore is a UniqStore whose Add blocks for the identifier "slow" until unblocked.
+type blockingStore struct {
+	*MemoryStore[string]
+	entered chan struct{}
+	unblock chan struct{}
+}
+
+func (s blockingStore) Add(id string) bool {
+	if id == "slow" {
+		close(s.entered)
+		<-s.unblock
+	}
+	return s.MemoryStore.Add(id)
+}
+
+// TestUniqStoreUnlocked checks that a slow store call does not block the submitters of other identifiers.
+func TestUniqStoreUnlocked(t *testing.T) {
+	store := blockingStore{
+		MemoryStore: NewMemoryStore[string](),
+		entered:     make(chan struct{}),
+		unblock:     make(chan struct{}),
+	}
+	pool := MustNew(10, 2, 10, time.Millisecond, WithUniqStore[string](store))
+
+	slowDone := make(chan Outcome)
+	go func() {
+		outcome, _ := pool.SubmitEx("slow", func() {})
+		slowDone <- outcome
+	}()
+	<-store.entered
+
+	executed := make(chan struct{})
+	fastDone := make(chan Outcome)
+	go func() {
+		outcome, _ := pool.SubmitEx("fast", func() { close(executed) })
+		fastDone <- outcome
+	}()
+
+	select {
+	case outcome := <-fastDone:
+		require.Equal(t, Enqueued, outcome)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the submitter is blocked by the store call of another identifier")
+	}
+	select {
+	case <-executed:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the dispatcher is blocked by the store call of another identifier")
+	}
+
+	close(store.unblock)
+	require.Equal(t, Enqueued, <-slowDone)
+	pool.StopAndWait()
+	require.Zero(t, store.Len())
+}
@@ -0,0 +1,55 @@
+package uniqpool
+
+import "time"
+
+// SubmitOption configures a single submission.
+type SubmitOption func(*submitOptions)
+
+type submitOptions struct {
+	onDone func(err error, dur time.Duration)
+	labels map[string]string
+	// The result waiter calling onDone. Nil if onDone is not set.
+	waiter resultWaiter
+}
+
+// WithOnDone sets the callback called when the task finishes, including the case when the submission is coalesced
+// with a pending task: then the callback is called when that task finishes. err is ErrTaskPanicked if the task
+// panicked, ErrPoolStopped if it was discarded by StopNow, ErrQueueFull if it was rejected or dropped,
+// ErrQuarantined if its identifier is quarantined, and nil if it was suppressed. dur is the time from
+// the submission to the completion. The callback is called in the worker goroutine, or right away
+// in the caller's goroutine if the task is not executed.
+func WithOnDone(fn func(err error, dur time.Duration)) SubmitOption {
+	return func(o *submitOptions) {
+		o.onDone = fn
+	}
+}
+
+// WithLabels attaches the labels to the task, so they are reported by Peek while the task is pending.
+// If the submission is coalesced with a pending task, the labels of that task are kept.
+// The map must not be modified after the submission.
+func WithLabels(labels map[string]string) SubmitOption {
+	return func(o *submitOptions) {
+		o.labels = labels
+	}
+}
+
+// submitOptions applies the options of a submission.
+func (p *UniqPool[T]) submitOptions(opts []SubmitOption) submitOptions {
+	var o submitOptions
+	if len(opts) == 0 {
+		return o
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if onDone := o.onDone; onDone != nil {
+		submittedAt := p.clock.Now()
+		o.waiter = func(_ ExecutionID, _ any, err error) {
+			onDone(err, p.clock.Now().Sub(submittedAt))
+		}
+	}
+
+	return o
+}
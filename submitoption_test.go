@@ -0,0 +1,29 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOnDone checks that the completion callbacks of the coalesced submissions are called when the task finishes.
+func TestOnDone(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(2, 2, 10, time.Hour, WithClock[string](clock))
+
+	done := make(chan error, 4)
+	onDone := WithOnDone(func(err error, _ time.Duration) { done <- err })
+
+	pool.Submit("task1", func() {}, onDone)
+	pool.Submit("task1", func() {}, onDone)
+	pool.Submit("task2", func() { panic("test") }, onDone)
+	require.False(t, pool.TrySubmit("task3", func() {}, onDone))
+	require.ErrorIs(t, <-done, ErrQueueFull)
+
+	clock.tickChan <- time.Now()
+	errs := []error{<-done, <-done, <-done}
+	require.ElementsMatch(t, []error{nil, nil, ErrTaskPanicked}, errs)
+
+	pool.StopAndWait()
+}
@@ -0,0 +1,170 @@
+package uniqpool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDispatcherPanicked is the error of EventDispatcherRestarted.
+var ErrDispatcherPanicked = errors.New("dispatcher panicked")
+
+const (
+	// dispatcherMinBackoff is the delay before the first restart of the panicked dispatch loop.
+	dispatcherMinBackoff = time.Millisecond * 10
+	// dispatcherMaxBackoff is the maximum delay before a restart. The delay doubles after each consecutive panic.
+	dispatcherMaxBackoff = time.Second
+)
+
+// superviseDispatcher runs the dispatch loop and restarts it with a backoff after an internal panic, so one panic
+// does not silently kill all future dispatching while the submitters keep blocking. Each restart emits
+// EventDispatcherRestarted. The tasks being dispatched at the moment of the panic are dropped (see abandon).
+func (p *UniqPool[T]) superviseDispatcher() {
+	backoff := dispatcherMinBackoff
+	for {
+		startedAt := p.clock.Now()
+		panicValue := runProtected(p.dispatchLoop)
+		if panicValue == nil {
+			return
+		}
+
+		var zero T
+		p.observe(EventDispatcherRestarted, zero, fmt.Errorf("%w: %v", ErrDispatcherPanicked, panicValue))
+
+		// a loop that has run long enough is not considered to panic consecutively
+		if p.clock.Now().Sub(startedAt) > dispatcherMaxBackoff {
+			backoff = dispatcherMinBackoff
+		}
+		elapsed := make(chan struct{})
+		timer := p.clock.AfterFunc(backoff, func() { close(elapsed) })
+		select {
+		case <-elapsed:
+		case <-p.stopChan:
+			// the restarted loop processes the stop right away
+			timer.Stop()
+		}
+		if backoff *= 2; backoff > dispatcherMaxBackoff {
+			backoff = dispatcherMaxBackoff
+		}
+	}
+}
+
+// observeDispatched reports the dispatched tasks. The observer is called after the tasks are released from
+// the dedup state but before they are handed over to the executor, so if it panics, the tasks are abandoned
+// before the panic is passed on to the supervisor.
+func (p *UniqPool[T]) observeDispatched(ready []task[T]) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.abandon(ready, fmt.Errorf("%w: %v", ErrDispatcherPanicked, r))
+			panic(r)
+		}
+	}()
+
+	for _, t := range ready {
+		p.observe(EventDispatched, t.id, nil)
+	}
+}
+
+// abandon completes the dispatched tasks that will not be handed over to the executor, so their identifiers
+// do not stay in the dedup set, and notifies their result waiters with err.
+func (p *UniqPool[T]) abandon(lost []task[T], err error) {
+	atomic.AddInt64(&p.executorBacklog, -int64(len(lost)))
+	for _, t := range lost {
+		if t.probe {
+			p.breaker.cancelProbe()
+		}
+
+		waiters := t.waiters
+		if p.keyRelease == ReleaseOnCompletion {
+			waiters = append(waiters, p.releaseCompleted(t.id)...)
+		}
+		for _, w := range waiters {
+			w(t.execID, nil, err)
+		}
+
+		if t.chainFn != nil {
+			// no follow-up tasks will be pushed
+			p.pushed()
+		}
+		p.finish(t.id)
+		p.resumeParked(t.id)
+	}
+}
+
+const (
+	// taskRunning means that the supervised task is executing within the hard limit.
+	taskRunning int32 = iota
+	// taskHung means that the supervised task has been executing longer than the hard limit.
+	taskHung
+	// taskFinished means that the supervised task returned within the hard limit.
+	taskFinished
+)
+
+// superviseWorker executes the task, reporting it as hung if it runs longer than workerHardLimit.
+// With replaceHungWorkers the worker waits for the task only until it is hung.
+func (p *UniqPool[T]) superviseWorker(t task[T]) {
+	var state int32
+	hungChan := make(chan struct{})
+	timer := p.clock.AfterFunc(p.workerHardLimit, func() {
+		// counted before the state is changed, so finished never makes the counter negative
+		atomic.AddInt64(&p.hungTasks, 1)
+		if !atomic.CompareAndSwapInt32(&state, taskRunning, taskHung) {
+			atomic.AddInt64(&p.hungTasks, -1)
+			return
+		}
+		p.observe(EventWorkerHung, t.id, nil)
+		close(hungChan)
+	})
+	finished := func() {
+		timer.Stop()
+		if !atomic.CompareAndSwapInt32(&state, taskRunning, taskFinished) {
+			atomic.AddInt64(&p.hungTasks, -1)
+		}
+	}
+
+	if !p.replaceHungWorkers {
+		defer finished()
+		p.execute(t)
+		return
+	}
+
+	var detached int32
+	done := make(chan any, 1)
+	go func() {
+		panicValue := runProtected(func() { p.execute(t) })
+		finished()
+		if atomic.CompareAndSwapInt32(&detached, 0, -1) {
+			done <- panicValue
+			return
+		}
+
+		// the worker is released, so the panic is not rethrown to the executor: it is passed to the panic handler
+		// here. EventFailed is emitted by run only if the events are observed, the fast path does not report it.
+		if panicValue != nil {
+			if p.panicHandler != nil {
+				p.panicHandler(panicValue)
+			} else {
+				printPanic(panicValue)
+			}
+		}
+		p.jobsWaitGroup.Done()
+	}()
+
+	select {
+	case panicValue := <-done:
+		if panicValue != nil {
+			panic(panicValue)
+		}
+	case <-hungChan:
+		// the detached task is waited for by StopAndWait like the dispatched ones
+		p.jobsWaitGroup.Add(1)
+		if !atomic.CompareAndSwapInt32(&detached, 0, 1) {
+			// the task has just returned
+			p.jobsWaitGroup.Done()
+			if panicValue := <-done; panicValue != nil {
+				panic(panicValue)
+			}
+		}
+	}
+}
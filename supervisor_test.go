@@ -0,0 +1,222 @@
+package uniqpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDispatcherRestart checks that the dispatcher keeps dispatching after a panic in the dispatch loop.
+func TestDispatcherRestart(t *testing.T) {
+	clock := panickyClock{panics: new(int32)}
+	var restarted int32
+	pool := MustNew(10, 1, 10, time.Millisecond, WithClock[string](clock),
+		WithObserver[string](ObserverFunc[string](func(e Event[string]) {
+			if e.Type == EventDispatcherRestarted && errors.Is(e.Err, ErrDispatcherPanicked) {
+				atomic.AddInt32(&restarted, 1)
+			}
+		})))
+
+	atomic.StoreInt32(clock.panics, 1)
+	done := make(chan struct{})
+	pool.Submit("task1", func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the task is not dispatched after the restart")
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&restarted))
+	require.NoError(t, pool.Healthy())
+
+	pool.StopAndWait()
+}
+
+// TestDispatcherClassifierPanic checks that the dispatcher restarted after a panic of the classifier does not
+// leave the dedup state locked.
+func TestDispatcherClassifierPanic(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	var panicking, restarted int32
+	classify := func(id string) string {
+		if atomic.CompareAndSwapInt32(&panicking, 1, 0) {
+			panic("classifier failed")
+		}
+		return "ns"
+	}
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[string](clock),
+		WithNamespaceProfiles(classify, map[string]NamespaceProfile{"ns": {SuppressionWindow: time.Hour}}),
+		WithObserver[string](ObserverFunc[string](func(e Event[string]) {
+			if e.Type == EventDispatcherRestarted {
+				atomic.AddInt32(&restarted, 1)
+			}
+		})))
+	defer pool.StopAndWait()
+
+	done := make(chan struct{})
+	pool.Submit("a", func() { close(done) })
+	clock.tickChan <- time.Now()
+	<-done
+
+	// the pending task keeps the ticker running, and the tick prunes the execution time of "a",
+	// calling the classifier under the stripe mutex
+	done = make(chan struct{})
+	pool.Submit("b", func() { close(done) })
+	atomic.StoreInt32(&panicking, 1)
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&restarted) == 1 }, time.Second, time.Millisecond)
+
+	go func() { clock.tickChan <- time.Now() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the dedup state is left locked")
+	}
+
+	outcome, err := pool.SubmitEx("a", func() {})
+	require.NoError(t, err)
+	require.Equal(t, Suppressed, outcome)
+}
+
+// TestDispatcherLostBatch checks that the tasks lost by the panicked dispatcher leave the dedup set
+// and their result waiters are notified.
+func TestDispatcherLostBatch(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	var panicked int32
+	pool := MustNew(10, 1, 10, time.Hour, WithClock[string](clock), WithKeyRelease[string](ReleaseOnCompletion),
+		WithObserver[string](ObserverFunc[string](func(e Event[string]) {
+			if e.Type == EventDispatched && atomic.CompareAndSwapInt32(&panicked, 0, 1) {
+				panic("observer failed")
+			}
+		})))
+	defer pool.StopAndWait()
+
+	results := make(chan error, 1)
+	pool.SubmitWithResult("a", func() (any, error) { return nil, nil }, func(_ any, err error) { results <- err })
+	clock.tickChan <- time.Now()
+	require.ErrorIs(t, <-results, ErrDispatcherPanicked)
+	require.Zero(t, pool.Stats().Pending)
+
+	done := make(chan struct{})
+	outcome, err := pool.SubmitEx("a", func() { close(done) })
+	require.NoError(t, err)
+	require.Equal(t, Enqueued, outcome)
+	clock.tickChan <- time.Now()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the task is not dispatched after the restart")
+	}
+}
+
+// panickyClock is a real Clock whose tickers panic in C the given number of times.
+type panickyClock struct {
+	panics *int32
+}
+
+func (c panickyClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c panickyClock) NewTicker(d time.Duration) Ticker {
+	return panickyTicker{Ticker: realClock{}.NewTicker(d), panics: c.panics}
+}
+
+func (c panickyClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// panickyTicker is a Ticker that panics in C while the counter is positive.
+type panickyTicker struct {
+	Ticker
+	panics *int32
+}
+
+func (t panickyTicker) C() <-chan time.Time {
+	if atomic.AddInt32(t.panics, -1) >= 0 {
+		panic("ticker failed")
+	}
+	return t.Ticker.C()
+}
+
+// TestWorkerSupervisor checks that the worker executing a hung task is replaced.
+func TestWorkerSupervisor(t *testing.T) {
+	var hung int32
+	pool := MustNew(10, 1, 10, time.Millisecond, WithWorkerSupervisor[string](time.Millisecond*20, true),
+		WithObserver[string](ObserverFunc[string](func(e Event[string]) {
+			if e.Type == EventWorkerHung && e.ID == "hung" {
+				atomic.AddInt32(&hung, 1)
+			}
+		})))
+
+	gate := make(chan struct{})
+	pool.Submit("hung", func() { <-gate })
+	require.Eventually(t, func() bool { return pool.Stats().HungTasks == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&hung))
+
+	// the only worker is released, so the next task is executed while the hung one is still running
+	done := make(chan struct{})
+	pool.Submit("next", func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "the next task is not executed")
+	}
+
+	close(gate)
+	pool.StopAndWait()
+	require.Zero(t, pool.Stats().HungTasks)
+}
+
+// TestWorkerSupervisorDetachedPanic checks that the panic of a detached task is reported to the observer.
+func TestWorkerSupervisorDetachedPanic(t *testing.T) {
+	failed := make(chan error, 1)
+	pool := MustNew(10, 1, 10, time.Millisecond, WithWorkerSupervisor[string](time.Millisecond*20, true),
+		WithObserver[string](ObserverFunc[string](func(e Event[string]) {
+			if e.Type == EventFailed && e.ID == "hung" {
+				failed <- e.Err
+			}
+		})))
+
+	gate := make(chan struct{})
+	pool.Submit("hung", func() {
+		<-gate
+		panic("detached task failed")
+	})
+	require.Eventually(t, func() bool { return pool.Stats().HungTasks == 1 }, time.Second, time.Millisecond)
+
+	close(gate)
+	select {
+	case err := <-failed:
+		require.ErrorIs(t, err, ErrTaskPanicked)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the panic is not reported")
+	}
+	pool.StopAndWait()
+}
+
+// TestWorkerSupervisorDetachedPanicFastPath checks that the panic of a detached task executed without observers
+// is passed to the panic handler.
+func TestWorkerSupervisorDetachedPanicFastPath(t *testing.T) {
+	panics := make(chan any, 1)
+	pool := MustNew(10, 1, 10, time.Millisecond, WithWorkerSupervisor[string](time.Millisecond*20, true),
+		WithPanicHandler[string](func(panicValue any) { panics <- panicValue }))
+
+	gate := make(chan struct{})
+	pool.Submit("hung", func() {
+		<-gate
+		panic("detached task failed")
+	})
+	require.Eventually(t, func() bool { return pool.Stats().HungTasks == 1 }, time.Second, time.Millisecond)
+
+	close(gate)
+	select {
+	case panicValue := <-panics:
+		require.Equal(t, "detached task failed", panicValue)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the panic is not reported")
+	}
+	pool.StopAndWait()
+}
@@ -0,0 +1,71 @@
+//go:build go1.25
+
+package uniqpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSynctest checks that the pool works with fake time inside a testing/synctest bubble
+// and leaves no goroutines or tickers behind after it is stopped.
+func TestSynctest(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pool := MustNew(10, 2, 10, time.Minute, WithSuppressionWindow[string](time.Hour))
+
+		var processed int32
+		fn := func() { atomic.AddInt32(&processed, 1) }
+
+		pool.Submit("task1", fn)
+		pool.Submit("task1", fn)
+
+		time.Sleep(time.Second * 59)
+		synctest.Wait()
+		require.Zero(t, atomic.LoadInt32(&processed))
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		require.Equal(t, int32(1), atomic.LoadInt32(&processed))
+
+		pool.Submit("task1", fn)
+		require.Equal(t, uint64(1), pool.Stats().Suppressed)
+
+		pool.StopAndWait()
+		require.Equal(t, int32(1), processed)
+	})
+}
+
+// TestSynctestBlockingSubmit checks that a Submit blocked on the full inbound queue is durably blocked,
+// so the fake time advances and the dispatcher frees the queue.
+func TestSynctestBlockingSubmit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pool := MustNew(1, 1, 1, time.Minute, WithKeySharding[int](nil))
+
+		start := time.Now()
+		pool.Submit(1, func() {})
+		pool.Submit(2, func() {})
+		require.Equal(t, time.Minute, time.Since(start))
+
+		pool.StopAndWait()
+	})
+}
+
+// TestSynctestPoolSet checks that idle tenant pools are removed on fake time.
+func TestSynctestPoolSet(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		set := NewPoolSet[string](10, 2, 10, time.Second, time.Hour)
+
+		set.Submit("tenant1", "task1", func() {})
+		require.Equal(t, 1, set.Tenants())
+
+		time.Sleep(time.Hour * 2)
+		synctest.Wait()
+		require.Zero(t, set.Tenants())
+
+		set.StopAndWait()
+	})
+}
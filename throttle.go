@@ -0,0 +1,94 @@
+package uniqpool
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttler implements leading-edge throttle per task identifier on top of the pool: the first task with an
+// identifier is dispatched to the worker pool immediately, without waiting for the interval, and further
+// tasks with the same identifier are dropped during the cooldown.
+type Throttler[T comparable] struct {
+	pool     *UniqPool[T]
+	cooldown time.Duration
+
+	// The time of the last dispatched task by identifier.
+	dispatchedAt map[T]time.Time
+	// The time of the last removal of the expired identifiers.
+	prunedAt time.Time
+	mutex    sync.Mutex
+}
+
+// NewThrottler creates a new Throttler dispatching tasks to the pool at most once per cooldown per identifier.
+func NewThrottler[T comparable](pool *UniqPool[T], cooldown time.Duration) *Throttler[T] {
+	return &Throttler[T]{
+		pool:         pool,
+		cooldown:     cooldown,
+		dispatchedAt: make(map[T]time.Time),
+		prunedAt:     pool.clock.Now(),
+	}
+}
+
+// Submit dispatches the task to the pool immediately, unless a task with the same identifier has been
+// dispatched during the cooldown. Returns false if the task is dropped. Panics if the pool is stopped.
+func (t *Throttler[T]) Submit(id T, fn func()) bool {
+	id = t.pool.normalizeKey(id)
+	now := t.pool.clock.Now()
+
+	t.mutex.Lock()
+	last, ok := t.dispatchedAt[id]
+	if ok && now.Sub(last) < t.cooldown {
+		t.mutex.Unlock()
+		return false
+	}
+	// recorded before the dispatch, so concurrent duplicates are dropped while it is in progress
+	t.dispatchedAt[id] = now
+	t.prune(now)
+	t.mutex.Unlock()
+
+	outcome := t.pool.dispatchNow(task[T]{id: id, fn: fn})
+	if outcome != Enqueued {
+		// the identifier is not throttled by a task that is not dispatched
+		t.restore(id, now, last, ok)
+	}
+
+	switch outcome {
+	case Enqueued:
+		return true
+	case Stopped:
+		panic("pool is stopped")
+	default:
+		// coalesced with a pending task or suppressed by the pool
+		return false
+	}
+}
+
+// restore restores the time of the last dispatched task with the identifier, if it is still the one recorded
+// at now for the task that is not dispatched.
+func (t *Throttler[T]) restore(id T, now, last time.Time, ok bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if at, recorded := t.dispatchedAt[id]; !recorded || !at.Equal(now) {
+		return
+	}
+	if ok {
+		t.dispatchedAt[id] = last
+	} else {
+		delete(t.dispatchedAt, id)
+	}
+}
+
+// prune removes the identifiers whose cooldown has expired. Must be called under mutex.
+func (t *Throttler[T]) prune(now time.Time) {
+	if now.Sub(t.prunedAt) < t.cooldown {
+		return
+	}
+	t.prunedAt = now
+
+	for id, at := range t.dispatchedAt {
+		if now.Sub(at) >= t.cooldown {
+			delete(t.dispatchedAt, id)
+		}
+	}
+}
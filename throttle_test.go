@@ -0,0 +1,50 @@
+package uniqpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestThrottler checks that the first task is executed without waiting for the interval and its duplicates
+// are dropped during the cooldown.
+func TestThrottler(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Hour)
+	th := NewThrottler(pool, time.Millisecond*200)
+
+	var executed int32
+	require.True(t, th.Submit("task1", func() { atomic.AddInt32(&executed, 1) }))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&executed) == 1 }, time.Second, time.Millisecond)
+
+	require.False(t, th.Submit("task1", func() { atomic.AddInt32(&executed, 1) }))
+	require.True(t, th.Submit("task2", func() { atomic.AddInt32(&executed, 1) }))
+
+	time.Sleep(time.Millisecond * 200)
+	require.True(t, th.Submit("task1", func() { atomic.AddInt32(&executed, 1) }))
+
+	pool.StopAndWait()
+	require.Equal(t, int32(3), atomic.LoadInt32(&executed))
+	require.Equal(t, uint64(3), pool.Stats().Dispatched)
+	require.Zero(t, pool.pending())
+
+	require.Panics(t, func() { th.Submit("task3", func() {}) })
+}
+
+// TestThrottlerNotDispatched checks that a task coalesced with a pending one does not start the cooldown.
+func TestThrottlerNotDispatched(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Hour)
+	th := NewThrottler(pool, time.Hour)
+
+	executed := make(chan string, 2)
+	pool.Submit("task1", func() { executed <- "pending" })
+	require.False(t, th.Submit("task1", func() { executed <- "coalesced" }))
+
+	require.True(t, pool.FlushKey("task1"))
+	require.Equal(t, "pending", <-executed)
+	require.True(t, th.Submit("task1", func() { executed <- "throttled" }))
+	require.Equal(t, "throttled", <-executed)
+
+	pool.StopAndWait()
+}
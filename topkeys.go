@@ -0,0 +1,88 @@
+package uniqpool
+
+import (
+	"sort"
+	"sync"
+)
+
+// KeyCount is the number of the duplicate submissions of a task identifier (see TopKeys).
+type KeyCount[T comparable] struct {
+	ID T
+	// The estimated number of the submissions coalesced with the pending tasks with the identifier.
+	// Overestimated by at most Error.
+	Count uint64
+	// The maximum overestimation of Count.
+	Error uint64
+}
+
+// heavyHitters tracks the identifiers with the most coalesced submissions in a fixed memory by the space-saving
+// algorithm: when a new identifier does not fit, it replaces the one with the smallest count and inherits
+// that count as its error.
+type heavyHitters[T comparable] struct {
+	mutex    sync.Mutex
+	capacity int
+	counts   map[T]*KeyCount[T]
+}
+
+func newHeavyHitters[T comparable](capacity int) *heavyHitters[T] {
+	return &heavyHitters[T]{
+		capacity: capacity,
+		counts:   make(map[T]*KeyCount[T], capacity),
+	}
+}
+
+// record counts a coalesced submission of the identifier.
+func (h *heavyHitters[T]) record(id T) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if c, ok := h.counts[id]; ok {
+		c.Count++
+		return
+	}
+
+	if len(h.counts) < h.capacity {
+		h.counts[id] = &KeyCount[T]{ID: id, Count: 1}
+		return
+	}
+
+	var least *KeyCount[T]
+	for _, c := range h.counts {
+		if least == nil || c.Count < least.Count {
+			least = c
+		}
+	}
+	delete(h.counts, least.ID)
+	least.ID = id
+	least.Error = least.Count
+	least.Count++
+	h.counts[id] = least
+}
+
+// top returns up to n identifiers with the highest counts in descending order.
+func (h *heavyHitters[T]) top(n int) []KeyCount[T] {
+	h.mutex.Lock()
+	top := make([]KeyCount[T], 0, len(h.counts))
+	for _, c := range h.counts {
+		top = append(top, *c)
+	}
+	h.mutex.Unlock()
+
+	sort.Slice(top, func(i, j int) bool { return top[i].Count > top[j].Count })
+	if len(top) > n {
+		top = top[:n]
+	}
+
+	return top
+}
+
+// TopKeys returns up to n identifiers that generate the most duplicate traffic, i.e. the most submissions coalesced
+// with their pending tasks, in descending order, so operators can see which keys are the hottest.
+// The counts are estimated in a fixed memory (see WithTopKeys). Returns nil if the tracking is not enabled.
+func (p *UniqPool[T]) TopKeys(n int) []KeyCount[T] {
+	if p.hotKeys == nil || n <= 0 {
+		return nil
+	}
+
+	return p.hotKeys.top(n)
+}
@@ -0,0 +1,37 @@
+package uniqpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTopKeys checks that the identifiers with the most coalesced submissions are reported first.
+func TestTopKeys(t *testing.T) {
+	h := newHeavyHitters[string](2)
+	h.record("a")
+	h.record("a")
+	h.record("b")
+	h.record("c")
+	require.ElementsMatch(t, []KeyCount[string]{{ID: "a", Count: 2}, {ID: "c", Count: 2, Error: 1}}, h.top(3))
+
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[string](clock), WithTopKeys[string](10))
+	untracked := MustNew[string](10, 2, 10, time.Hour)
+	require.Nil(t, untracked.TopKeys(1))
+	untracked.StopAndWait()
+
+	for i := 0; i < 3; i++ {
+		pool.Submit("hot", func() {})
+	}
+	pool.Submit("warm", func() {})
+	pool.Submit("warm", func() {})
+	pool.Submit("cold", func() {})
+
+	require.Equal(t, []KeyCount[string]{{ID: "hot", Count: 2}}, pool.TopKeys(1))
+	require.Len(t, pool.TopKeys(10), 2)
+
+	clock.tickChan <- time.Now()
+	pool.StopAndWait()
+}
@@ -1,11 +1,13 @@
 package uniqpool
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/alitto/pond"
 )
 
 type task[T comparable] struct {
@@ -13,6 +15,39 @@ type task[T comparable] struct {
 	id T
 	// The function that will be executed by the task.
 	fn func()
+	// The function that will be executed by the task, if it receives a context. Replaces fn.
+	ctxFn func(ctx context.Context)
+	// The function that will be executed by the task, if it produces a result. Replaces fn.
+	resultFn func(ctx context.Context) (any, error)
+	// The function that will be executed by the task, if it returns the follow-up tasks. Replaces fn.
+	chainFn func() []Step[T]
+	// The function that will be executed by the task, if it is requeued on error. Replaces fn.
+	errFn func(ctx context.Context) error
+	// True if the task is a follow-up of a completed chained task, so it is accepted while the pool is stopping.
+	followUp bool
+	// Callbacks waiting for the result of the task. Filled in when the task leaves the inbound queue.
+	waiters []resultWaiter
+	// The ID of the execution of the task. Assigned when the task is dispatched.
+	execID ExecutionID
+	// The namespace of the task identifier. Empty if namespaces are not used.
+	namespace string
+	// The time when the task was accepted to the inbound queue.
+	submittedAt time.Time
+	// The estimated memory used by the pending task. Zero if the memory budget is not used.
+	size int64
+	// The payload of the task passed to the size function of the memory budget. Nil if the task has no payload.
+	payload any
+	// The priority of the task. Used only if priorities are enabled.
+	priority int
+	// The number of the worker slots taken by the executing task (see SubmitWeighted).
+	weight int
+	// True if the task is the probe of the half-open circuit breaker.
+	probe bool
+}
+
+// plain returns true if the task executes fn.
+func (t *task[T]) plain() bool {
+	return t.ctxFn == nil && t.resultFn == nil && t.chainFn == nil && t.errFn == nil
 }
 
 // UniqPool is a pool of tasks. Each task has a unique identifier. If several tasks with the same identifier
@@ -21,125 +56,1634 @@ type task[T comparable] struct {
 // You can set an interval during which tasks will accumulate so as not to create many identical tasks.
 type UniqPool[T comparable] struct {
 	// The pool of workers that will execute the tasks.
-	pool *pond.WorkerPool
+	executor executor[T]
+	// The executor is shared with other pools and is not stopped by the pool.
+	sharedExecutor bool
+	// The number of workers and the capacity of the worker pool. Used to recreate the executor on Start.
+	workersCount int
+	capacity     int
 	// The interval during which tasks will accumulate so as not to create many identical tasks.
 	interval time.Duration
+	// Source of the current time and tickers.
+	clock Clock
+	// The error of the first option with an invalid value, returned by New.
+	optionErr error
+	// Ticker for processing the inbound queue. Nil while the pool is idle. Used only by the processTasks goroutine.
+	ticker Ticker
+	// The pool is idle: the inbound queue is empty and the ticker is stopped. Updated atomically.
+	idle int32
+	// Delivers the ticker started by the submitter that woke the idle pool up.
+	wakeChan chan Ticker
+	// Requests to flush the inbound queue before the next tick (see SubmitWithMaxWait).
+	flushChan chan struct{}
+	// The timer of the earliest requested flush and its time. Nil if no flush is requested.
+	flushTimer Timer
+	flushAt    time.Time
+	flushMutex sync.Mutex
+	// The maximum time an accepted task waits in the inbound queue. Zero if not limited.
+	maxQueueLatency time.Duration
 
-	// Channel for Submit.
-	inboundChan chan task[T]
-	// Map for checking the uniqueness of the task identifier. [key]->[position in inboundQueue]
-	uniqMap map[T]struct{}
-	// Mutex for working with the inbound queue.
-	inboundMutex sync.Mutex
+	// Queue for Submit.
+	inboundQueue *inboundQueue[T]
+	// Stripes of the dedup state: identifiers of the pending tasks, execution times and result waiters.
+	stripes []*dedupStripe[T]
+	// Hash function for distributing task identifiers among the stripes. Nil if there is one stripe.
+	stripeHash func(T) uint64
+	// The index of the stripe swept on the next tick. Used only by the dispatcher.
+	sweepCursor int
+	// External set of pending identifiers shared with other pools. Nil if not used.
+	uniqStore UniqStore[T]
+	// The number of accepted tasks that are not pushed into the inbound queue or dispatched yet. Updated atomically.
+	pendingPushes int32
+	// Signals that the last pending task is pushed into the inbound queue after the pool is stopped.
+	pushedChan chan struct{}
 
 	// Wait group for waiting for all tasks to be executed before stopping the pool.
 	stopWaitGroup sync.WaitGroup
 	// Channel for stopping the pool.
 	stopChan chan struct{}
 	stopped  int32
+	// The pool is being stopped. Updated atomically.
+	stopping int32
+	// Closed when the pool is stopped and all its tasks are completed.
+	doneChan chan struct{}
+	// Serializes Start with shutdown and abort: protects stopChan, doneChan, stopping and the context of the tasks
+	// while they are replaced by Start.
+	lifecycleMutex sync.Mutex
+	// The context that stops the pool when cancelled. See NewWithContext.
+	parentCtx context.Context
+	// The time for finishing the backlog when parentCtx is cancelled, after which the rest of it is discarded.
+	shutdownGrace time.Duration
+	// The pool is stopped by StopNow, the tasks that are not started yet are discarded. Updated atomically.
+	aborted int32
+	// Identifiers of the discarded tasks. Protected by discardedMutex.
+	discarded      []T
+	discardedMutex sync.Mutex
+	// Wait group for waiting for the tasks sent to the executor, which can be shared with other pools.
+	jobsWaitGroup sync.WaitGroup
+	// Context passed to the tasks added by SubmitContext. Cancelled by StopNow.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Statistics counters.
+	counters counters
+	// The statistics snapshot at the last ResetStats. Nil if the statistics have not been reset.
+	statsBase atomic.Pointer[statsSnapshot]
+	// Serializes ResetStats.
+	statsResetMutex sync.Mutex
+	// Rolling rates of the statistics counters.
+	rates rateWindow
+	// Histogram of the time from the submission of a task to the start of its execution.
+	latency latencyHistogram
+
+	// Guarantees that tasks with the same identifier are never executed concurrently.
+	serialKeys bool
+	// Identifiers of the tasks being executed. Used only in serial keys mode.
+	runningKeys map[T]struct{}
+	// Tasks waiting for the completion of a running task with the same identifier. Used only in serial keys mode.
+	parkedTasks map[T]task[T]
+	// Mutex for working with runningKeys and parkedTasks.
+	runningMutex sync.Mutex
+
+	// The moment when the task identifier is removed from the dedup set.
+	keyRelease ReleasePoint
+
+	// Hash function for binding task identifiers to workers. Used only in key sharding mode.
+	shardHash func(T) uint64
+	// Tasks are executed one by one in submission order.
+	orderedDispatch bool
+	// Decides what happens to the tasks that do not fit into the full inbound queue. Nil if they wait for the space.
+	rejectionPolicy RejectionPolicy[T]
+	// Drops a fraction of the new tasks as the inbound queue fills up. Nil if load shedding is disabled.
+	shedding *loadShedding
+	// The maximum number of the pending tasks, above which the oldest ones are dropped. Zero if not limited.
+	maxPendingKeys int
+	// The number of the oldest pending tasks to drop (see DropOldest). Updated atomically.
+	evictions int32
+	// Wakes up the dispatcher to drop the oldest pending tasks.
+	evictChan chan struct{}
+	// Identifiers of the pending tasks to dispatch right away (see FlushKey).
+	flushKeyRequests []T
+	flushKeysMutex   sync.Mutex
+	// Wakes up the dispatcher to dispatch the requested pending tasks.
+	flushKeyChan chan struct{}
+	// Tasks taken from the inbound queue in FIFO order, but not dispatched yet. Their slots are kept.
+	// Used only by the processTasks goroutine if priorities are not enabled.
+	carried carriedQueue[T]
+
+	// The interval after the execution of a task during which new tasks with the same identifier are dropped.
+	suppressionWindow time.Duration
+
+	// Cache of the results of executed tasks. Nil if the cache is disabled.
+	resultCache *resultCache[T]
+
+	// Returns the namespace of the task identifier. Nil if namespaces are not used.
+	namespaceClassifier func(T) string
+	// Semaphores limiting the number of pending tasks for the namespaces with quotas.
+	namespaceSlots map[string]chan struct{}
+	// The namespace classifier set by WithNamespaceProfiles. Nil if not set.
+	profileClassifier func(T) string
+	// The configuration overrides of the namespaces (see WithNamespaceProfiles). Nil if not used.
+	profiles map[string]NamespaceProfile
+	// The number of the pending tasks of the namespaces with a flush threshold. Updated atomically.
+	profilePending map[string]*int64
+	// True if any profile overrides the suppression window.
+	profileWindows bool
+
+	// The number of stripes of the dedup state.
+	dedupStripes int
+
+	// Converts task identifiers to the canonical form before the dedup. Nil if not used.
+	keyNormalizer func(T) T
+
+	// Pauses the dispatching when tasks fail too often. Nil if not used.
+	breaker *circuitBreaker
+	// 1 while the processTasks goroutine is running. Updated atomically.
+	dispatching int32
+	// The time of the last tick or wake-up of the dispatcher in Unix nanoseconds. Updated atomically.
+	tickedAt int64
+	// The times of the last iteration of the dispatcher loop and of the last dispatch in Unix nanoseconds.
+	// Updated atomically.
+	loopAt       int64
+	dispatchedAt int64
+	// The age of the oldest pending task above which the backlog is stale. Zero if not checked.
+	staleThreshold time.Duration
+	onStale        func(age time.Duration)
+	// 1 if the backlog is stale. Updated atomically.
+	stale int32
+	// Receives the periodic snapshots of the pending identifiers. Nil if not used.
+	checkpoint      func(keys []T)
+	checkpointEvery time.Duration
+	// The execution time after which onSlowTask is called for the still running task. Zero if not used.
+	slowTaskThreshold time.Duration
+	onSlowTask        func(id T, elapsed time.Duration)
+	// The execution time after which the task is reported as hung. Zero if the workers are not supervised.
+	workerHardLimit time.Duration
+	// Release the worker executing a hung task, so it can execute the next tasks.
+	replaceHungWorkers bool
+	// Receives the values of the panics of the tasks. Nil if not used.
+	panicHandler func(panicValue any)
+	// The configured lanes (see WithLane) and their pools by name. The pools share the executor of the pool.
+	laneSpecs []laneSpec
+	lanes     map[string]*UniqPool[T]
+	// The number of the tasks executing longer than workerHardLimit. Updated atomically.
+	hungTasks int64
+	// Keeps the identifiers with the longest execution times. Nil if not used.
+	slowKeys *slowKeys[T]
+	// Counts the coalesced submissions of the hottest identifiers. Nil if not used.
+	hotKeys *heavyHitters[T]
+	// Slows down the dispatching when the process uses too many resources. Nil if not used.
+	resources *resourceLimits
+	// Limits the number of the executing tasks by their latency and error rate. Nil if not used.
+	limiter *concurrencyLimiter
+	// Limits the total weight of the executing tasks. Shared with the lanes.
+	weights *weightedSemaphore
+
+	// Tasks are dispatched in priority order.
+	priorities bool
+	// The period during which a pending task gains one priority level. Zero disables aging.
+	priorityAging time.Duration
+	// Orders the pending tasks by priority. Nil if priorities are not enabled.
+	// Used only by the processTasks goroutine.
+	priorityQueue *priorityQueue[T]
+	// Identifiers of the pending tasks whose priority was changed, to be applied to the priority queue.
+	reprioritized      []T
+	reprioritizedMutex sync.Mutex
+
+	// Tasks held until their desired execution time by identifier (see SubmitAt).
+	schedule      map[T]*scheduledTask[T]
+	scheduleMutex sync.Mutex
+	// The gangs holding tasks (see NewGang).
+	gangs     map[*Gang[T]]struct{}
+	gangMutex sync.Mutex
+
+	// The maximum estimated memory of the pending tasks in bytes. Zero if not limited.
+	memoryBudget int64
+	// Returns the estimated memory used by the pending task. Nil if the default estimation is used.
+	sizeFn func(id T, payload any) int64
+	// The estimated memory of the pending tasks in bytes. Updated atomically.
+	pendingBytes int64
+	// The number of the submitters waiting for the memory budget. Updated atomically.
+	memoryWaiters int32
+	// Signals the submitters waiting for the memory budget that the memory is released.
+	memoryCond *sync.Cond
+	// The number of the pending tasks cancelled by CancelKey and not dropped yet. Updated atomically.
+	cancelledKeys int32
+	// The number of the dispatched tasks not started by the workers yet. Updated atomically.
+	executorBacklog int64
+
+	// True if the inbound queue is not bounded by its capacity.
+	unbounded bool
+	// True if the inbound queue stores the tasks in a ring buffer.
+	ringBuffer bool
+	// The number of tasks in the unbounded inbound queue above which onSoftCap is called. Zero if not set.
+	softCap   int
+	onSoftCap func(pending int)
+	// 1 if the inbound queue has exceeded the soft cap and has not been drained below it yet. Updated atomically.
+	overSoftCap int32
+
+	// The maximum number of tasks dispatched per tick. Updated atomically.
+	maxBatch int64
+	// The limit is set by WithMaxDrainBatch, so it does not follow the capacity of the inbound queue.
+	maxBatchFixed bool
+	// Buffer for the tasks taken from the inbound queue. Used only by the processTasks goroutine.
+	batch []task[T]
+	// Free jobs, so dispatching does not allocate in the steady state.
+	jobPool sync.Pool
+
+	// Receives the lifecycle events. Nil if not set.
+	observer Observer[T]
+	// Delivers the lifecycle events to the channel returned by Events. Nil until Events is called.
+	events     atomic.Pointer[eventStream[T]]
+	eventsOnce sync.Once
+
+	// Quarantines the identifiers of the tasks that fail repeatedly. Nil if quarantine is disabled.
+	quarantine *quarantine[T]
+
+	// Requeues the failed tasks added by SubmitRetryable. Nil if requeuing is disabled.
+	requeue *requeuer[T]
+
+	// The name of the pool for logs. Empty if not set.
+	name string
+
+	// The time when the pool was created or restarted.
+	startedAt time.Time
+
+	// Executes all the plain tasks dispatched at once in a single call. Nil if the tasks are executed one by one.
+	batchHandler func(batch []KeyedTask[T])
+}
+
+// job is a task dispatched to the executor. Jobs are reused, so the function passed to the executor
+// is allocated once per job rather than once per task.
+type job[T comparable] struct {
+	t   task[T]
+	run func()
 }
 
-// New creates a new UniqPool.
-func New[T comparable](inboundQueueCapacity, poolWorkersCount, poolCapacity int, interval time.Duration) *UniqPool[T] {
-	if inboundQueueCapacity <= 0 || poolWorkersCount <= 0 || poolCapacity <= 0 || interval <= 0 {
-		panic("invalid parameters")
+// newJob creates a job that executes its task and returns itself to the pool.
+func (p *UniqPool[T]) newJob() any {
+	j := &job[T]{}
+	j.run = func() {
+		t := j.t
+		j.t = task[T]{}
+		p.jobPool.Put(j)
+		atomic.AddInt64(&p.executorBacklog, -1)
+
+		defer p.jobsWaitGroup.Done()
+		if p.workerHardLimit > 0 {
+			p.superviseWorker(t)
+			return
+		}
+		p.execute(t)
+	}
+
+	return j
+}
+
+// ErrInvalidParameters is returned by New if the parameters of the pool are invalid.
+var ErrInvalidParameters = errors.New("invalid parameters")
+
+// New creates a new UniqPool. Returns an error wrapping ErrInvalidParameters if the parameters are invalid.
+func New[T comparable](inboundQueueCapacity, poolWorkersCount, poolCapacity int, interval time.Duration,
+	opts ...Option[T],
+) (*UniqPool[T], error) {
+	if err := validateParameters(inboundQueueCapacity, poolWorkersCount, poolCapacity, interval); err != nil {
+		return nil, err
 	}
 
 	p := &UniqPool[T]{
-		pool:        pond.New(poolWorkersCount, poolCapacity),
-		interval:    interval,
-		inboundChan: make(chan task[T], inboundQueueCapacity),
-		uniqMap:     make(map[T]struct{}, inboundQueueCapacity),
-		stopChan:    make(chan struct{}),
+		interval:     interval,
+		clock:        realClock{},
+		inboundQueue: newInboundQueue[T](inboundQueueCapacity),
+		dedupStripes: runtime.GOMAXPROCS(0),
+		maxBatch:     int64(inboundQueueCapacity),
+		rates:        rateWindow{window: defaultStatsWindow},
+		memoryCond:   sync.NewCond(new(sync.Mutex)),
+		stopChan:     make(chan struct{}),
+		doneChan:     make(chan struct{}),
+		parentCtx:    context.Background(),
+		pushedChan:   make(chan struct{}, 1),
+		wakeChan:     make(chan Ticker, 1),
+		flushChan:    make(chan struct{}, 1),
+		evictChan:    make(chan struct{}, 1),
+		flushKeyChan: make(chan struct{}, 1),
+		runningKeys:  make(map[T]struct{}),
+		parkedTasks:  make(map[T]task[T]),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.optionErr != nil {
+		return nil, p.optionErr
+	}
+	if err := p.resolveClassifier(); err != nil {
+		return nil, err
+	}
+	if err := p.checkProfiles(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.unbounded:
+		p.inboundQueue = newInboundQueue[T](maxInt)
+	case p.ringBuffer:
+		p.inboundQueue = newRingInboundQueue[T](inboundQueueCapacity)
+	}
+
+	p.stripes = newDedupStripes[T](p.dedupStripes, inboundQueueCapacity)
+	if p.dedupStripes > 1 {
+		p.stripeHash = newDefaultHasher[T]()
 	}
 
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	if p.priorities {
+		p.priorityQueue = newPriorityQueue[T](p.priorityAging, p.clock.Now())
+	}
+
+	p.workersCount = poolWorkersCount
+	p.capacity = poolCapacity
+	if p.limiter != nil {
+		p.limiter.setMax(poolWorkersCount)
+	}
+	p.weights = newWeightedSemaphore(poolWorkersCount)
+	if p.executor == nil {
+		p.executor = p.newExecutor()
+	}
+	p.newLanes(poolWorkersCount, poolCapacity, opts)
+
+	p.jobPool.New = p.newJob
+	// the rolling statistics of a young pool are computed from its creation
+	p.startedAt = p.clock.Now()
+	p.rates.observe(rateSample{at: p.startedAt})
+
+	// the pool starts idle, the ticker is started by the first submitted task
+	p.idle = 1
+
 	p.stopWaitGroup.Add(1)
+	atomic.StoreInt32(&p.dispatching, 1)
 	go p.processTasks()
+	p.watchContext()
+	p.startCheckpoints()
+	p.startStaleWatch()
 
-	return p
+	return p, nil
 }
 
-// Try submit adds a task to the pool.
-func (p *UniqPool[T]) TrySubmit(id T, fn func()) bool {
-	if p.Stopped() {
-		panic("pool is stopped")
+// NewWithContext creates a new UniqPool bound to ctx: when ctx is cancelled, the pool is stopped the same way
+// as by StopAndWait. With WithShutdownGrace, the backlog that is not finished within the grace period
+// is discarded as by StopNow. Use Done to wait for the stop to complete.
+func NewWithContext[T comparable](ctx context.Context, inboundQueueCapacity, poolWorkersCount, poolCapacity int,
+	interval time.Duration, opts ...Option[T],
+) (*UniqPool[T], error) {
+	return New(inboundQueueCapacity, poolWorkersCount, poolCapacity, interval,
+		append([]Option[T]{withParentContext[T](ctx)}, opts...)...)
+}
+
+// watchContext stops the pool when the parent context is cancelled.
+func (p *UniqPool[T]) watchContext() {
+	if p.parentCtx.Done() == nil {
+		return
 	}
 
-	p.inboundMutex.Lock()
-	defer p.inboundMutex.Unlock()
+	stopChan := p.stopChan
+	go func() {
+		select {
+		case <-p.parentCtx.Done():
+		case <-stopChan:
+			// stopped explicitly
+			return
+		}
 
-	// check the uniqueness of the task identifier
-	if _, ok := p.uniqMap[id]; ok {
-		return true
+		if p.shutdownGrace > 0 {
+			timer := p.clock.AfterFunc(p.shutdownGrace, p.abort)
+			defer timer.Stop()
+		}
+		p.shutdown()
+	}()
+}
+
+// newExecutor creates the executor of the pool according to its options.
+func (p *UniqPool[T]) newExecutor() executor[T] {
+	switch {
+	case p.orderedDispatch:
+		// a single worker executes tasks in the order of dispatching
+		return newShardedExecutor(1, p.capacity, func(T) uint64 { return 0 }, p.panicHandler)
+	case p.shardHash != nil:
+		return newShardedExecutor(p.workersCount, p.capacity, p.shardHash, p.panicHandler)
+	default:
+		return newPondExecutor[T](p.workersCount, p.capacity, p.panicHandler)
 	}
+}
 
-	select {
-	case p.inboundChan <- task[T]{id: id, fn: fn}:
-		p.uniqMap[id] = struct{}{}
-		return true
+// MustNew creates a new UniqPool like New, but panics if the parameters are invalid.
+func MustNew[T comparable](inboundQueueCapacity, poolWorkersCount, poolCapacity int, interval time.Duration,
+	opts ...Option[T],
+) *UniqPool[T] {
+	p, err := New(inboundQueueCapacity, poolWorkersCount, poolCapacity, interval, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// validateParameters checks the parameters of the pool.
+func validateParameters(inboundQueueCapacity, poolWorkersCount, poolCapacity int, interval time.Duration) error {
+	switch {
+	case inboundQueueCapacity <= 0:
+		return fmt.Errorf("%w: inbound queue capacity must be positive, got %d", ErrInvalidParameters, inboundQueueCapacity)
+	case poolWorkersCount <= 0:
+		return fmt.Errorf("%w: workers count must be positive, got %d", ErrInvalidParameters, poolWorkersCount)
+	case poolCapacity <= 0:
+		return fmt.Errorf("%w: worker pool capacity must be positive, got %d", ErrInvalidParameters, poolCapacity)
+	case interval <= 0:
+		return fmt.Errorf("%w: interval must be positive, got %v", ErrInvalidParameters, interval)
 	default:
-		return false
+		return nil
 	}
 }
 
+// Try submit adds a task to the pool.
+func (p *UniqPool[T]) TrySubmit(id T, fn func(), opts ...SubmitOption) bool {
+	o := p.submitOptions(opts)
+	return mustSubmit(p.submitWaiter(task[T]{id: id, fn: fn}, false, o.waiter, o.labels)) != Rejected
+}
+
 // Submit adds a task to the pool. Will block if the inbound queue is full.
-func (p *UniqPool[T]) Submit(id T, fn func()) {
-	if p.Stopped() {
-		panic("pool is stopped")
+func (p *UniqPool[T]) Submit(id T, fn func(), opts ...SubmitOption) {
+	o := p.submitOptions(opts)
+	mustSubmit(p.submitWaiter(task[T]{id: id, fn: fn}, true, o.waiter, o.labels))
+}
+
+// SubmitContext adds a task that receives a context to the pool. Will block if the inbound queue is full.
+// The context is cancelled by StopNow, so long-running tasks can be aborted.
+func (p *UniqPool[T]) SubmitContext(id T, fn func(ctx context.Context)) {
+	mustSubmit(p.submit(task[T]{id: id, ctxFn: fn}, true))
+}
+
+// SubmitWithPriority adds a task with the priority to the pool. Will block if the inbound queue is full.
+// Tasks with higher priority are dispatched first if priorities are enabled (see WithPriorities),
+// otherwise the priority is ignored.
+func (p *UniqPool[T]) SubmitWithPriority(id T, priority int, fn func()) {
+	mustSubmit(p.submit(task[T]{id: id, fn: fn, priority: priority}, true))
+}
+
+// TrySubmitWithPriority adds a task with the priority to the pool. Returns false if the inbound queue is full.
+func (p *UniqPool[T]) TrySubmitWithPriority(id T, priority int, fn func()) bool {
+	return mustSubmit(p.submit(task[T]{id: id, fn: fn, priority: priority}, false)) != Rejected
+}
+
+// SubmitEx adds a task to the pool and reports what happened to it. Will block if the inbound queue is full.
+// Unlike Submit, it does not panic if the pool is stopped, but returns Stopped and ErrPoolStopped.
+func (p *UniqPool[T]) SubmitEx(id T, fn func()) (Outcome, error) {
+	return outcomeErr(p.submit(task[T]{id: id, fn: fn}, true))
+}
+
+// TrySubmitEx adds a task to the pool and reports what happened to it. Returns Rejected if the inbound queue is full.
+// Unlike TrySubmit, it does not panic if the pool is stopped, but returns Stopped and ErrPoolStopped.
+func (p *UniqPool[T]) TrySubmitEx(id T, fn func()) (Outcome, error) {
+	return outcomeErr(p.submit(task[T]{id: id, fn: fn}, false))
+}
+
+// TrySubmitErr adds a task to the pool. Returns ErrQueueFull if the inbound queue or the namespace quota is full,
+// ErrPoolStopped if the pool is stopped. Coalesced and suppressed tasks are not errors.
+func (p *UniqPool[T]) TrySubmitErr(id T, fn func()) error {
+	switch p.submit(task[T]{id: id, fn: fn}, false) {
+	case Rejected:
+		return ErrQueueFull
+	case Stopped:
+		return ErrPoolStopped
+	default:
+		return nil
+	}
+}
+
+// submit adds a task to the pool. If wait is true, blocks until there is space in the inbound queue.
+func (p *UniqPool[T]) submit(t task[T], wait bool) Outcome {
+	return p.submitWaiter(t, wait, nil, nil)
+}
+
+// submitWaiter is submit that attaches the result waiter w to the task, or to the pending task it is coalesced with.
+// If the task is not executed, w is called right away. w is ignored if it is nil or the pool is stopped.
+// The labels are attached to the enqueued task (see Peek).
+func (p *UniqPool[T]) submitWaiter(t task[T], wait bool, w resultWaiter, labels map[string]string) Outcome {
+	t.id = p.normalizeKey(t.id)
+	s := p.stripe(t.id)
+
+	// The shared store may be remote, so it is called without the stripe mutex and the identifier is checked
+	// again after the call. stored is true if this submission added the identifier to the store, external is true
+	// if the identifier is pending in another pool sharing the store.
+	var stored, external bool
+	for {
+		s.mutex.Lock()
+
+		// checked under the mutex, so no task can get into the inbound queue after the pool is stopped
+		if p.Stopped() && !t.followUp {
+			s.mutex.Unlock()
+			if stored {
+				p.uniqStore.Remove(t.id)
+			}
+			return Stopped
+		}
+
+		// check the uniqueness of the task identifier
+		if result, ok := p.isDuplicate(s, t.id); ok {
+			if result == Coalesced && p.priorityQueue != nil {
+				p.upgradePriority(s, t)
+			}
+			if result == Coalesced && w != nil {
+				s.resultWaiters[t.id] = append(s.resultWaiters[t.id], w)
+				w = nil
+			}
+			s.mutex.Unlock()
+			if stored {
+				p.uniqStore.Remove(t.id)
+			}
+			p.observeOutcome(t.id, result)
+			if w != nil {
+				// suppressed, there is no pending execution to wait for
+				w(0, nil, nil)
+			}
+			return result
+		}
+
+		if external {
+			atomic.AddUint64(&p.counters.coalesced, 1)
+			s.mutex.Unlock()
+			p.observeOutcome(t.id, Coalesced)
+			if w != nil {
+				// pending in another pool sharing the store, the result is not available here
+				w(0, nil, nil)
+			}
+			return Coalesced
+		}
+
+		if p.uniqStore == nil || stored {
+			break
+		}
+		s.mutex.Unlock()
+		stored = p.uniqStore.Add(t.id)
+		external = !stored
 	}
 
-	p.inboundMutex.Lock()
-	defer p.inboundMutex.Unlock()
+	result, r := p.reserve(s, &t, wait)
+	if result == Enqueued && labels != nil {
+		s.setLabels(t.id, labels)
+	}
+	if result == Enqueued && w != nil {
+		// registered before the task is pushed, so it is moved to the task when it is dispatched
+		s.resultWaiters[t.id] = append(s.resultWaiters[t.id], w)
+		w = nil
+	}
+	s.mutex.Unlock()
 
-	// check the uniqueness of the task identifier
-	if _, ok := p.uniqMap[id]; ok {
+	if result == Rejected && stored {
+		p.uniqStore.Remove(t.id)
+	}
+
+	// observed before the push, so the task cannot be dispatched before it is observed as enqueued
+	p.observeOutcome(t.id, result)
+	if result == Enqueued {
+		p.push(t, r)
+	}
+
+	if w != nil {
+		// the task is rejected
+		w(0, nil, ErrQueueFull)
+	}
+
+	return result
+}
+
+// normalizeKey returns the canonical form of the task identifier.
+func (p *UniqPool[T]) normalizeKey(id T) T {
+	if p.keyNormalizer == nil {
+		return id
+	}
+
+	return p.keyNormalizer(id)
+}
+
+// reservation contains the slots reserved for a task without blocking.
+type reservation struct {
+	// The namespace quota slot is reserved.
+	namespaceSlot bool
+	// The inbound queue slot is reserved.
+	queueSlot bool
+	// The task is executed by the submitter instead of being pushed into the inbound queue (see CallerRuns).
+	inline bool
+	// The oldest pending task is dropped to make space for the task (see DropOldest).
+	evict bool
+	// The memory budget is not reserved, the task waits for it before it is pushed.
+	waitMemory bool
+}
+
+// reserve accepts the task into the dedup set and tries to reserve the slots for it without blocking.
+// If wait is false and there is no space in the inbound queue or the namespace quota is exhausted,
+// the task is rejected. Otherwise the task must be passed to push after the mutex is released.
+// Must be called under the mutex of the stripe s holding the identifier.
+func (p *UniqPool[T]) reserve(s *dedupStripe[T], t *task[T], wait bool) (Outcome, reservation) {
+	if p.shedding != nil && !t.followUp && p.shed() {
+		p.reject()
+		return Rejected, reservation{}
+	}
+
+	memory := p.reserveMemory(t)
+	if !memory && !wait {
+		p.reject()
+		return Rejected, reservation{}
+	}
+
+	if p.namespaceClassifier != nil {
+		t.namespace = p.namespaceClassifier(t.id)
+	}
+	if t.priority == 0 {
+		t.priority = p.profiles[t.namespace].Priority
+	}
+
+	r := reservation{waitMemory: !memory}
+	slots := p.namespaceSlots[t.namespace]
+	if slots == nil {
+		r.namespaceSlot = true
+	} else {
+		select {
+		case slots <- struct{}{}:
+			r.namespaceSlot = true
+		default:
+		}
+	}
+	if r.namespaceSlot {
+		r.queueSlot = p.inboundQueue.tryReserve()
+	}
+
+	if wait && r.namespaceSlot && !r.queueSlot && p.rejectionPolicy != nil {
+		saturated := p.executorSaturated()
+		switch p.rejectionPolicy.Decide(t.id, saturated) {
+		case Reject:
+			wait = false
+		case DropOldest:
+			r.evict = true
+		case CallerRuns:
+			if saturated {
+				// the task is not queued, so it does not hold the namespace quota and the memory budget
+				if slots != nil {
+					<-slots
+				}
+				p.releaseMemory(*t)
+				r = reservation{inline: true}
+			}
+		}
+	}
+
+	if !wait && !r.queueSlot {
+		if r.namespaceSlot && slots != nil {
+			<-slots
+		}
+		p.releaseMemory(*t)
+		p.reject()
+		return Rejected, reservation{}
+	}
+
+	if p.maxPendingKeys > 0 && !r.inline && !r.evict && p.overPendingKeys(r.queueSlot) {
+		p.requestEviction()
+	}
+
+	// the task is accepted, so its duplicates are coalesced even while push waits for the space
+	t.submittedAt = p.clock.Now()
+	s.insertKey(t, atomic.AddUint64(&p.counters.submitted, 1))
+	if p.priorityQueue != nil {
+		s.priorities[t.id] = t.priority
+	}
+	atomic.AddInt32(&p.pendingPushes, 1)
+	if t.chainFn != nil {
+		// the dispatcher does not stop until the follow-up tasks are pushed
+		atomic.AddInt32(&p.pendingPushes, 1)
+	}
+
+	return Enqueued, r
+}
+
+// push puts the reserved task into the inbound queue, waiting for the slots that were not reserved.
+// Must be called without the stripe mutex, so waiting does not block other submitters and the dispatcher.
+func (p *UniqPool[T]) push(t task[T], r reservation) {
+	if r.inline {
+		defer p.pushed()
+		p.runInline(t)
 		return
 	}
 
-	p.inboundChan <- task[T]{id: id, fn: fn}
-	p.uniqMap[id] = struct{}{}
+	if !r.namespaceSlot {
+		p.namespaceSlots[t.namespace] <- struct{}{}
+	}
+	if r.waitMemory {
+		p.waitMemory(&t)
+	}
+	if !r.queueSlot {
+		if r.evict {
+			p.requestEviction()
+		}
+		p.inboundQueue.reserve()
+	}
+
+	p.inboundQueue.push(t)
+	p.checkSoftCap()
+
+	if atomic.LoadInt32(&p.idle) == 1 && atomic.CompareAndSwapInt32(&p.idle, 1, 0) {
+		p.beat()
+		// the ticker is started before push returns, so the time of a fake clock can be advanced right away
+		p.wakeChan <- p.clock.NewTicker(p.interval)
+	}
+	if p.maxQueueLatency > 0 {
+		p.flushBy(t.submittedAt.Add(p.maxQueueLatency))
+	}
+	if p.profiles != nil {
+		p.profilePushed(t)
+	}
+
+	p.pushed()
+}
+
+// pushed marks the end of a push started by reserve.
+func (p *UniqPool[T]) pushed() {
+	atomic.AddInt32(&p.pendingPushes, -1)
+	if p.Stopped() {
+		// wake up the dispatcher waiting for the pending pushes to stop, so it drains the pushed task
+		// and makes space for the other pending pushes
+		select {
+		case p.pushedChan <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// dispatchNow sends the task to the worker pool right away, bypassing the inbound queue and the interval.
+// The task is deduplicated like a submitted one, but is not subject to the queue capacity, the namespace
+// quotas, the memory budget and the priorities.
+func (p *UniqPool[T]) dispatchNow(t task[T]) Outcome {
+	outcome := p.acceptNow(&t)
+	if outcome == Enqueued {
+		p.dispatch([]task[T]{t})
+		p.pushed()
+	}
+
+	return outcome
+}
+
+// acceptNow deduplicates the task dispatched right away. If it returns Enqueued, the caller must dispatch the task
+// and then call pushed, as the dispatcher does not stop until the task is handed over to the worker pool.
+func (p *UniqPool[T]) acceptNow(t *task[T]) Outcome {
+	t.id = p.normalizeKey(t.id)
+	s := p.stripe(t.id)
+
+	// the shared store is called without the stripe mutex like in submitWaiter
+	var stored, external bool
+	for {
+		s.mutex.Lock()
+
+		if p.Stopped() && !t.followUp {
+			s.mutex.Unlock()
+			if stored {
+				p.uniqStore.Remove(t.id)
+			}
+			return Stopped
+		}
+
+		if result, ok := p.isDuplicate(s, t.id); ok {
+			s.mutex.Unlock()
+			if stored {
+				p.uniqStore.Remove(t.id)
+			}
+			p.observeOutcome(t.id, result)
+			return result
+		}
+
+		if external {
+			s.mutex.Unlock()
+			atomic.AddUint64(&p.counters.coalesced, 1)
+			p.observeOutcome(t.id, Coalesced)
+			return Coalesced
+		}
+
+		if p.uniqStore == nil || stored {
+			break
+		}
+		s.mutex.Unlock()
+		stored = p.uniqStore.Add(t.id)
+		external = !stored
+	}
+
+	t.submittedAt = p.clock.Now()
+	s.insertKey(t, atomic.AddUint64(&p.counters.submitted, 1))
+	// the dispatcher does not stop until the task is handed over to the worker pool
+	atomic.AddInt32(&p.pendingPushes, 1)
+	s.mutex.Unlock()
+
+	p.observeOutcome(t.id, Enqueued)
+
+	return Enqueued
+}
+
+// reject counts the rejected task. The identifier is removed from the shared store by the submitter,
+// after the stripe mutex is released.
+func (p *UniqPool[T]) reject() {
+	atomic.AddUint64(&p.counters.rejected, 1)
+}
+
+// isDuplicate returns true if the task with the same identifier is already in the inbound queue
+// or was executed within the suppression window. Must be called under the mutex of the stripe s holding the identifier.
+func (p *UniqPool[T]) isDuplicate(s *dedupStripe[T], id T) (Outcome, bool) {
+	if k, ok := s.keys[id]; ok {
+		k.coalesced++
+		s.keys[id] = k
+		// resubmitting revives the pending task cancelled by CancelKey
+		p.uncancel(s, id)
+		atomic.AddUint64(&p.counters.coalesced, 1)
+		if p.hotKeys != nil {
+			p.hotKeys.record(id)
+		}
+		return Coalesced, true
+	}
+
+	if window := p.suppressionWindowOf(id); window > 0 {
+		if executedAt, ok := s.executedAt[id]; ok && p.clock.Now().Sub(executedAt) < window {
+			atomic.AddUint64(&p.counters.suppressed, 1)
+			return Suppressed, true
+		}
+	}
+
+	if p.quarantine != nil && p.quarantine.contains(id) {
+		atomic.AddUint64(&p.counters.suppressed, 1)
+		return Suppressed, true
+	}
+
+	return Enqueued, false
 }
 
 // StopAndWait stops the pool and waits for all tasks to be executed.
+// If the pool is already stopping, waits for the stop to complete.
 func (p *UniqPool[T]) StopAndWait() {
+	p.shutdown()
+}
+
+// StopNow stops the pool without finishing the backlog: the context of the running tasks added by SubmitContext
+// is cancelled and the tasks that are not started yet are discarded. Waits for the running tasks to return.
+// Returns the identifiers of the discarded tasks. Their result waiters receive ErrPoolStopped.
+// If the pool is already stopping by StopAndWait, the rest of its backlog is discarded.
+func (p *UniqPool[T]) StopNow() []T {
+	p.abort()
+	p.shutdown()
+
+	p.discardedMutex.Lock()
+	defer p.discardedMutex.Unlock()
+
+	discarded := append([]T(nil), p.discarded...)
+	for _, l := range p.lanes {
+		discarded = append(discarded, l.StopNow()...)
+	}
+
+	return discarded
+}
+
+// Done returns a channel that is closed when the pool is stopped and all its tasks are completed.
+func (p *UniqPool[T]) Done() <-chan struct{} {
+	p.lifecycleMutex.Lock()
+	defer p.lifecycleMutex.Unlock()
+
+	return p.doneChan
+}
+
+// abort makes the pool discard the tasks that are not started yet and cancels the context of the running tasks.
+func (p *UniqPool[T]) abort() {
+	p.lifecycleMutex.Lock()
+	defer p.lifecycleMutex.Unlock()
+
+	atomic.StoreInt32(&p.aborted, 1)
+	p.cancel()
+	for _, l := range p.lanes {
+		l.abort()
+	}
+}
+
+// shutdown stops the pool and waits for all tasks to be completed. Only the first call stops the pool,
+// the others wait for it.
+func (p *UniqPool[T]) shutdown() {
+	// Start does not replace the state until doneChan is closed, so it is read under the mutex only here
+	p.lifecycleMutex.Lock()
+	first := atomic.CompareAndSwapInt32(&p.stopping, 0, 1)
+	stopChan, doneChan := p.stopChan, p.doneChan
+	p.lifecycleMutex.Unlock()
+	if !first {
+		<-doneChan
+		return
+	}
+
 	// first stop the processTasks goroutine
-	close(p.stopChan)
+	close(stopChan)
 	p.stopWaitGroup.Wait()
-	// then stop the pool
-	p.pool.StopAndWait()
+	// then wait for the dispatched tasks and stop the pool
+	p.jobsWaitGroup.Wait()
+	// the lanes execute tasks on the executor of the pool
+	for _, l := range p.lanes {
+		l.shutdown()
+	}
+	if !p.sharedExecutor {
+		p.executor.stopAndWait()
+	}
+	p.cancel()
+	if p.checkpoint != nil {
+		// the final snapshot, so the persisted set does not keep the executed tasks
+		p.checkpoint(p.pendingKeys(maxInt))
+	}
+
+	var zero T
+	p.observe(EventStopped, zero, nil)
+	close(doneChan)
 }
 
-// processTasks processes the tasks from the inbound queue.
+// Start restarts the stopped pool, so it can be paused and resumed without reconstructing it.
+// The statistics and the options are kept. Returns ErrPoolRunning if the pool is not stopped or is still
+// stopping. A pool created by NewWithContext cannot be restarted after its context is done: Start returns
+// the error of the context.
+func (p *UniqPool[T]) Start() error {
+	p.lifecycleMutex.Lock()
+	defer p.lifecycleMutex.Unlock()
+
+	select {
+	case <-p.doneChan:
+	default:
+		return ErrPoolRunning
+	}
+	if err := p.parentCtx.Err(); err != nil {
+		return err
+	}
+
+	if !p.sharedExecutor {
+		p.executor = p.newExecutor()
+	}
+	for _, l := range p.lanes {
+		l.executor = p.executor
+		if err := l.Start(); err != nil {
+			return err
+		}
+	}
+	p.stopChan = make(chan struct{})
+	p.doneChan = make(chan struct{})
+	atomic.StoreInt32(&p.stopping, 0)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	atomic.StoreInt32(&p.aborted, 0)
+	p.discarded = nil
+	p.startedAt = p.clock.Now()
+	p.ticker = nil
+	atomic.StoreInt32(&p.idle, 1)
+
+	p.stopWaitGroup.Add(1)
+	atomic.StoreInt32(&p.dispatching, 1)
+	go p.processTasks()
+	p.watchContext()
+	p.startCheckpoints()
+	p.startStaleWatch()
+
+	// reset under the stripe mutexes, so submitters see the restarted pool consistently
+	p.lockStripes()
+	atomic.StoreInt32(&p.stopped, 0)
+	p.unlockStripes()
+
+	return nil
+}
+
+// processTasks processes the tasks from the inbound queue, restarting the dispatch loop after a panic.
 func (p *UniqPool[T]) processTasks() {
 	defer p.stopWaitGroup.Done()
+	defer atomic.StoreInt32(&p.dispatching, 0)
 
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+	defer p.stopTicker()
 
+	p.superviseDispatcher()
+}
+
+// dispatchLoop processes the tasks from the inbound queue until the pool is stopped.
+func (p *UniqPool[T]) dispatchLoop() {
 	for {
+		atomic.StoreInt64(&p.loopAt, p.clock.Now().UnixNano())
+
+		var tickChan <-chan time.Time
+		if p.ticker != nil {
+			tickChan = p.ticker.C()
+		}
+
+		var flush bool
 		select {
 		case <-p.stopChan:
+			// set under the stripe mutexes, so no submitter can get past the stopped check after this point
+			p.lockStripes()
 			atomic.StoreInt32(&p.stopped, 1)
-		case <-ticker.C:
-		}
-
-		drain := true
-		for drain {
-			select {
-			case t := <-p.inboundChan:
-				p.pool.Submit(t.fn)
-				p.inboundMutex.Lock()
-				delete(p.uniqMap, t.id)
-				p.inboundMutex.Unlock()
-			default:
-				drain = false
+			p.unlockStripes()
+			p.flushSchedule()
+			p.flushGangs()
+		case ticker := <-p.wakeChan:
+			// the first task after the idle period, let the tasks accumulate for the interval
+			p.ticker = ticker
+			p.beat()
+			continue
+		case <-p.flushChan:
+			if p.ticker == nil {
+				select {
+				case p.ticker = <-p.wakeChan:
+					p.beat()
+				default:
+					// the inbound queue is empty
+					continue
+				}
 			}
+			flush = true
+		case <-p.evictChan:
+			p.evict()
+			continue
+		case <-p.flushKeyChan:
+			p.flushKeys()
+			continue
+		case <-tickChan:
+			p.beat()
+			p.sweepStripe()
+			p.rates.observe(p.sample())
+		}
+
+		if p.Stopped() && p.isAborted() {
+			p.discardPending()
+			return
+		}
+
+		var full bool
+		if flush {
+			// the requested flush must dispatch the overdue tasks regardless of the drain batch limit
+			p.drainAll()
+		} else {
+			full = p.drain()
 		}
 
 		if p.Stopped() {
+			p.drainPending()
+			return
+		}
+
+		if !full {
+			p.sleepIfIdle()
+		}
+	}
+}
+
+// sleepIfIdle stops the ticker if the inbound queue is empty, so an idle pool does not wake up every interval.
+// The next pushed task wakes the pool up and starts a new ticker.
+func (p *UniqPool[T]) sleepIfIdle() {
+	if p.inboundQueue.len() > 0 {
+		return
+	}
+
+	p.ticker.Stop()
+	p.ticker = nil
+	// the ticks are stopped, so the memory left after a burst is released now
+	p.shrinkDedup()
+	atomic.StoreInt32(&p.idle, 1)
+
+	// a task pushed before the flag was set did not wake the pool up
+	if p.inboundQueue.len() > 0 && atomic.CompareAndSwapInt32(&p.idle, 1, 0) {
+		p.ticker = p.clock.NewTicker(p.interval)
+		p.beat()
+	}
+}
+
+// stopTicker stops the ticker, including the one started by a submitter but not received yet.
+func (p *UniqPool[T]) stopTicker() {
+	if p.ticker != nil {
+		p.ticker.Stop()
+	}
+
+	select {
+	case ticker := <-p.wakeChan:
+		ticker.Stop()
+	default:
+	}
+}
+
+// drain dispatches up to maxBatch tasks from the inbound queue. Returns true if the limit is reached,
+// so the queue may still contain tasks.
+func (p *UniqPool[T]) drain() bool {
+	maxBatch := int(atomic.LoadInt64(&p.maxBatch))
+	limit := maxBatch
+	if p.breaker != nil && !p.Stopped() {
+		// the backlog is dispatched on stop regardless of the breaker
+		limit = p.breaker.allow(p.clock.Now(), limit)
+	}
+	if p.resources != nil && !p.Stopped() {
+		limit = p.resources.allow(limit)
+	}
+
+	batch := p.take(p.batch[:0], limit)
+	if len(batch) == 0 {
+		return false
+	}
+	if p.softCap > 0 && p.inboundQueue.len() <= p.softCap {
+		atomic.StoreInt32(&p.overSoftCap, 0)
+	}
+
+	p.dispatchTaken(batch)
+
+	// the buffer is reused by the next drain, so it must not retain the tasks
+	for i := range batch {
+		batch[i] = task[T]{}
+	}
+	p.batch = batch[:0]
+
+	return len(batch) == maxBatch
+}
+
+// dispatchTaken frees the slots of the tasks taken from the inbound queue and dispatches them.
+func (p *UniqPool[T]) dispatchTaken(batch []task[T]) {
+	p.inboundQueue.notifySpace()
+	for _, t := range batch {
+		// the task has left the inbound queue, so the namespace quota is freed
+		p.freeSlots(t)
+	}
+
+	if atomic.LoadInt32(&p.cancelledKeys) > 0 {
+		batch = p.dropCancelled(batch)
+	}
+	if p.breaker != nil && len(batch) > 0 {
+		// only a task handed over to a worker is the probe, so a cancelled one does not keep the breaker open
+		batch[0].probe = p.breaker.probe()
+	}
+	p.dispatch(batch)
+}
+
+// maxInt is the maximum value of int.
+const maxInt = int(^uint(0) >> 1)
+
+// take appends up to limit tasks taken from the inbound queue to batch. If priorities are enabled,
+// all queued tasks are moved to the priority queue and the highest ranked ones are taken.
+func (p *UniqPool[T]) take(batch []task[T], limit int) []task[T] {
+	if p.priorityQueue == nil {
+		if p.carried.len() > 0 {
+			batch = p.takeCarried(batch, limit)
+		}
+		for len(batch) < limit {
+			t, ok := p.inboundQueue.pop()
+			if !ok {
+				break
+			}
+			batch = append(batch, t)
+		}
+		return batch
+	}
+
+	p.holdQueued()
+	p.applyPriorities()
+
+	n := len(batch)
+	for len(batch) < limit && p.priorityQueue.Len() > 0 {
+		batch = append(batch, p.priorityQueue.pop())
+	}
+	p.inboundQueue.release(len(batch) - n)
+
+	return batch
+}
+
+// drainAll dispatches all tasks from the inbound queue.
+func (p *UniqPool[T]) drainAll() {
+	for p.drain() {
+	}
+}
+
+// drainPending dispatches the tasks of the submitters that are still pushing them into the inbound queue,
+// until there are none left.
+func (p *UniqPool[T]) drainPending() {
+	for {
+		p.drainAll()
+
+		if atomic.LoadInt32(&p.pendingPushes) == 0 {
+			p.drainAll()
+			return
+		}
+
+		<-p.pushedChan
+	}
+}
+
+// discardPending discards the tasks in the inbound queue, including the ones that are still being pushed.
+func (p *UniqPool[T]) discardPending() {
+	for {
+		p.discardQueued()
+
+		if atomic.LoadInt32(&p.pendingPushes) == 0 {
+			p.discardQueued()
+			return
+		}
+
+		<-p.pushedChan
+	}
+}
+
+// discardQueued discards the tasks in the inbound queue.
+func (p *UniqPool[T]) discardQueued() {
+	for _, t := range p.take(nil, maxInt) {
+		p.freeSlots(t)
+		p.discard(t)
+	}
+
+	p.inboundQueue.notifySpace()
+}
+
+// discard drops the task that will not be executed because of StopNow.
+func (p *UniqPool[T]) discard(t task[T]) {
+	if t.probe {
+		p.breaker.cancelProbe()
+	}
+	p.drop(t, ErrPoolStopped)
+
+	p.discardedMutex.Lock()
+	p.discarded = append(p.discarded, t.id)
+	p.discardedMutex.Unlock()
+}
+
+// drop releases the identifier of the task that will not be executed and notifies its result waiters with err.
+func (p *UniqPool[T]) drop(t task[T], err error) {
+	p.unstore(t.id)
+
+	s := p.stripe(t.id)
+	s.mutex.Lock()
+	waiters, held := p.dropLocked(s, t)
+	s.mutex.Unlock()
+
+	p.notifyDropped(t, err, waiters, held)
+}
+
+// dropLocked releases the identifier of the dropped task under the mutex of the stripe s holding the identifier.
+// The identifier must be removed from the shared store by unstore before. Returns the result waiters and the held tasks of the task, to be passed to notifyDropped.
+func (p *UniqPool[T]) dropLocked(s *dedupStripe[T], t task[T]) ([]resultWaiter, []*heldTask[T]) {
+	p.removeKey(s, t.id)
+	waiters := append(t.waiters, s.resultWaiters[t.id]...)
+	delete(s.resultWaiters, t.id)
+
+	return waiters, s.takeDependents(t.id)
+}
+
+// notifyDropped notifies the result waiters of the dropped task with err and submits the tasks held by it.
+func (p *UniqPool[T]) notifyDropped(t task[T], err error, waiters []resultWaiter, held []*heldTask[T]) {
+	for _, w := range waiters {
+		w(0, nil, err)
+	}
+	p.resolveDetached(held)
+	p.observe(EventDropped, t.id, err)
+
+	if t.chainFn != nil {
+		// no follow-up tasks will be pushed
+		p.pushed()
+	}
+}
+
+// isAborted returns true if the pool is stopped by StopNow.
+func (p *UniqPool[T]) isAborted() bool {
+	return atomic.LoadInt32(&p.aborted) == 1
+}
+
+// dispatch sends the batch of tasks taken from the inbound queue to the worker pool.
+// Each stripe of the dedup state touched by the batch is locked once.
+func (p *UniqPool[T]) dispatch(batch []task[T]) {
+	ready := batch[:0]
+	for _, t := range batch {
+		if !p.park(t) {
+			ready = append(ready, t)
+		}
+	}
+
+	if len(ready) == 0 {
+		return
+	}
+
+	if p.keyRelease == ReleaseOnDispatch {
+		for _, t := range ready {
+			p.unstore(t.id)
+		}
+	}
+
+	byStripe := make(map[*dedupStripe[T]][]int, len(p.stripes))
+	for i := range ready {
+		s := p.stripe(ready[i].id)
+		byStripe[s] = append(byStripe[s], i)
+	}
+	for s, indexes := range byStripe {
+		s.mutex.Lock()
+		for _, i := range indexes {
+			ready[i].execID = newExecutionID()
+			p.releaseLocked(s, &ready[i])
+		}
+		s.mutex.Unlock()
+	}
+
+	atomic.AddUint64(&p.counters.dispatched, uint64(len(ready)))
+	atomic.StoreInt64(&p.dispatchedAt, p.clock.Now().UnixNano())
+	atomic.AddInt64(&p.executorBacklog, int64(len(ready)))
+	if p.observed() {
+		p.observeDispatched(ready)
+	}
+	if p.batchHandler != nil {
+		ready = p.dispatchBatch(ready)
+	}
+	p.jobsWaitGroup.Add(len(ready))
+	for _, t := range ready {
+		j := p.jobPool.Get().(*job[T])
+		j.t = t
+		p.executor.submit(t.id, j.run)
+	}
+}
+
+// park delays the task if a task with the same identifier is still running in serial keys mode.
+// The parked task is executed right after the running one. Returns false if the task can be executed now.
+func (p *UniqPool[T]) park(t task[T]) bool {
+	if !p.serialKeys {
+		return false
+	}
+
+	p.runningMutex.Lock()
+	defer p.runningMutex.Unlock()
+
+	if _, ok := p.runningKeys[t.id]; ok {
+		// the identifier is kept in the dedup set so that duplicates are coalesced with the parked task
+		p.parkedTasks[t.id] = t
+		return true
+	}
+	p.runningKeys[t.id] = struct{}{}
+
+	return false
+}
+
+// release removes the task identifier from the dedup set, so a new task with the same identifier can be submitted.
+// The task is about to be executed, so the suppression window starts here.
+// The result waiters accumulated while the task was pending are moved to the task.
+func (p *UniqPool[T]) release(t *task[T]) {
+	if p.keyRelease == ReleaseOnDispatch {
+		p.unstore(t.id)
+	}
+
+	s := p.stripe(t.id)
+	s.mutex.Lock()
+	p.releaseLocked(s, t)
+	s.mutex.Unlock()
+}
+
+// releaseLocked is release called under the mutex of the stripe s holding the identifier.
+// In ReleaseOnDispatch mode the identifier must be removed from the shared store by unstore before.
+func (p *UniqPool[T]) releaseLocked(s *dedupStripe[T], t *task[T]) {
+	if k := s.keys[t.id]; k.fn != nil {
+		t.fn = k.fn
+		k.fn = nil
+		s.keys[t.id] = k
+	}
+	if p.keyRelease == ReleaseOnDispatch {
+		p.removeKey(s, t.id)
+	}
+	s.running[t.id]++
+	delete(s.priorities, t.id)
+	if p.taskSuppressionWindow(t) > 0 {
+		s.setExecutedAt(t.id, p.clock.Now())
+	}
+	if waiters, ok := s.resultWaiters[t.id]; ok {
+		t.waiters = waiters
+		delete(s.resultWaiters, t.id)
+	}
+}
+
+// releaseCompleted removes the identifier of the completed task from the dedup set in ReleaseOnCompletion mode.
+// Returns the result waiters of the tasks coalesced with the task while it was running.
+func (p *UniqPool[T]) releaseCompleted(id T) []resultWaiter {
+	p.unstore(id)
+
+	s := p.stripe(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p.removeKey(s, id)
+
+	waiters := s.resultWaiters[id]
+	delete(s.resultWaiters, id)
+
+	return waiters
+}
+
+// removeKey removes the identifier from the dedup set. Must be called under the mutex of the stripe s holding the identifier.
+func (p *UniqPool[T]) removeKey(s *dedupStripe[T], id T) {
+	delete(s.keys, id)
+	delete(s.priorities, id)
+	p.uncancel(s, id)
+}
+
+// unstore removes the identifier from the shared store. The store may be remote, so it is called without
+// the stripe mutex, before the identifier is removed from the dedup set: a task with the same identifier
+// submitted in between is coalesced locally instead of being taken for pending in another pool.
+func (p *UniqPool[T]) unstore(id T) {
+	if p.uniqStore != nil {
+		p.uniqStore.Remove(id)
+	}
+}
+
+// execute runs the task inside the worker pool. In serial keys mode it also runs the tasks
+// that were parked while the task was running.
+func (p *UniqPool[T]) execute(t task[T]) {
+	if !p.serialKeys {
+		defer p.finish(t.id)
+		if p.isAborted() {
+			p.discard(t)
 			return
 		}
+		p.run(t)
+		return
 	}
+
+	// the first panic is re-raised after the parked tasks are executed, so that the worker pool handles it as usual
+	var panicValue any
+	for {
+		if p.isAborted() {
+			p.discard(t)
+		} else if r := runProtected(func() { p.run(t) }); r != nil && panicValue == nil {
+			panicValue = r
+		}
+		p.finish(t.id)
+
+		p.runningMutex.Lock()
+		next, ok := p.parkedTasks[t.id]
+		if !ok {
+			delete(p.runningKeys, t.id)
+			p.runningMutex.Unlock()
+			break
+		}
+		delete(p.parkedTasks, t.id)
+		p.runningMutex.Unlock()
+
+		next.execID = newExecutionID()
+		p.release(&next)
+		atomic.AddUint64(&p.counters.dispatched, 1)
+		p.observe(EventDispatched, next.id, nil)
+		t = next
+	}
+
+	if panicValue != nil {
+		panic(panicValue)
+	}
+}
+
+// run executes the task function and delivers the result to the waiters.
+func (p *UniqPool[T]) run(t task[T]) {
+	if p.quarantine != nil && p.quarantine.contains(t.id) {
+		p.skip(t)
+		return
+	}
+
+	atomic.AddUint64(&p.counters.executed, 1)
+	p.latency.record(p.clock.Now().Sub(t.submittedAt))
+
+	if weight := p.weights.acquire(t.weight); weight > 0 {
+		defer p.weights.release(weight)
+	}
+	if t.plain() && len(t.waiters) == 0 && p.keyRelease == ReleaseOnDispatch &&
+		p.breaker == nil && p.quarantine == nil && p.limiter == nil && p.slowKeys == nil &&
+		p.slowTaskThreshold == 0 && !p.observed() {
+		t.fn()
+		return
+	}
+
+	var (
+		value     any
+		err       error
+		completed bool
+	)
+
+	if p.limiter != nil {
+		p.limiter.acquire()
+		startedAt := p.clock.Now()
+		// deferred before complete, so it sees the error of a panicked task
+		defer func() { p.limiter.release(p.clock.Now().Sub(startedAt), err != nil) }()
+	}
+	if p.slowTaskThreshold > 0 {
+		defer p.watchSlowTask(t.id).Stop()
+	}
+	if p.slowKeys != nil {
+		startedAt := p.clock.Now()
+		defer func() {
+			now := p.clock.Now()
+			p.slowKeys.record(t.id, now.Sub(startedAt), now)
+		}()
+	}
+
+	defer func() {
+		if !completed {
+			err = ErrTaskPanicked
+		}
+		p.complete(t, value, err)
+	}()
+
+	var ctx context.Context
+	if t.resultFn != nil || t.errFn != nil || t.ctxFn != nil {
+		var release func()
+		ctx, release = p.taskContext(t)
+		defer release()
+	}
+
+	switch {
+	case t.resultFn != nil:
+		value, err = t.resultFn(ctx)
+		if err == nil {
+			p.cacheResult(t.id, value)
+		}
+	case t.chainFn != nil:
+		p.runChain(t)
+	case t.errFn != nil:
+		err = t.errFn(p.withAttempt(ctx, t.id))
+	case t.ctxFn != nil:
+		t.ctxFn(ctx)
+	default:
+		t.fn()
+	}
+
+	completed = true
+}
+
+// complete records the result of the executed task and delivers it to the waiters.
+func (p *UniqPool[T]) complete(t task[T], value any, err error) {
+	if err != nil {
+		p.observe(EventFailed, t.id, err)
+	} else {
+		p.observe(EventCompleted, t.id, nil)
+	}
+	if p.breaker != nil {
+		p.breaker.record(p.clock.Now(), err != nil)
+	}
+
+	if p.quarantine != nil {
+		p.quarantine.completed(t.id, err, p.clock.Now())
+	}
+	if t.errFn != nil && p.requeue != nil {
+		p.requeue.completed(p, t, err)
+	}
+
+	waiters := t.waiters
+	if p.keyRelease == ReleaseOnCompletion {
+		waiters = append(waiters, p.releaseCompleted(t.id)...)
+	}
+	for _, w := range waiters {
+		w(t.execID, value, err)
+	}
+}
+
+// pruneExecuted removes the execution times of the stripe that are out of the suppression window.
+// The stripe must be locked.
+func (p *UniqPool[T]) pruneExecuted(s *dedupStripe[T], now time.Time) {
+	if p.suppressionWindow <= 0 && !p.profileWindows {
+		return
+	}
+
+	for id, executedAt := range s.executedAt {
+		if now.Sub(executedAt) >= p.suppressionWindowOf(id) {
+			delete(s.executedAt, id)
+		}
+	}
+}
+
+// sweepStripe prunes and shrinks the next stripe of the dedup state. One stripe is swept per tick, so a tick
+// does not lock all stripes; the stale execution times are ignored by isDuplicate until they are pruned.
+func (p *UniqPool[T]) sweepStripe() {
+	s := p.stripes[p.sweepCursor]
+	p.sweepCursor = (p.sweepCursor + 1) % len(p.stripes)
+
+	// the classifier called by pruneExecuted may panic, the supervisor restarts the dispatcher then
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p.pruneExecuted(s, p.clock.Now())
+	s.shrink()
+}
+
+// runProtected runs fn and returns the recovered panic value, if any.
+func runProtected(fn func()) (panicValue any) {
+	defer func() {
+		panicValue = recover()
+	}()
+
+	fn()
+
+	return nil
+}
+
+// pending returns the number of tasks in the inbound queue.
+func (p *UniqPool[T]) pending() int {
+	n := 0
+	for _, s := range p.stripes {
+		s.mutex.Lock()
+		n += len(s.keys)
+		s.mutex.Unlock()
+	}
+
+	return n
 }
 
 // Stopped returns true if the pool is stopped.
@@ -1,6 +1,12 @@
 package uniqpool
 
 import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -11,7 +17,7 @@ import (
 // TestUniq checks that tasks with the same identifier are executed only once.
 func TestUniq(t *testing.T) {
 	// Create a new UniqPool instance
-	pool := New[string](10, 2, 10, time.Millisecond*100)
+	pool := MustNew[string](10, 2, 10, time.Millisecond*100)
 
 	var processed int32
 
@@ -39,7 +45,7 @@ func TestUniq(t *testing.T) {
 
 	// Assert that only two tasks were processed
 	require.Equal(t, int32(2), processed)
-	require.Empty(t, pool.uniqMap)
+	require.Zero(t, pool.pending())
 
 	// panic because pool is stopped
 	require.Panics(t, func() { pool.Submit("task1", func() {}) })
@@ -49,7 +55,7 @@ func TestUniq(t *testing.T) {
 // TestInboundQueueOverflow checks that the inbound queue overflow and waiting for available space works correctly.
 func TestInboundQueueOverflow(t *testing.T) {
 	// Create a new UniqPool instance
-	pool := New[string](2, 2, 10, time.Millisecond*100)
+	pool := MustNew[string](2, 2, 10, time.Millisecond*100)
 
 	var (
 		processed int32
@@ -85,5 +91,675 @@ func TestInboundQueueOverflow(t *testing.T) {
 
 	// Assert that only two tasks were processed
 	require.Equal(t, int32(3), processed)
-	require.Empty(t, pool.uniqMap)
+	require.Zero(t, pool.pending())
+}
+
+// TestSerialKeys checks that tasks with the same identifier are never executed concurrently in serial keys mode.
+func TestSerialKeys(t *testing.T) {
+	pool := MustNew(10, 4, 10, time.Millisecond*10, WithSerialKeys[string]())
+
+	var (
+		processed int32
+		running   int32
+		overlaps  int32
+	)
+
+	fn := func() {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		time.Sleep(time.Millisecond * 100)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&processed, 1)
+	}
+
+	pool.Submit("task1", fn)
+	// wait for the first task to be dispatched
+	time.Sleep(time.Millisecond * 50)
+
+	// will be parked until the first task is completed
+	pool.Submit("task1", fn)
+	time.Sleep(time.Millisecond * 20)
+	// coalesced with the parked task
+	pool.Submit("task1", fn)
+
+	pool.StopAndWait()
+
+	require.Equal(t, int32(2), processed)
+	require.Zero(t, overlaps)
+	require.Zero(t, pool.pending())
+	require.Empty(t, pool.runningKeys)
+	require.Empty(t, pool.parkedTasks)
+}
+
+// TestKeySharding checks that tasks with the same identifier are executed in submission order in key sharding mode.
+func TestKeySharding(t *testing.T) {
+	pool := MustNew(10, 4, 10, time.Millisecond*10, WithKeySharding[int](nil))
+
+	var (
+		mu    sync.Mutex
+		order = make(map[int][]int)
+	)
+
+	for i := 0; i < 5; i++ {
+		for key := 0; key < 3; key++ {
+			key, i := key, i
+			pool.Submit(key, func() {
+				time.Sleep(time.Millisecond * time.Duration(5-i))
+				mu.Lock()
+				order[key] = append(order[key], i)
+				mu.Unlock()
+			})
+		}
+		// wait for the tasks to be dispatched, so the next ones are not coalesced
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	pool.StopAndWait()
+
+	for key := 0; key < 3; key++ {
+		require.Equal(t, []int{0, 1, 2, 3, 4}, order[key])
+	}
+	require.Zero(t, pool.pending())
+}
+
+// TestOrderedDispatch checks that tasks are executed one by one in submission order in ordered dispatch mode.
+func TestOrderedDispatch(t *testing.T) {
+	pool := MustNew(100, 4, 10, time.Millisecond*10, WithOrderedDispatch[int]())
+
+	var (
+		mu      sync.Mutex
+		order   []int
+		running int32
+	)
+
+	for i := 0; i < 50; i++ {
+		i := i
+		pool.Submit(i, func() {
+			require.Equal(t, int32(1), atomic.AddInt32(&running, 1))
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			atomic.AddInt32(&running, -1)
+		})
+	}
+
+	pool.StopAndWait()
+
+	require.Len(t, order, 50)
+	for i := range order {
+		require.Equal(t, i, order[i])
+	}
+}
+
+// TestPanicHandler checks that the panics of the tasks are passed to the panic handler by all the executors.
+func TestPanicHandler(t *testing.T) {
+	for name, opts := range map[string][]Option[int]{
+		"pond":    nil,
+		"sharded": {WithKeySharding[int](nil)},
+		"ordered": {WithOrderedDispatch[int]()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			panics := make(chan any, 1)
+			pool := MustNew(10, 2, 10, time.Millisecond,
+				append(opts, WithPanicHandler[int](func(panicValue any) { panics <- panicValue }))...)
+
+			pool.Submit(1, func() { panic("task failed") })
+			require.Equal(t, "task failed", <-panics)
+
+			// the worker is not stopped by the panic
+			done := make(chan struct{})
+			pool.Submit(2, func() { close(done) })
+			<-done
+
+			pool.StopAndWait()
+		})
+	}
+}
+
+// TestPanicPrinted checks that the panics of the tasks are printed if no panic handler is set.
+func TestPanicPrinted(t *testing.T) {
+	for name, opts := range map[string][]Option[int]{
+		"pond":    nil,
+		"sharded": {WithKeySharding[int](nil)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			require.NoError(t, err)
+			stdout := os.Stdout
+			os.Stdout = w
+			defer func() { os.Stdout = stdout }()
+
+			pool := MustNew(10, 2, 10, time.Millisecond, opts...)
+			pool.Submit(1, func() { panic("task failed") })
+			pool.StopAndWait()
+			os.Stdout = stdout
+			require.NoError(t, w.Close())
+
+			out, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Contains(t, string(out), "task failed")
+			require.Contains(t, string(out), "Stack trace")
+		})
+	}
+}
+
+// TestSuppressionWindow checks that tasks executed within the suppression window are dropped.
+func TestSuppressionWindow(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Millisecond*10, WithSuppressionWindow[string](time.Millisecond*200))
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	pool.Submit("task1", fn)
+	time.Sleep(time.Millisecond * 50)
+	require.Equal(t, int32(1), atomic.LoadInt32(&processed))
+
+	// dropped because of the suppression window
+	require.True(t, pool.TrySubmit("task1", fn))
+	pool.Submit("task1", fn)
+	// another identifier is not affected
+	pool.Submit("task2", fn)
+	time.Sleep(time.Millisecond * 50)
+	require.Equal(t, int32(2), atomic.LoadInt32(&processed))
+
+	// the suppression window is over
+	time.Sleep(time.Millisecond * 200)
+	pool.Submit("task1", fn)
+
+	pool.StopAndWait()
+
+	require.Equal(t, int32(3), processed)
+	require.Zero(t, pool.pending())
+}
+
+// TestStats checks the statistics counters.
+func TestStats(t *testing.T) {
+	pool := MustNew(2, 2, 10, time.Millisecond*100, WithSuppressionWindow[string](time.Second))
+
+	fn := func() {}
+
+	pool.Submit("task1", fn)
+	pool.Submit("task1", fn)
+	pool.Submit("task2", fn)
+	require.False(t, pool.TrySubmit("task3", fn))
+
+	stats := pool.Stats()
+	require.Equal(t, 2, stats.Pending)
+	require.Equal(t, uint64(2), stats.Submitted)
+	require.Equal(t, uint64(1), stats.Coalesced)
+	require.Equal(t, uint64(1), stats.Rejected)
+	require.Zero(t, stats.Dispatched)
+
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 2 }, time.Second, time.Millisecond*10)
+	// suppressed
+	pool.Submit("task1", fn)
+
+	pool.StopAndWait()
+
+	stats = pool.Stats()
+	require.Zero(t, stats.Pending)
+	require.Equal(t, uint64(1), stats.Suppressed)
+	require.True(t, stats.Stopped)
+	// the statistics of the worker pool
+	require.Equal(t, uint64(2), stats.SubmittedTasks)
+	require.Equal(t, uint64(2), stats.SuccessfulTasks)
+	require.Zero(t, stats.FailedTasks)
+	require.Zero(t, stats.WaitingTasks)
+}
+
+// TestBacklogs checks that the inbound and the executor backlogs are reported separately.
+func TestBacklogs(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(2, 1, 1, time.Hour, WithClock[string](clock))
+
+	started := make(chan struct{})
+	gate := make(chan struct{})
+	pool.Submit("running", func() {
+		close(started)
+		<-gate
+	})
+	clock.tickChan <- time.Now()
+	<-started
+	pool.Submit("waiting", func() {})
+	clock.tickChan <- time.Now()
+	// the task is submitted after the drain, so it is not taken by the drain started by the tick
+	require.Eventually(t, func() bool { return pool.Stats().ExecutorBacklog == 1 }, time.Second, time.Millisecond)
+	pool.Submit("pending", func() {})
+
+	require.Equal(t, 1, pool.Stats().ExecutorBacklog)
+	require.Equal(t, 1, pool.Stats().Pending)
+
+	close(gate)
+	pool.StopAndWait()
+	require.Zero(t, pool.Stats().ExecutorBacklog)
+	require.Zero(t, pool.Stats().Pending)
+}
+
+// TestReleaseOnCompletion checks that tasks submitted while the task with the same identifier is running
+// are coalesced with it.
+func TestReleaseOnCompletion(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Millisecond*10, WithKeyRelease[string](ReleaseOnCompletion))
+
+	var processed int32
+	fn := func() {
+		time.Sleep(time.Millisecond * 100)
+		atomic.AddInt32(&processed, 1)
+	}
+
+	pool.Submit("task1", fn)
+	// wait for the task to be dispatched
+	time.Sleep(time.Millisecond * 50)
+
+	// coalesced with the running task
+	pool.Submit("task1", fn)
+	results := make(chan any, 1)
+	pool.SubmitWithResult("task1", func() (any, error) { return "unexpected", nil }, func(value any, err error) {
+		require.NoError(t, err)
+		results <- value
+	})
+	require.Nil(t, <-results)
+	require.Equal(t, int32(1), atomic.LoadInt32(&processed))
+
+	// the identifier is released after the completion
+	pool.Submit("task1", fn)
+
+	pool.StopAndWait()
+
+	require.Equal(t, int32(2), processed)
+	require.Equal(t, uint64(2), pool.Stats().Coalesced)
+	require.Zero(t, pool.pending())
+}
+
+// TestBlockedSubmitDoesNotHoldMutex checks that a Submit waiting for space in the inbound queue
+// does not block other submitters, and its task is executed even if the pool is stopped meanwhile.
+func TestBlockedSubmitDoesNotHoldMutex(t *testing.T) {
+	pool := MustNew[string](1, 2, 10, time.Hour)
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	pool.Submit("task1", fn)
+
+	blocked := make(chan struct{})
+	go func() {
+		// blocks until the pool is stopped
+		pool.Submit("task2", fn)
+		close(blocked)
+	}()
+	require.Eventually(t, func() bool { return pool.Stats().Pending == 2 }, time.Second, time.Millisecond)
+
+	// not blocked by the waiting submitter
+	now := time.Now()
+	require.False(t, pool.TrySubmit("task3", fn))
+	// coalesced with the waiting task
+	require.True(t, pool.TrySubmit("task2", fn))
+	pool.Submit("task2", fn)
+	require.Less(t, time.Since(now).Milliseconds(), int64(50))
+
+	pool.StopAndWait()
+	<-blocked
+
+	require.Equal(t, int32(2), processed)
+	require.Zero(t, pool.pending())
+}
+
+// TestDedupStripes checks that tasks are coalesced when the dedup state is split into stripes.
+func TestDedupStripes(t *testing.T) {
+	single := MustNew[int](10, 1, 10, time.Hour, WithDedupStripes[int](1))
+	require.Len(t, single.stripes, 1)
+	single.StopAndWait()
+
+	// sized by GOMAXPROCS by default
+	pool := MustNew[int](1000, 4, 1000, time.Hour)
+	require.Len(t, pool.stripes, runtime.GOMAXPROCS(0))
+
+	var processed int32
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				pool.Submit(i, func() {
+					atomic.AddInt32(&processed, 1)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 100, pool.pending())
+
+	pool.StopAndWait()
+
+	require.Equal(t, int32(100), atomic.LoadInt32(&processed))
+	require.Zero(t, pool.pending())
+}
+
+// manualClock is a Clock whose ticker ticks only when the test sends to tickChan.
+type manualClock struct {
+	tickChan chan time.Time
+}
+
+func (c manualClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c manualClock) NewTicker(time.Duration) Ticker {
+	return c
+}
+
+func (c manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (c manualClock) C() <-chan time.Time {
+	return c.tickChan
+}
+
+func (c manualClock) Stop() {}
+
+// TestMaxDrainBatch checks that the number of tasks dispatched per tick is limited.
+func TestMaxDrainBatch(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[int](clock), WithMaxDrainBatch[int](2))
+
+	for i := 0; i < 5; i++ {
+		pool.Submit(i, func() {})
+	}
+
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 2 }, time.Second, time.Millisecond)
+	require.Equal(t, 3, pool.Stats().Pending)
+
+	clock.tickChan <- time.Now()
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 4 }, time.Second, time.Millisecond)
+
+	// the rest is dispatched on stop
+	pool.StopAndWait()
+	require.Equal(t, uint64(5), pool.Stats().Dispatched)
+}
+
+// TestIdleWakeup checks that an idle pool stops ticking and is woken up by the next submitted task.
+func TestIdleWakeup(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(10, 2, 10, time.Hour, WithClock[int](clock))
+	require.Equal(t, int32(1), atomic.LoadInt32(&pool.idle))
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	for i := 1; i <= 2; i++ {
+		pool.Submit(i, fn)
+		require.Zero(t, atomic.LoadInt32(&pool.idle))
+
+		clock.tickChan <- time.Now()
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt32(&processed) == int32(i) && atomic.LoadInt32(&pool.idle) == 1
+		}, time.Second, time.Millisecond)
+	}
+
+	pool.StopAndWait()
+}
+
+// TestSubmitEx checks the outcomes of the submissions.
+func TestSubmitEx(t *testing.T) {
+	pool := MustNew(1, 1, 10, time.Hour, WithSuppressionWindow[string](time.Hour))
+
+	fn := func() {}
+
+	outcome, err := pool.SubmitEx("task1", fn)
+	require.NoError(t, err)
+	require.Equal(t, Enqueued, outcome)
+
+	outcome, err = pool.TrySubmitEx("task1", fn)
+	require.NoError(t, err)
+	require.Equal(t, Coalesced, outcome)
+
+	outcome, err = pool.TrySubmitEx("task2", fn)
+	require.NoError(t, err)
+	require.Equal(t, Rejected, outcome)
+
+	pool.StopAndWait()
+
+	outcome, err = pool.SubmitEx("task1", fn)
+	require.ErrorIs(t, err, ErrPoolStopped)
+	require.Equal(t, Stopped, outcome)
+	require.Equal(t, "stopped", outcome.String())
+	require.Panics(t, func() { pool.Submit("task1", fn) })
+}
+
+// TestTrySubmitErr checks the errors returned for the rejected tasks.
+func TestTrySubmitErr(t *testing.T) {
+	pool := MustNew[string](1, 1, 10, time.Hour)
+
+	fn := func() {}
+
+	require.NoError(t, pool.TrySubmitErr("task1", fn))
+	require.NoError(t, pool.TrySubmitErr("task1", fn))
+	require.ErrorIs(t, pool.TrySubmitErr("task2", fn), ErrQueueFull)
+
+	pool.StopAndWait()
+
+	require.ErrorIs(t, pool.TrySubmitErr("task1", fn), ErrPoolStopped)
+}
+
+// TestNewInvalidParameters checks the validation of the parameters.
+func TestNewInvalidParameters(t *testing.T) {
+	_, err := New[string](10, 2, 10, 0)
+	require.ErrorIs(t, err, ErrInvalidParameters)
+	require.ErrorContains(t, err, "interval")
+
+	_, err = New[string](0, 2, 10, time.Second)
+	require.ErrorIs(t, err, ErrInvalidParameters)
+
+	require.Panics(t, func() { MustNew[string](10, 0, 10, time.Second) })
+}
+
+// TestNewInvalidOptions checks that New returns an error for the options with invalid values.
+func TestNewInvalidOptions(t *testing.T) {
+	classify := PrefixClassifier(":")
+	for name, opt := range map[string]Option[string]{
+		"key release":          WithKeyRelease[string](ReleasePoint(42)),
+		"suppression window":   WithSuppressionWindow[string](-time.Second),
+		"result cache":         WithResultCache[string](10, 0),
+		"namespace quotas":     WithNamespaceQuotas(classify, map[string]int{"a": 0}),
+		"no classifier":        WithNamespaceQuotas[string](nil, map[string]int{"a": 1}),
+		"clock":                WithClock[string](nil),
+		"dedup stripes":        WithDedupStripes[string](-1),
+		"max drain batch":      WithMaxDrainBatch[string](0),
+		"stats window":         WithStatsWindow[string](0),
+		"shutdown grace":       WithShutdownGrace[string](-time.Second),
+		"memory budget":        WithMemoryBudget[string](0, nil),
+		"priorities":           WithPriorities[string](-time.Second),
+		"circuit breaker":      WithCircuitBreaker[string](1.5, 10, time.Second, time.Second),
+		"requeue":              WithRequeue[string](time.Second, time.Millisecond, nil),
+		"quarantine":           WithQuarantine[string](0, time.Second, nil),
+		"unbounded queue":      WithUnboundedQueue[string](10, nil),
+		"max queue latency":    WithMaxQueueLatency[string](0),
+		"load shedding":        WithLoadShedding[string](0.9, 0.5, 1),
+		"adaptive concurrency": WithAdaptiveConcurrency[string](1, time.Second, 2),
+		"resource limits":      WithResourceLimits[string](0, 0),
+		"checkpoint":           WithCheckpoint[string](time.Second, nil),
+		"max pending keys":     WithMaxPendingKeys[string](0),
+		"top keys":             WithTopKeys[string](0),
+		"slow keys":            WithSlowKeys[string](10, 0),
+		"slow task threshold":  WithSlowTaskThreshold[string](0, func(string, time.Duration) {}),
+		"stale backlog":        WithStaleBacklog[string](0, nil),
+		"worker supervisor":    WithWorkerSupervisor[string](0, false),
+		"panic handler":        WithPanicHandler[string](nil),
+		"lane":                 WithLane[string]("", time.Second, 10),
+		"namespace profiles":   WithNamespaceProfiles[string](classify, nil),
+	} {
+		_, err := New(10, 2, 10, time.Second, opt)
+		require.ErrorIs(t, err, ErrInvalidParameters, name)
+	}
+
+	_, err := NewShardedUniqPool(2, 10, 2, 10, time.Second, WithMaxDrainBatch[string](0))
+	require.ErrorIs(t, err, ErrInvalidParameters)
+}
+
+// TestStopNow checks that StopNow cancels the running tasks and discards the pending ones.
+func TestStopNow(t *testing.T) {
+	pool := MustNew[string](10, 1, 10, time.Millisecond*10, WithSerialKeys[string]())
+
+	started := make(chan struct{})
+	var cancelled int32
+	pool.SubmitContext("task1", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		atomic.StoreInt32(&cancelled, 1)
+	})
+	<-started
+
+	var executed int32
+	pool.Submit("task1", func() { atomic.AddInt32(&executed, 1) })
+	pool.Submit("task2", func() { atomic.AddInt32(&executed, 1) })
+
+	var resultErr error
+	pool.SubmitWithResult("task3", func() (any, error) { return nil, nil }, func(_ any, err error) { resultErr = err })
+
+	discarded := pool.StopNow()
+
+	require.ElementsMatch(t, []string{"task1", "task2", "task3"}, discarded)
+	require.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+	require.Zero(t, atomic.LoadInt32(&executed))
+	require.ErrorIs(t, resultErr, ErrPoolStopped)
+	require.Zero(t, pool.pending())
+}
+
+// TestRestart checks that the stopped pool can be started again.
+func TestRestart(t *testing.T) {
+	pool := MustNew[string](10, 2, 10, time.Hour)
+	require.ErrorIs(t, pool.Start(), ErrPoolRunning)
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	pool.Submit("task1", fn)
+	pool.StopAndWait()
+	require.Panics(t, func() { pool.Submit("task1", fn) })
+
+	require.NoError(t, pool.Start())
+	require.False(t, pool.Stopped())
+
+	pool.Submit("task1", fn)
+	pool.StopAndWait()
+	require.Equal(t, int32(2), atomic.LoadInt32(&processed))
+
+	require.NoError(t, pool.Start())
+	pool.Submit("task2", fn)
+	require.Equal(t, []string{"task2"}, pool.StopNow())
+
+	require.NoError(t, pool.Start())
+	pool.Submit("task3", fn)
+	pool.StopAndWait()
+
+	require.Equal(t, int32(3), atomic.LoadInt32(&processed))
+
+	// the pool cannot be restarted until it is stopped completely
+	require.NoError(t, pool.Start())
+	gate := make(chan struct{})
+	pool.Submit("task4", func() { <-gate })
+	require.True(t, pool.FlushKey("task4"))
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 4 }, time.Second, time.Millisecond)
+	stopped := make(chan struct{})
+	go func() {
+		pool.StopAndWait()
+		close(stopped)
+	}()
+	require.Eventually(t, pool.Stopped, time.Second, time.Millisecond)
+	require.ErrorIs(t, pool.Start(), ErrPoolRunning)
+	close(gate)
+	<-stopped
+	require.NoError(t, pool.Start())
+	pool.StopAndWait()
+}
+
+// TestNewWithContext checks that cancelling the context stops the pool, discarding the backlog after the grace period.
+func TestNewWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, err := NewWithContext[string](ctx, 10, 1, 10, time.Hour)
+	require.NoError(t, err)
+
+	var processed int32
+	pool.Submit("task1", func() { atomic.AddInt32(&processed, 1) })
+
+	cancel()
+	<-pool.Done()
+	require.True(t, pool.Stopped())
+	require.Equal(t, int32(1), atomic.LoadInt32(&processed))
+	// does not panic after the context stop
+	pool.StopAndWait()
+	// the pool would be stopped by the done context right away
+	require.ErrorIs(t, pool.Start(), context.Canceled)
+	require.True(t, pool.Stopped())
+
+	ctx, cancel = context.WithCancel(context.Background())
+	pool, err = NewWithContext(ctx, 10, 1, 10, time.Millisecond, WithShutdownGrace[string](time.Millisecond*10))
+	require.NoError(t, err)
+
+	pool.SubmitContext("task1", func(ctx context.Context) { <-ctx.Done() })
+	require.Eventually(t, func() bool { return pool.Stats().Dispatched == 1 }, time.Second, time.Millisecond)
+	pool.Submit("task2", func() { atomic.AddInt32(&processed, 1) })
+
+	cancel()
+	<-pool.Done()
+	require.Equal(t, int32(1), atomic.LoadInt32(&processed))
+	require.Equal(t, []string{"task2"}, pool.StopNow())
+}
+
+// TestKeyNormalizer checks that the identifiers equal after the normalization are coalesced.
+func TestKeyNormalizer(t *testing.T) {
+	pool := MustNew(10, 2, 10, time.Hour, WithKeyNormalizer(strings.ToLower))
+
+	var processed int32
+	fn := func() { atomic.AddInt32(&processed, 1) }
+
+	pool.Submit("Task1", fn)
+	pool.Submit("TASK1", fn)
+	pool.Submit("task1", fn)
+	require.Equal(t, []string{"task1"}, pool.pendingKeys(10))
+
+	pool.StopAndWait()
+	require.Equal(t, int32(1), processed)
+}
+
+// TestResetStats checks that the statistics are accumulated since the last reset.
+func TestResetStats(t *testing.T) {
+	pool := MustNew[int](10, 2, 10, time.Hour)
+	pool.Submit(1, func() {})
+	pool.Submit(1, func() {})
+
+	stats := pool.ResetStats()
+	require.Equal(t, uint64(1), stats.Submitted)
+	require.Equal(t, uint64(1), stats.Coalesced)
+
+	stats = pool.Stats()
+	require.Zero(t, stats.Submitted)
+	require.Zero(t, stats.Coalesced)
+	require.Equal(t, 1, stats.Pending)
+
+	pool.StopAndWait()
+	stats = pool.Stats()
+	require.Equal(t, uint64(1), stats.Dispatched)
+	require.Positive(t, stats.LatencyP50)
+	require.Equal(t, uint64(1), pool.ResetStats().Dispatched)
+	require.Zero(t, pool.Stats().LatencyP50)
+}
+
+// TestRingBuffer checks that the pool works with the ring buffer inbound queue.
+func TestRingBuffer(t *testing.T) {
+	var processed int32
+	pool := MustNew(3, 2, 10, time.Millisecond, WithRingBuffer[int]())
+	for i := 0; i < 100; i++ {
+		pool.Submit(i, func() { atomic.AddInt32(&processed, 1) })
+	}
+
+	pool.StopAndWait()
+	require.Equal(t, int32(100), atomic.LoadInt32(&processed))
+	require.NotNil(t, pool.inboundQueue.ring)
 }
@@ -0,0 +1,22 @@
+package uniqpool
+
+// Update replaces the function of the pending task with the identifier, so the latest closure is executed
+// for this identifier only, without the keep-last semantics for all tasks. Returns false if there is no task
+// with the identifier waiting to be dispatched, or if it was added by SubmitContext, SubmitWithResult
+// or another submission whose function has a different signature.
+func (p *UniqPool[T]) Update(id T, fn func()) bool {
+	id = p.normalizeKey(id)
+	s := p.stripe(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	k, ok := s.keys[id]
+	// with ReleaseOnCompletion the identifier of the executing task is kept in the dedup set
+	if !ok || !k.plain || (p.keyRelease == ReleaseOnCompletion && s.running[id] > 0) {
+		return false
+	}
+	k.fn = fn
+	s.keys[id] = k
+
+	return true
+}
@@ -0,0 +1,35 @@
+package uniqpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestUpdate checks that the latest function passed to Update is executed instead of the submitted one.
+func TestUpdate(t *testing.T) {
+	clock := manualClock{tickChan: make(chan time.Time)}
+	pool := MustNew(2, 2, 10, time.Hour, WithClock[string](clock))
+
+	executed := make(chan string, 4)
+	require.False(t, pool.Update("task1", func() {}))
+
+	pool.Submit("task1", func() { executed <- "submitted" })
+	require.True(t, pool.Update("task1", func() { executed <- "first" }))
+	require.True(t, pool.Update("task1", func() { executed <- "latest" }))
+	pool.SubmitContext("task2", func(context.Context) { executed <- "context" })
+	require.False(t, pool.Update("task2", func() {}))
+
+	clock.tickChan <- time.Now()
+	pool.StopAndWait()
+	close(executed)
+
+	var got []string
+	for name := range executed {
+		got = append(got, name)
+	}
+	require.ElementsMatch(t, []string{"latest", "context"}, got)
+	require.False(t, pool.Update("task1", func() {}))
+}
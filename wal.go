@@ -0,0 +1,157 @@
+package uniqpool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// WAL is a write-ahead log of the pending tasks of a PayloadPool.
+type WAL interface {
+	// Append appends the record to the log.
+	Append(record []byte) error
+	// Replay calls fn for each record of the log in the order they were appended.
+	Replay(fn func(record []byte) error) error
+	// Rewrite atomically replaces the content of the log with the given records.
+	Rewrite(records [][]byte) error
+}
+
+// FileWAL is a WAL stored in a file. Each record is prefixed with its length.
+type FileWAL struct {
+	path string
+	// Sync the file after each write.
+	syncWrites bool
+
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// OpenFileWAL opens or creates a file WAL. If syncWrites is true, the file is synced after each write,
+// so the records survive a crash of the machine, not only of the process.
+func OpenFileWAL(path string, syncWrites bool) (*FileWAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileWAL{path: path, syncWrites: syncWrites, file: file}, nil
+}
+
+// Append appends the record to the log.
+func (w *FileWAL) Append(record []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, err := w.file.Write(encodeWALRecord(record)); err != nil {
+		return err
+	}
+
+	return w.sync(w.file)
+}
+
+// Replay calls fn for each record of the log. An incomplete last record, left by a crash during the write,
+// is ignored.
+func (w *FileWAL) Replay(fn func(record []byte) error) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	file, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+
+		record := make([]byte, size)
+		if _, err := io.ReadFull(r, record); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// Rewrite atomically replaces the content of the log with the given records.
+func (w *FileWAL) Rewrite(records [][]byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(tmp)
+	for _, record := range records {
+		if _, err := bw.Write(encodeWALRecord(record)); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := w.sync(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, w.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.path, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_ = w.file.Close()
+	w.file = file
+
+	return nil
+}
+
+// Close closes the log file.
+func (w *FileWAL) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.file.Close()
+}
+
+func (w *FileWAL) sync(file *os.File) error {
+	if !w.syncWrites {
+		return nil
+	}
+
+	return file.Sync()
+}
+
+// encodeWALRecord prefixes the record with its length.
+func encodeWALRecord(record []byte) []byte {
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(record))
+	n := binary.PutUvarint(buf, uint64(len(record)))
+
+	return append(buf[:n], record...)
+}
@@ -0,0 +1,88 @@
+package uniqpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// weightedSemaphore limits the total weight of the executing tasks by the number of the workers. The tasks acquire
+// their weights in the order they start waiting, so a heavy task is not starved by the light ones.
+// It is enabled by the first weighted task, until then the tasks do not acquire their weights.
+type weightedSemaphore struct {
+	mutex sync.Mutex
+	cond  *sync.Cond
+
+	size int
+	used int
+	// The ticket of the next task to acquire its weight and the ticket of the next waiting task.
+	head uint64
+	tail uint64
+
+	// 1 if the tasks acquire their weights. Updated atomically.
+	enabled int32
+}
+
+func newWeightedSemaphore(size int) *weightedSemaphore {
+	s := &weightedSemaphore{size: size}
+	s.cond = sync.NewCond(&s.mutex)
+
+	return s
+}
+
+// enable makes the tasks acquire their weights.
+func (s *weightedSemaphore) enable() {
+	atomic.StoreInt32(&s.enabled, 1)
+}
+
+// acquire waits until the weight is available and takes it. A weight below one is one, a weight above the size
+// is the size. Returns the taken weight, or zero if the semaphore is not enabled.
+func (s *weightedSemaphore) acquire(weight int) int {
+	if atomic.LoadInt32(&s.enabled) == 0 {
+		return 0
+	}
+
+	switch {
+	case weight < 1:
+		weight = 1
+	case weight > s.size:
+		weight = s.size
+	}
+
+	s.mutex.Lock()
+	ticket := s.tail
+	s.tail++
+	for ticket != s.head || s.used+weight > s.size {
+		s.cond.Wait()
+	}
+	s.used += weight
+	s.head++
+	s.mutex.Unlock()
+	// the next waiting task may fit too
+	s.cond.Broadcast()
+
+	return weight
+}
+
+// release frees the weight taken by acquire.
+func (s *weightedSemaphore) release(weight int) {
+	s.mutex.Lock()
+	s.used -= weight
+	s.mutex.Unlock()
+	s.cond.Broadcast()
+}
+
+// SubmitWeighted adds a task that counts as weight worker slots, so heavy tasks (e.g. big exports) cannot all
+// execute at once. The total weight of the executing tasks is limited by the number of the workers, a weight above
+// it is the number of the workers. Once a weighted task is submitted, every task counts as its weight, one by
+// default. A task waiting for its weight occupies a worker. A duplicate keeps the weight of the pending task.
+// Will block if the inbound queue is full.
+func (p *UniqPool[T]) SubmitWeighted(id T, weight int, fn func()) {
+	p.weights.enable()
+	mustSubmit(p.submit(task[T]{id: id, fn: fn, weight: weight}, true))
+}
+
+// TrySubmitWeighted adds a weighted task like SubmitWeighted. Returns false if the inbound queue is full.
+func (p *UniqPool[T]) TrySubmitWeighted(id T, weight int, fn func()) bool {
+	p.weights.enable()
+	return mustSubmit(p.submit(task[T]{id: id, fn: fn, weight: weight}, false)) != Rejected
+}
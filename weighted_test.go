@@ -0,0 +1,40 @@
+package uniqpool
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubmitWeighted checks that the total weight of the executing tasks is limited by the number of the workers.
+func TestSubmitWeighted(t *testing.T) {
+	pool := MustNew[string](10, 3, 10, time.Millisecond)
+
+	var running, maxRunning int32
+	task := func(weight int32) func() {
+		return func() {
+			n := atomic.AddInt32(&running, weight)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 5)
+			atomic.AddInt32(&running, -weight)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		pool.SubmitWeighted("heavy"+strconv.Itoa(i), 2, task(2))
+		require.True(t, pool.TrySubmitWeighted("huge"+strconv.Itoa(i), 10, task(3)))
+		pool.Submit("light"+strconv.Itoa(i), task(1))
+	}
+	pool.StopAndWait()
+
+	require.Equal(t, uint64(9), pool.Stats().Dispatched)
+	require.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(3))
+}